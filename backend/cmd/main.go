@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -10,7 +11,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/config"
 	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/database"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/geo"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/handlers"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/mailer"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
 )
 
 func main() {
@@ -18,7 +26,9 @@ func main() {
 		log.Println("No se encontró archivo .env, usando variables del sistema")
 	}
 
-	if os.Getenv("APP_ENV") == "production" {
+	cfg := config.Load()
+
+	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
@@ -26,24 +36,77 @@ func main() {
 
 	database.AutoMigrate()
 
-	database.CreateInitialData()
+	database.CreateInitialData(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
 		log.Println("Cerrando aplicación...")
+		cancel()
 		database.CloseDatabase()
 		os.Exit(0)
 	}()
 
+	utils.SetupValidator()
+
+	mail := mailer.New(cfg)
+	geocodificador := geo.NewTablaLocal()
+
+	authService := services.NewAuthService(database.DB, cfg)
+	registroService := services.NewRegistroService(database.DB)
+	cuentaService := services.NewCuentaService(database.DB, cfg, mail)
+	personaService := services.NewPersonaService(database.DB, geocodificador)
+	familiaService := services.NewFamiliaService(database.DB)
+	eventoService := services.NewEventoService(database.DB, geocodificador, mail)
+	participacionService := services.NewParticipacionService(database.DB, mail)
+	checkinService := services.NewCheckinService(database.DB, cfg)
+	genealogiaService := services.NewGenealogiaService(database.DB)
+	gedcomService := services.NewGedcomService(database.DB)
+	duplicadosService := services.NewDuplicadosService(database.DB, mail)
+	directorioService := services.NewDirectorioService(database.DB)
+	empresaService := services.NewEmpresaService(database.DB)
+	reporteService := services.NewReporteService(database.DB)
+	migracionService := services.NewMigracionService(database.DB)
+	geoService := services.NewGeoService(database.DB, geocodificador)
+
+	eventoService.IniciarActualizadorEstados(ctx, cfg.EventosTickInterval)
+
+	if resultado, err := geoService.CompletarCoordenadas(); err != nil {
+		log.Printf("Error completando coordenadas: %v", err)
+	} else if len(resultado.SinUbicar) > 0 {
+		log.Printf("Lugares sin coordenadas: %v", resultado.SinUbicar)
+	}
+
+	authHandler := handlers.NewAuthHandler(authService)
+	registroHandler := handlers.NewRegistroHandler(registroService, cuentaService)
+	cuentaHandler := handlers.NewCuentaHandler(cuentaService)
+	personaHandler := handlers.NewPersonaHandler(personaService)
+	familiaHandler := handlers.NewFamiliaHandler(familiaService)
+	eventoHandler := handlers.NewEventoHandler(eventoService)
+	participacionHandler := handlers.NewParticipacionHandler(participacionService)
+	checkinHandler := handlers.NewCheckinHandler(checkinService, participacionHandler)
+	genealogiaHandler := handlers.NewGenealogiaHandler(genealogiaService)
+	gedcomHandler := handlers.NewGedcomHandler(gedcomService)
+	duplicadosHandler := handlers.NewDuplicadosHandler(duplicadosService)
+	directorioHandler := handlers.NewDirectorioHandler(directorioService)
+	empresaHandler := handlers.NewEmpresaHandler(empresaService)
+	reporteHandler := handlers.NewReporteHandler(reporteService)
+	migracionHandler := handlers.NewMigracionHandler(migracionService)
+	mapaHandler := handlers.NewMapaHandler(geoService)
+
+	authMiddleware := middleware.NewAuthMiddleware(database.DB, cfg)
+
 	r := gin.Default()
 
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{"http://localhost:3000", "http://localhost:3001"}
-	config.AllowCredentials = true
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	r.Use(cors.New(config))
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = []string{"http://localhost:3000", "http://localhost:3001"}
+	corsConfig.AllowCredentials = true
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	r.Use(cors.New(corsConfig))
 
 	api := r.Group("/api/v1")
 	{
@@ -63,7 +126,97 @@ func main() {
 			})
 		})
 
-		api.GET("/database/info", func(c *gin.Context) {
+		auth := api.Group("/auth")
+		{
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/register", registroHandler.Registrar)
+			auth.POST("/verify-email", cuentaHandler.VerificarEmail)
+			auth.POST("/password/forgot", cuentaHandler.ForgotPassword)
+			auth.POST("/password/reset", cuentaHandler.ResetPassword)
+		}
+
+		// Directorio de negocios: público, solo muestra los que aceptaron la
+		// promoción.
+		api.GET("/directorio/empresas", empresaHandler.Directorio)
+		api.GET("/directorio/empresas/:id", empresaHandler.GetDirectorio)
+		api.GET("/directorio/sectores", empresaHandler.Sectores)
+		api.GET("/mapas/empresas", mapaHandler.Empresas)
+
+		// Rutas que requieren sesión. Los usuarios pendientes solo llegan a
+		// su propio perfil; el resto de los grupos restringe por rol.
+		protected := api.Group("")
+		protected.Use(authMiddleware.RequireAuth())
+
+		protected.GET("/auth/me", authHandler.Me)
+		protected.POST("/auth/verify-email/resend", cuentaHandler.ReenviarVerificacion)
+		protected.PATCH("/personas/:id", personaHandler.Patch)
+
+		miembros := protected.Group("")
+		miembros.Use(authMiddleware.RequireMiembro())
+
+		miembros.GET("/personas", personaHandler.List)
+		miembros.GET("/personas/:id", personaHandler.Get)
+		miembros.GET("/familias", familiaHandler.List)
+		miembros.GET("/familias/:id", familiaHandler.Get)
+		miembros.GET("/eventos", eventoHandler.List)
+		miembros.GET("/eventos/:id", eventoHandler.Get)
+		miembros.POST("/eventos/:id/participaciones", participacionHandler.Registrar)
+		miembros.POST("/participaciones/:id/confirmar", participacionHandler.Confirmar)
+		miembros.POST("/participaciones/:id/cancelar", participacionHandler.Cancelar)
+		miembros.GET("/participaciones/:id/qr", checkinHandler.QR)
+		miembros.GET("/participaciones/:id/checkin-token", checkinHandler.Token)
+		miembros.GET("/personas/:id/genealogia", genealogiaHandler.ListarPorPersona)
+		miembros.GET("/personas/:id/arbol", genealogiaHandler.Arbol)
+		miembros.GET("/personas/:id/parentesco/:id_otro", genealogiaHandler.Parentesco)
+		miembros.GET("/personas/:id/gedcom", gedcomHandler.ExportarPersona)
+		miembros.GET("/familias/:id/gedcom", gedcomHandler.ExportarFamilia)
+		miembros.POST("/genealogia", genealogiaHandler.Crear)
+		miembros.PUT("/genealogia/:id", genealogiaHandler.Actualizar)
+		miembros.DELETE("/genealogia/:id", genealogiaHandler.Eliminar)
+		miembros.POST("/genealogia/:id/confirmar", genealogiaHandler.Confirmar)
+		miembros.GET("/directorio/search", directorioHandler.Buscar)
+		miembros.GET("/historia/migracion", migracionHandler.Historia)
+		miembros.GET("/mapas/miembros", mapaHandler.Miembros)
+		miembros.GET("/mapas/prefecturas", mapaHandler.Prefecturas)
+
+		admin := protected.Group("")
+		admin.Use(authMiddleware.RequireAdmin())
+
+		admin.GET("/admin/solicitudes", registroHandler.ListarPendientes)
+		admin.POST("/admin/solicitudes/:id/aprobar", registroHandler.Aprobar)
+		admin.POST("/admin/solicitudes/:id/rechazar", registroHandler.Rechazar)
+		admin.POST("/admin/geocodificar", mapaHandler.Geocodificar)
+
+		admin.POST("/personas", personaHandler.Create)
+		admin.PUT("/personas/:id", personaHandler.Update)
+		admin.DELETE("/personas/:id", personaHandler.Delete)
+		admin.GET("/personas/duplicados", duplicadosHandler.Candidatos)
+		admin.POST("/personas/:id/fusionar", duplicadosHandler.Fusionar)
+		admin.POST("/familias", familiaHandler.Create)
+		admin.PUT("/familias/:id", familiaHandler.Update)
+		admin.DELETE("/familias/:id", familiaHandler.Delete)
+		admin.POST("/eventos", eventoHandler.Create)
+		admin.PUT("/eventos/:id", eventoHandler.Update)
+		admin.DELETE("/eventos/:id", eventoHandler.Delete)
+		admin.POST("/eventos/:id/publicar", eventoHandler.Publicar)
+		admin.POST("/eventos/:id/cancelar", eventoHandler.Cancelar)
+		admin.POST("/eventos/:id/finalizar", eventoHandler.Finalizar)
+		admin.GET("/eventos/:id/participaciones", participacionHandler.ListByEvento)
+		admin.POST("/eventos/:id/checkin", checkinHandler.RegistrarAsistencia)
+		admin.GET("/genealogia/inferencias", genealogiaHandler.Inferencias)
+		admin.POST("/genealogia/inferencias/materializar", genealogiaHandler.MaterializarInferencias)
+		admin.POST("/gedcom/importar", gedcomHandler.Importar)
+		admin.GET("/reportes/demografico", reporteHandler.Demografico)
+		admin.GET("/reportes/asistencia", reporteHandler.Asistencia)
+		admin.GET("/reportes/asistencia/tipos", reporteHandler.AsistenciaPorTipo)
+		admin.GET("/reportes/eventos/:id", reporteHandler.AnalisisEvento)
+		admin.GET("/reportes/compromiso", reporteHandler.Compromiso)
+		admin.GET("/reportes/empresas", reporteHandler.Empresas)
+		admin.GET("/reportes/general", reporteHandler.General)
+
+		admin.GET("/database/info", func(c *gin.Context) {
 			var tables []string
 			database.DB.Raw("SELECT tablename FROM pg_tables WHERE schemaname = 'public'").Scan(&tables)
 
@@ -78,7 +231,7 @@ func main() {
 			})
 		})
 
-		api.GET("/stats", func(c *gin.Context) {
+		admin.GET("/stats", func(c *gin.Context) {
 			stats := make(map[string]int64)
 
 			// Variables temporales para contar registros
@@ -111,10 +264,7 @@ func main() {
 		})
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	port := cfg.Port
 
 	log.Printf("Servidor iniciando en puerto %s", port)
 	log.Printf("API disponible en: http://localhost:%s/api/v1", port)
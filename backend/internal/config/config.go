@@ -0,0 +1,83 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+type Config struct {
+	AppEnv string
+	Port   string
+
+	AdminPassword string
+
+	JWTSecret           string
+	JWTExpiresIn        time.Duration
+	JWTRefreshExpiresIn time.Duration
+
+	FrontendURL   string
+	MailDriver    string
+	MailFrom      string
+	MailOutboxDir string
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUser      string
+	SMTPPassword  string
+
+	EventosTickInterval time.Duration
+}
+
+func Load() *Config {
+	cfg := &Config{
+		AppEnv:              getEnv("APP_ENV", "development"),
+		Port:                getEnv("PORT", "8080"),
+		AdminPassword:       getEnv("ADMIN_PASSWORD", ""),
+		JWTSecret:           getEnv("JWT_SECRET", ""),
+		JWTExpiresIn:        getDuration("JWT_EXPIRES_IN", 24*time.Hour),
+		JWTRefreshExpiresIn: getDuration("JWT_REFRESH_EXPIRES_IN", 7*24*time.Hour),
+		FrontendURL:         getEnv("FRONTEND_URL", "http://localhost:3000"),
+		MailDriver:          getEnv("MAIL_DRIVER", "outbox"),
+		MailFrom:            getEnv("MAIL_FROM", "Asociación Nikkei de Sinaloa <no-reply@nikkei-sinaloa.org>"),
+		MailOutboxDir:       getEnv("MAIL_OUTBOX_DIR", "tmp/outbox"),
+		SMTPHost:            getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:            getEnv("SMTP_PORT", "1025"),
+		SMTPUser:            getEnv("SMTP_USER", ""),
+		SMTPPassword:        getEnv("SMTP_PASSWORD", ""),
+		EventosTickInterval: getDuration("EVENTOS_TICK_INTERVAL", time.Minute),
+	}
+
+	if cfg.JWTSecret == "" {
+		if cfg.IsProduction() {
+			log.Fatal("JWT_SECRET es obligatorio en producción")
+		}
+		log.Println("JWT_SECRET no definido, usando secreto de desarrollo")
+		cfg.JWTSecret = "nikkei_dev_jwt_secret"
+	}
+
+	return cfg
+}
+
+func (c *Config) IsProduction() bool {
+	return c.AppEnv == "production"
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s (%q), usando %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
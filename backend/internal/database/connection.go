@@ -10,7 +10,9 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/config"
 	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
 )
 
 var DB *gorm.DB
@@ -71,6 +73,8 @@ func AutoMigrate() {
 		&models.Evento{},
 		&models.ParticipacionEvento{},
 		&models.Genealogia{},
+		&models.Sesion{},
+		&models.TokenUsuario{},
 	}
 
 	err := DB.AutoMigrate(models...)
@@ -82,6 +86,10 @@ func AutoMigrate() {
 
 	createAdditionalConstraints()
 
+	actualizarClavesBusqueda()
+
+	createBusquedaTextoCompleto()
+
 	log.Println("¡Migraciones completadas exitosamente!")
 	log.Println("Base de datos lista para usar")
 }
@@ -152,22 +160,74 @@ func createForeignKeys() {
 		ON DELETE CASCADE;
 	`)
 
+	crearRestriccion("sesiones", "fk_sesiones_user",
+		"FOREIGN KEY (id_user) REFERENCES users(id_user) ON DELETE CASCADE")
+
+	crearRestriccion("tokens_usuario", "fk_tokens_usuario_user",
+		"FOREIGN KEY (id_user) REFERENCES users(id_user) ON DELETE CASCADE")
+
 	log.Println("Foreign keys creadas")
 }
 
+// crearRestriccion agrega la restricción solo si aún no existe, porque
+// PostgreSQL no admite ADD CONSTRAINT IF NOT EXISTS.
+func crearRestriccion(tabla, nombre, definicion string) {
+	err := DB.Exec(fmt.Sprintf(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '%[2]s') THEN
+				ALTER TABLE %[1]s ADD CONSTRAINT %[2]s %[3]s;
+			END IF;
+		END
+		$$;
+	`, tabla, nombre, definicion)).Error
+	if err != nil {
+		log.Printf("No se pudo crear %s: %v", nombre, err)
+	}
+}
+
 func createAdditionalConstraints() {
 	log.Println("Creando restricciones adicionales...")
 
+	// Como en genealogia, un índice único en lugar de ADD CONSTRAINT IF NOT
+	// EXISTS. Si ya hay inscripciones repetidas no se crea; se avisa para
+	// limpiarlas a mano.
+	if err := DB.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS unique_persona_evento 
+		ON participacion_eventos (id_persona, id_evento);
+	`).Error; err != nil {
+		log.Printf("No se pudo crear unique_persona_evento: %v", err)
+	}
+
+	// El CHECK de status_participacion se recrea para incluir la lista de
+	// espera en bases creadas antes de que existiera ese estado.
+	DB.Exec(`
+		ALTER TABLE participacion_eventos 
+		DROP CONSTRAINT IF EXISTS chk_participacion_eventos_status_participacion;
+	`)
+
 	DB.Exec(`
 		ALTER TABLE participacion_eventos 
-		ADD CONSTRAINT IF NOT EXISTS unique_persona_evento 
-		UNIQUE (id_persona, id_evento);
+		ADD CONSTRAINT chk_participacion_eventos_status_participacion 
+		CHECK (status_participacion IN ('registrado','confirmado','asistio','no_asistio','cancelado','en_espera'));
+	`)
+
+	// Se usa un índice único porque PostgreSQL no admite ADD CONSTRAINT IF
+	// NOT EXISTS; el nombre es el que reporta la violación.
+	DB.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS unique_relacion_genealogia 
+		ON genealogia (id_persona, id_pariente, tipo_relacion);
+	`)
+
+	DB.Exec(`
+		ALTER TABLE genealogia 
+		DROP CONSTRAINT IF EXISTS chk_genealogia_tipo_relacion;
 	`)
 
 	DB.Exec(`
 		ALTER TABLE genealogia 
-		ADD CONSTRAINT IF NOT EXISTS unique_relacion_genealogia 
-		UNIQUE (id_persona, id_pariente, tipo_relacion);
+		ADD CONSTRAINT chk_genealogia_tipo_relacion 
+		CHECK (tipo_relacion IN ('padre','madre','hijo','hija','esposo','esposa','hermano','hermana','abuelo','abuela','nieto','nieta','tio','tia','primo','prima','cuniado','cuniada','yerno','nuera','suegro','suegra','sobrino','sobrina'));
 	`)
 
 	DB.Exec(`
@@ -185,7 +245,122 @@ func createAdditionalConstraints() {
 	log.Println("Restricciones adicionales creadas")
 }
 
-func CreateInitialData() {
+// actualizarClavesBusqueda llena la clave de búsqueda de los registros que se
+// crearon antes de que existiera la columna; los nuevos la calculan al guardar.
+func actualizarClavesBusqueda() {
+	llenarClavesBusqueda[models.Familia]()
+	llenarClavesBusqueda[models.Persona]()
+	llenarClavesBusqueda[models.Empresa]()
+}
+
+func llenarClavesBusqueda[T any, P interface {
+	*T
+	CalcularClaveBusqueda() string
+}]() {
+	var filas []T
+	DB.Where("clave_busqueda IS NULL OR clave_busqueda = ''").
+		FindInBatches(&filas, 200, func(_ *gorm.DB, _ int) error {
+			for i := range filas {
+				fila := P(&filas[i])
+				DB.Model(fila).UpdateColumn("clave_busqueda", fila.CalcularClaveBusqueda())
+			}
+			return nil
+		})
+}
+
+// createBusquedaTextoCompleto prepara el directorio: una configuración de
+// texto en español que ignora acentos, columnas tsvector calculadas por la
+// propia base y los índices de trigramas sobre las claves de búsqueda.
+func createBusquedaTextoCompleto() {
+	log.Println("Creando índices de búsqueda...")
+
+	for _, extension := range []string{"unaccent", "pg_trgm"} {
+		if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS " + extension).Error; err != nil {
+			log.Printf("No se pudo crear la extensión %s; el directorio no funcionará: %v", extension, err)
+			return
+		}
+	}
+
+	// Un error aquí deja /directorio/search respondiendo 500, así que se
+	// registra cada uno.
+	ejecutar := func(descripcion, sentencia string) {
+		if err := DB.Exec(sentencia).Error; err != nil {
+			log.Printf("Error creando %s; el directorio no funcionará: %v", descripcion, err)
+		}
+	}
+
+	ejecutar("la configuración es_unaccent", `
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'es_unaccent') THEN
+				CREATE TEXT SEARCH CONFIGURATION es_unaccent (COPY = spanish);
+				ALTER TEXT SEARCH CONFIGURATION es_unaccent
+					ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+			END IF;
+		END
+		$$;
+	`)
+
+	// El documento de personas solo lleva lo que un miembro puede ver según
+	// ProyectarPersona: el nombre siempre, y ciudad, estado y puesto si la
+	// persona aceptó el directorio. Las bases creadas con la versión que
+	// incluía el lugar de nacimiento recrean la columna.
+	ejecutar("personas.documento_busqueda", `
+		DO $$
+		BEGIN
+			IF EXISTS (
+				SELECT 1 FROM pg_attrdef d
+				JOIN pg_attribute a ON a.attrelid = d.adrelid AND a.attnum = d.adnum
+				WHERE d.adrelid = 'personas'::regclass AND a.attname = 'documento_busqueda'
+					AND pg_get_expr(d.adbin, d.adrelid) LIKE '%lugar_nacimiento%'
+			) THEN
+				ALTER TABLE personas DROP COLUMN documento_busqueda;
+			END IF;
+		END
+		$$;
+	`)
+
+	ejecutar("personas.documento_busqueda", `
+		ALTER TABLE personas 
+		ADD COLUMN IF NOT EXISTS documento_busqueda tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('es_unaccent', coalesce(nombres, '') || ' ' || coalesce(apellido_paterno, '') || ' ' ||
+				coalesce(apellido_materno, '') || ' ' || coalesce(nombre_japones, '')), 'A') ||
+			setweight(to_tsvector('es_unaccent', CASE WHEN acepta_directorio_publico
+				THEN coalesce(ciudad, '') || ' ' || coalesce(estado, '') ELSE '' END), 'B') ||
+			setweight(to_tsvector('es_unaccent', CASE WHEN acepta_directorio_publico
+				THEN coalesce(puesto, '') ELSE '' END), 'C')
+		) STORED;
+	`)
+
+	ejecutar("familias.documento_busqueda", `
+		ALTER TABLE familias 
+		ADD COLUMN IF NOT EXISTS documento_busqueda tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('es_unaccent', coalesce(apellido_jp, '') || ' ' || coalesce(apellido_romanji, '')), 'A') ||
+			setweight(to_tsvector('es_unaccent', coalesce(prefectura_origen, '') || ' ' || coalesce(ciudad_origen, '') || ' ' ||
+				coalesce(lugar_llegada, '')), 'B') ||
+			setweight(to_tsvector('es_unaccent', coalesce(apellido_significado, '') || ' ' || coalesce(historia_familiar, '')), 'C')
+		) STORED;
+	`)
+
+	ejecutar("empresas.documento_busqueda", `
+		ALTER TABLE empresas 
+		ADD COLUMN IF NOT EXISTS documento_busqueda tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('es_unaccent', coalesce(nombre_empresa, '') || ' ' || coalesce(razon_social, '')), 'A') ||
+			setweight(to_tsvector('es_unaccent', coalesce(giro_comercial, '') || ' ' || coalesce(sector, '') || ' ' ||
+				coalesce(ciudad, '')), 'B') ||
+			setweight(to_tsvector('es_unaccent', coalesce(descripcion, '') || ' ' || coalesce(servicios_productos, '')), 'C')
+		) STORED;
+	`)
+
+	for _, tabla := range []string{"personas", "familias", "empresas"} {
+		ejecutar("los índices de "+tabla, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_documento_busqueda ON %[1]s USING gin (documento_busqueda)", tabla))
+		ejecutar("los índices de "+tabla, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_clave_busqueda_trgm ON %[1]s USING gin (clave_busqueda gin_trgm_ops)", tabla))
+	}
+
+	log.Println("Índices de búsqueda creados")
+}
+
+func CreateInitialData(cfg *config.Config) {
 	log.Println("Creando datos iniciales...")
 
 	var userCount int64
@@ -295,9 +470,15 @@ func CreateInitialData() {
 		log.Printf("Creadas %d personas de ejemplo", len(personas))
 	}
 
+	adminHash, err := utils.HashPassword(passwordAdmin(cfg))
+	if err != nil {
+		log.Printf("Error generando hash del administrador: %v", err)
+		return
+	}
+
 	adminUser := models.User{
 		Email:         "admin@nikkei-sinaloa.org",
-		PasswordHash:  "$2a$10$ejemplo_hash_cambiar_en_produccion",
+		PasswordHash:  adminHash,
 		Role:          "admin",
 		IsActive:      true,
 		EmailVerified: true,
@@ -313,6 +494,20 @@ func CreateInitialData() {
 	log.Println("¡Datos iniciales creados exitosamente!")
 }
 
+// passwordAdmin exige ADMIN_PASSWORD en producción. En desarrollo, si no está
+// definida, genera una aleatoria y la muestra una sola vez en el log.
+func passwordAdmin(cfg *config.Config) string {
+	if cfg.AdminPassword != "" {
+		return cfg.AdminPassword
+	}
+	if cfg.IsProduction() {
+		log.Fatal("ADMIN_PASSWORD es obligatorio en producción para crear el administrador")
+	}
+	password := utils.RandomToken(12)
+	log.Printf("ADMIN_PASSWORD no definido; contraseña generada para admin@nikkei-sinaloa.org: %s", password)
+	return password
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,203 @@
+// Package dbtest arma un *gorm.DB con el dialecto de PostgreSQL sobre un
+// driver falso, para probar servicios sin una base real. Cada consulta se
+// compara con las respuestas registradas y todo lo ejecutado queda anotado.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Respuesta es lo que devuelve una consulta. Con Err se simula un error de
+// la base; Afectadas es el RowsAffected de un INSERT/UPDATE/DELETE.
+type Respuesta struct {
+	Columnas  []string
+	Filas     [][]driver.Value
+	Afectadas int64
+	Err       error
+}
+
+// Filas arma una respuesta de SELECT.
+func Filas(columnas []string, filas ...[]driver.Value) Respuesta {
+	return Respuesta{Columnas: columnas, Filas: filas}
+}
+
+// Conteo arma la respuesta de un SELECT count(*).
+func Conteo(n int64) Respuesta {
+	return Filas([]string{"count"}, []driver.Value{n})
+}
+
+type regla struct {
+	patron    *regexp.Regexp
+	respuesta Respuesta
+	veces     int
+}
+
+// Base guarda las respuestas y las sentencias recibidas.
+type Base struct {
+	mu     sync.Mutex
+	reglas []*regla
+	SQL    []string
+}
+
+// Nueva devuelve la base falsa y el *gorm.DB que la usa.
+func Nueva(t *testing.T) (*gorm.DB, *Base) {
+	t.Helper()
+	base := &Base{}
+	sqlDB := sql.OpenDB(conector{base})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:               logger.Discard,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, base
+}
+
+// Responder registra la respuesta para las sentencias que coinciden con la
+// expresión. Gana la primera regla registrada que coincida.
+func (b *Base) Responder(patron string, r Respuesta) {
+	b.ResponderVeces(patron, 0, r)
+}
+
+// ResponderVeces es Responder para las primeras n coincidencias; con n = 0
+// la regla no se agota.
+func (b *Base) ResponderVeces(patron string, n int, r Respuesta) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reglas = append(b.reglas, &regla{patron: regexp.MustCompile(patron), respuesta: r, veces: n})
+}
+
+// Ejecutada dice si alguna sentencia coincide con la expresión.
+func (b *Base) Ejecutada(patron string) bool {
+	return len(b.Buscar(patron)) > 0
+}
+
+// Buscar devuelve las sentencias que coinciden con la expresión.
+func (b *Base) Buscar(patron string) []string {
+	re := regexp.MustCompile(patron)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var encontradas []string
+	for _, s := range b.SQL {
+		if re.MatchString(s) {
+			encontradas = append(encontradas, s)
+		}
+	}
+	return encontradas
+}
+
+func (b *Base) responder(query string, args []driver.NamedValue) Respuesta {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.SQL = append(b.SQL, conArgumentos(query, args))
+	for _, r := range b.reglas {
+		if r.veces < 0 || !r.patron.MatchString(query) {
+			continue
+		}
+		if r.veces > 0 {
+			if r.veces--; r.veces == 0 {
+				r.veces = -1
+			}
+		}
+		return r.respuesta
+	}
+	return Respuesta{}
+}
+
+// conArgumentos reemplaza $1, $2... por los valores para que las pruebas
+// puedan buscar también por ellos.
+func conArgumentos(query string, args []driver.NamedValue) string {
+	for i := len(args) - 1; i >= 0; i-- {
+		query = strings.ReplaceAll(query, fmt.Sprintf("$%d", i+1), fmt.Sprintf("%v", args[i].Value))
+	}
+	return query
+}
+
+type conector struct{ base *Base }
+
+func (c conector) Connect(context.Context) (driver.Conn, error) { return conexion{c.base}, nil }
+func (c conector) Driver() driver.Driver                        { return controlador{c.base} }
+
+type controlador struct{ base *Base }
+
+func (d controlador) Open(string) (driver.Conn, error) { return conexion{d.base}, nil }
+
+type conexion struct{ base *Base }
+
+func (c conexion) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("dbtest: Prepare no está soportado")
+}
+
+func (c conexion) Close() error { return nil }
+
+func (c conexion) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c conexion) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.base.responder("BEGIN", nil)
+	return transaccion{c.base}, nil
+}
+
+func (c conexion) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c conexion) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r := c.base.responder(query, args)
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return driver.RowsAffected(r.Afectadas), nil
+}
+
+func (c conexion) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r := c.base.responder(query, args)
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return &filas{columnas: r.Columnas, filas: r.Filas}, nil
+}
+
+type transaccion struct{ base *Base }
+
+func (t transaccion) Commit() error {
+	t.base.responder("COMMIT", nil)
+	return nil
+}
+
+func (t transaccion) Rollback() error {
+	t.base.responder("ROLLBACK", nil)
+	return nil
+}
+
+type filas struct {
+	columnas []string
+	filas    [][]driver.Value
+	i        int
+}
+
+func (f *filas) Columns() []string { return f.columnas }
+func (f *filas) Close() error      { return nil }
+
+func (f *filas) Next(dest []driver.Value) error {
+	if f.i >= len(f.filas) {
+		return io.EOF
+	}
+	copy(dest, f.filas[f.i])
+	f.i++
+	return nil
+}
@@ -0,0 +1,127 @@
+package gedcom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	Version551 = "5.5.1"
+	Version70  = "7.0"
+
+	// largoMaximo551 es el límite de línea de 5.5.1; lo que sobra va en CONC.
+	largoMaximo551 = 248
+)
+
+// Nuevo arma un registro con sus subestructuras; los hijos nil se ignoran.
+func Nuevo(tag, valor string, hijos ...*Registro) *Registro {
+	r := &Registro{Tag: tag, Valor: valor}
+	return r.Agregar(hijos...)
+}
+
+// Opcional devuelve nil si el valor está vacío, para no escribir etiquetas
+// sin contenido.
+func Opcional(tag, valor string, hijos ...*Registro) *Registro {
+	if strings.TrimSpace(valor) == "" {
+		return nil
+	}
+	return Nuevo(tag, valor, hijos...)
+}
+
+func (r *Registro) Agregar(hijos ...*Registro) *Registro {
+	for _, h := range hijos {
+		if h != nil {
+			r.Hijos = append(r.Hijos, h)
+		}
+	}
+	return r
+}
+
+// Escribir genera el archivo completo: encabezado, registros y TRLR.
+func Escribir(w io.Writer, version string, registros []*Registro) error {
+	if version != Version70 {
+		version = Version551
+	}
+
+	head := Nuevo("HEAD", "",
+		Nuevo("GEDC", "", Nuevo("VERS", version)),
+		Nuevo("SOUR", "NIKKEI-SISTEMA", Nuevo("NAME", "Sistema Nikkei")),
+		Nuevo("DATE", strings.ToUpper(FormatFecha(time.Now()))),
+	)
+	todos := []*Registro{head}
+	if version == Version551 {
+		head.Primero("GEDC").Agregar(Nuevo("FORM", "LINEAGE-LINKED"))
+		head.Agregar(Nuevo("CHAR", "UTF-8"), Nuevo("SUBM", "@U1@"))
+		todos = append(todos, &Registro{XRef: "@U1@", Tag: "SUBM", Hijos: []*Registro{Nuevo("NAME", "Sistema Nikkei")}})
+	}
+	todos = append(todos, registros...)
+	todos = append(todos, Nuevo("TRLR", ""))
+
+	bw := bufio.NewWriter(w)
+	for _, r := range todos {
+		escribirRegistro(bw, r, 0, version)
+	}
+	return bw.Flush()
+}
+
+func escribirRegistro(w *bufio.Writer, r *Registro, nivel int, version string) {
+	lineas := strings.Split(r.Valor, "\n")
+	escribirLinea(w, nivel, nivel+1, r.XRef, r.Tag, lineas[0], version)
+	for _, l := range lineas[1:] {
+		escribirLinea(w, nivel+1, nivel+1, "", "CONT", l, version)
+	}
+	for _, h := range r.Hijos {
+		escribirRegistro(w, h, nivel+1, version)
+	}
+}
+
+// escribirLinea recibe aparte el nivel de los CONC: CONT y CONC de un mismo
+// valor van al mismo nivel, uno abajo del registro.
+func escribirLinea(w *bufio.Writer, nivel, nivelConc int, xref, tag, valor, version string) {
+	if version == Version70 && strings.HasPrefix(valor, "@") && !esPuntero(valor) {
+		valor = "@" + valor
+	}
+
+	var resto []string
+	if version == Version551 {
+		valor, resto = partirConc(valor)
+	}
+
+	linea := fmt.Sprint(nivel)
+	if xref != "" {
+		linea += " " + xref
+	}
+	linea += " " + tag
+	if valor != "" {
+		linea += " " + valor
+	}
+	w.WriteString(linea + "\n")
+
+	for _, trozo := range resto {
+		w.WriteString(fmt.Sprintf("%d CONC %s\n", nivelConc, trozo))
+	}
+}
+
+// partirConc corta valores largos en trozos para CONC. Los cortes evitan
+// caer junto a un espacio porque varios lectores los recortan.
+func partirConc(valor string) (string, []string) {
+	runas := []rune(valor)
+	var trozos []string
+	for len(runas) > largoMaximo551 {
+		corte := largoMaximo551
+		for corte > largoMaximo551/2 && (runas[corte] == ' ' || runas[corte-1] == ' ') {
+			corte--
+		}
+		trozos = append(trozos, string(runas[:corte]))
+		runas = runas[corte:]
+	}
+	trozos = append(trozos, string(runas))
+	return trozos[0], trozos[1:]
+}
+
+func esPuntero(valor string) bool {
+	return len(valor) > 2 && strings.HasPrefix(valor, "@") && strings.HasSuffix(valor, "@") && !strings.Contains(valor, " ")
+}
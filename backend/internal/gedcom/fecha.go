@@ -0,0 +1,80 @@
+package gedcom
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var meses = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// Fecha es una fecha GEDCOM. Exacta solo se llena con día, mes y año sin
+// modificadores; Anio se llena siempre que haya un año reconocible.
+type Fecha struct {
+	Exacta     *time.Time
+	Anio       *int
+	Aproximada bool
+}
+
+// ParseFecha entiende las formas comunes: "12 MAR 1931", "MAR 1931", "1931",
+// con prefijos ABT, EST, CAL, BEF, AFT, BET ... AND ... y FROM ... TO ....
+// En rangos se toma el primer extremo.
+func ParseFecha(valor string) Fecha {
+	campos := strings.Fields(strings.ToUpper(valor))
+	var f Fecha
+	if len(campos) == 0 {
+		return f
+	}
+
+	switch campos[0] {
+	case "ABT", "EST", "CAL", "BEF", "AFT", "BET", "FROM", "TO", "INT":
+		f.Aproximada = true
+		campos = campos[1:]
+	}
+	for i, c := range campos {
+		if c == "AND" || c == "TO" {
+			campos = campos[:i]
+			break
+		}
+	}
+	// 7.0 y algunos programas anteponen el calendario.
+	if len(campos) > 0 && (campos[0] == "GREGORIAN" || campos[0] == "@#DGREGORIAN@") {
+		campos = campos[1:]
+	}
+
+	var dia, mes, anio int
+	for _, c := range campos {
+		if n, err := strconv.Atoi(c); err == nil {
+			if len(c) <= 2 && anio == 0 && mes == 0 {
+				dia = n
+			} else {
+				anio = n
+			}
+			continue
+		}
+		for i, m := range meses {
+			if c == m {
+				mes = i + 1
+			}
+		}
+	}
+
+	if anio == 0 {
+		return f
+	}
+	f.Anio = &anio
+	if f.Aproximada || dia == 0 || mes == 0 {
+		return f
+	}
+	t := time.Date(anio, time.Month(mes), dia, 0, 0, 0, 0, time.UTC)
+	if t.Day() != dia {
+		return f
+	}
+	f.Exacta = &t
+	return f
+}
+
+// FormatFecha escribe una fecha exacta en la forma "12 MAR 1931".
+func FormatFecha(t time.Time) string {
+	return strconv.Itoa(t.Day()) + " " + meses[t.Month()-1] + " " + strconv.Itoa(t.Year())
+}
@@ -0,0 +1,236 @@
+// Package gedcom lee y escribe archivos GEDCOM 5.5.1 y 7.0. Solo modela la
+// estructura de líneas; la traducción a personas y familias vive en services.
+package gedcom
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var ErrFormatoInvalido = errors.New("el archivo no es un GEDCOM válido")
+
+// Registro es una línea con sus subestructuras. Valor ya incluye las
+// continuaciones CONT y CONC.
+type Registro struct {
+	Nivel int
+	XRef  string
+	Tag   string
+	Valor string
+	Hijos []*Registro
+	Linea int
+}
+
+// Documento es un archivo GEDCOM ya interpretado.
+type Documento struct {
+	Version      string
+	Registros    []*Registro
+	Advertencias []string
+	porXRef      map[string]*Registro
+}
+
+// Parse interpreta el contenido completo de un archivo. Acepta UTF-8 (con o
+// sin BOM) y UTF-16 con BOM; cualquier otra codificación se lee como Latin-1
+// y se avisa en Advertencias.
+func Parse(data []byte) (*Documento, error) {
+	doc := &Documento{porXRef: make(map[string]*Registro)}
+
+	texto, aviso := decodificar(data)
+	if aviso != "" {
+		doc.Advertencias = append(doc.Advertencias, aviso)
+	}
+
+	var pila []*Registro
+	scanner := bufio.NewScanner(strings.NewReader(texto))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	numero := 0
+	for scanner.Scan() {
+		numero++
+		linea := strings.TrimLeft(strings.TrimRight(scanner.Text(), "\r"), " \t")
+		if linea == "" {
+			continue
+		}
+
+		reg, err := parseLinea(linea)
+		if err != nil {
+			return nil, fmt.Errorf("%w: línea %d: %v", ErrFormatoInvalido, numero, err)
+		}
+		reg.Linea = numero
+
+		if reg.Nivel > len(pila) {
+			return nil, fmt.Errorf("%w: línea %d: nivel %d sin nivel %d previo", ErrFormatoInvalido, numero, reg.Nivel, len(pila))
+		}
+		pila = pila[:reg.Nivel]
+
+		if reg.Nivel > 0 {
+			padre := pila[reg.Nivel-1]
+			switch reg.Tag {
+			case "CONT":
+				padre.Valor += "\n" + reg.Valor
+				pila = append(pila, reg)
+				continue
+			case "CONC":
+				padre.Valor += reg.Valor
+				pila = append(pila, reg)
+				continue
+			}
+			padre.Hijos = append(padre.Hijos, reg)
+		} else {
+			doc.Registros = append(doc.Registros, reg)
+			if reg.XRef != "" {
+				doc.porXRef[reg.XRef] = reg
+			}
+		}
+		pila = append(pila, reg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormatoInvalido, err)
+	}
+
+	if len(doc.Registros) == 0 || doc.Registros[0].Tag != "HEAD" {
+		return nil, fmt.Errorf("%w: falta el encabezado HEAD", ErrFormatoInvalido)
+	}
+	doc.Version = doc.Registros[0].Texto("GEDC", "VERS")
+	if doc.Version == "" {
+		doc.Advertencias = append(doc.Advertencias, "El encabezado no indica la versión; se asume 5.5.1")
+		doc.Version = "5.5.1"
+	}
+
+	return doc, nil
+}
+
+func parseLinea(linea string) (*Registro, error) {
+	campos := strings.SplitN(linea, " ", 2)
+	nivel, err := strconv.Atoi(campos[0])
+	if err != nil || nivel < 0 {
+		return nil, fmt.Errorf("nivel inválido %q", campos[0])
+	}
+	reg := &Registro{Nivel: nivel}
+	if len(campos) < 2 {
+		return nil, errors.New("falta la etiqueta")
+	}
+	resto := campos[1]
+
+	if strings.HasPrefix(resto, "@") {
+		fin := strings.Index(resto[1:], "@")
+		if fin < 0 {
+			return nil, errors.New("referencia sin cerrar")
+		}
+		reg.XRef = resto[:fin+2]
+		resto = strings.TrimLeft(resto[fin+2:], " ")
+	}
+
+	partes := strings.SplitN(resto, " ", 2)
+	reg.Tag = strings.ToUpper(partes[0])
+	if reg.Tag == "" {
+		return nil, errors.New("falta la etiqueta")
+	}
+	if len(partes) == 2 {
+		reg.Valor = partes[1]
+		// En 7.0 una arroba inicial literal se escribe duplicada.
+		if strings.HasPrefix(reg.Valor, "@@") {
+			reg.Valor = reg.Valor[1:]
+		}
+	}
+	return reg, nil
+}
+
+func decodificar(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), ""
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodificarUTF16(data[2:], false), ""
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodificarUTF16(data[2:], true), ""
+	case utf8.Valid(data):
+		return string(data), ""
+	}
+
+	runas := make([]rune, len(data))
+	for i, b := range data {
+		runas[i] = rune(b)
+	}
+	return string(runas), "El archivo no está en UTF-8; se leyó como Latin-1 y los acentos pueden no ser exactos"
+}
+
+func decodificarUTF16(data []byte, bigEndian bool) string {
+	unidades := make([]uint16, len(data)/2)
+	for i := range unidades {
+		if bigEndian {
+			unidades[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			unidades[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(unidades))
+}
+
+// Buscar devuelve el registro de nivel 0 con esa referencia.
+func (d *Documento) Buscar(xref string) *Registro {
+	return d.porXRef[xref]
+}
+
+// Tipo devuelve los registros de nivel 0 con la etiqueta indicada.
+func (d *Documento) Tipo(tag string) []*Registro {
+	var registros []*Registro
+	for _, r := range d.Registros {
+		if r.Tag == tag {
+			registros = append(registros, r)
+		}
+	}
+	return registros
+}
+
+// Primero devuelve la primera subestructura que sigue la ruta de etiquetas.
+func (r *Registro) Primero(ruta ...string) *Registro {
+	actual := r
+	for _, tag := range ruta {
+		var siguiente *Registro
+		for _, h := range actual.Hijos {
+			if h.Tag == tag {
+				siguiente = h
+				break
+			}
+		}
+		if siguiente == nil {
+			return nil
+		}
+		actual = siguiente
+	}
+	return actual
+}
+
+// Todos devuelve las subestructuras directas con esa etiqueta.
+func (r *Registro) Todos(tag string) []*Registro {
+	var registros []*Registro
+	for _, h := range r.Hijos {
+		if h.Tag == tag {
+			registros = append(registros, h)
+		}
+	}
+	return registros
+}
+
+// Texto devuelve el valor de la subestructura en la ruta, o "" si no existe.
+func (r *Registro) Texto(ruta ...string) string {
+	if sub := r.Primero(ruta...); sub != nil {
+		return strings.TrimSpace(sub.Valor)
+	}
+	return ""
+}
+
+// Puntero devuelve el valor si es una referencia @X@. En 7.0 @VOID@ indica
+// una referencia vacía.
+func (r *Registro) Puntero() string {
+	v := strings.TrimSpace(r.Valor)
+	if len(v) > 2 && strings.HasPrefix(v, "@") && strings.HasSuffix(v, "@") && v != "@VOID@" {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+package gedcom
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const cabecera = "0 HEAD\n1 GEDC\n2 VERS 5.5.1\n"
+
+func TestParseContinuaciones(t *testing.T) {
+	data := cabecera +
+		"0 @I1@ INDI\n" +
+		"1 NOTE Primera línea\n" +
+		"2 CONT segunda\n" +
+		"2 CONC  línea\n" +
+		"2 CONT\n" +
+		"2 CONT cuarta\n" +
+		"1 NAME Taro /Tanaka/\n" +
+		"0 TRLR\n"
+
+	doc, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	indi := doc.Buscar("@I1@")
+	if indi == nil {
+		t.Fatal("no se encontró @I1@")
+	}
+	if got, want := indi.Primero("NOTE").Valor, "Primera línea\nsegunda línea\n\ncuarta"; got != want {
+		t.Errorf("NOTE = %q; se esperaba %q", got, want)
+	}
+	if len(indi.Hijos) != 2 {
+		t.Errorf("CONT y CONC no deben quedar como hijos: %d hijos", len(indi.Hijos))
+	}
+}
+
+func TestParseCodificaciones(t *testing.T) {
+	texto := cabecera + "0 @I1@ INDI\n1 NAME José /Tanaka/\n0 TRLR\n"
+
+	utf16LE := []byte{0xFF, 0xFE}
+	utf16BE := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune(texto)) {
+		utf16LE = append(utf16LE, byte(u), byte(u>>8))
+		utf16BE = append(utf16BE, byte(u>>8), byte(u))
+	}
+	latin1 := bytes.ReplaceAll([]byte(texto), []byte("é"), []byte{0xE9})
+
+	casos := []struct {
+		nombre string
+		data   []byte
+		aviso  bool
+	}{
+		{"utf-8", []byte(texto), false},
+		{"utf-8 con BOM", append([]byte{0xEF, 0xBB, 0xBF}, texto...), false},
+		{"utf-16 LE", utf16LE, false},
+		{"utf-16 BE", utf16BE, false},
+		{"latin-1", latin1, true},
+	}
+	for _, c := range casos {
+		doc, err := Parse(c.data)
+		if err != nil {
+			t.Errorf("%s: %v", c.nombre, err)
+			continue
+		}
+		if got := doc.Buscar("@I1@").Texto("NAME"); got != "José /Tanaka/" {
+			t.Errorf("%s: NAME = %q", c.nombre, got)
+		}
+		if (len(doc.Advertencias) > 0) != c.aviso {
+			t.Errorf("%s: advertencias = %v", c.nombre, doc.Advertencias)
+		}
+	}
+}
+
+func TestParseInvalido(t *testing.T) {
+	casos := map[string]string{
+		"sin HEAD":        "0 @I1@ INDI\n0 TRLR\n",
+		"salto de nivel":  cabecera + "0 @I1@ INDI\n2 DATE 1931\n",
+		"nivel inválido":  cabecera + "x INDI\n",
+		"xref sin cerrar": cabecera + "0 @I1 INDI\n",
+	}
+	for nombre, data := range casos {
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrFormatoInvalido) {
+			t.Errorf("%s: error = %v; se esperaba ErrFormatoInvalido", nombre, err)
+		}
+	}
+}
+
+func TestParseFecha(t *testing.T) {
+	casos := []struct {
+		valor      string
+		exacta     string
+		anio       int
+		aproximada bool
+	}{
+		{"12 MAR 1931", "1931-03-12", 1931, false},
+		{"12 mar 1931", "1931-03-12", 1931, false},
+		{"MAR 1931", "", 1931, false},
+		{"1931", "", 1931, false},
+		{"ABT 1931", "", 1931, true},
+		{"EST 12 MAR 1931", "", 1931, true},
+		{"BEF 1920", "", 1920, true},
+		{"BET 1920 AND 1925", "", 1920, true},
+		{"FROM 3 JAN 1910 TO 1915", "", 1910, true},
+		{"@#DGREGORIAN@ 5 MAY 1950", "1950-05-05", 1950, false},
+		{"31 FEB 1931", "", 1931, false},
+		{"", "", 0, false},
+		{"desconocida", "", 0, false},
+	}
+	for _, c := range casos {
+		f := ParseFecha(c.valor)
+		exacta := ""
+		if f.Exacta != nil {
+			exacta = f.Exacta.Format("2006-01-02")
+		}
+		anio := 0
+		if f.Anio != nil {
+			anio = *f.Anio
+		}
+		if exacta != c.exacta || anio != c.anio || f.Aproximada != c.aproximada {
+			t.Errorf("ParseFecha(%q) = {%q, %d, %v}; se esperaba {%q, %d, %v}",
+				c.valor, exacta, anio, f.Aproximada, c.exacta, c.anio, c.aproximada)
+		}
+	}
+}
+
+func TestParseNombre(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		registro *Registro
+		nombres  string
+		apellido string
+		original string
+	}{
+		{"con diagonales", Nuevo("NAME", "Taro /Tanaka/"), "Taro", "Tanaka", "Taro Tanaka"},
+		{"apellido primero", Nuevo("NAME", "/田中/ 太郎"), "太郎", "田中", "田中 太郎"},
+		{"sin apellido", Nuevo("NAME", "Taro  Jiro"), "Taro Jiro", "", "Taro Jiro"},
+		{"diagonal sin cerrar", Nuevo("NAME", "Taro /Tanaka"), "Taro", "Tanaka", "Taro Tanaka"},
+		{"sufijo", Nuevo("NAME", "Juan /Sato/ Jr."), "Juan Jr.", "Sato", "Juan Sato Jr."},
+		{"GIVN y SURN mandan", Nuevo("NAME", "T. /Tanaka/", Nuevo("GIVN", "Taro"), Nuevo("SURN", "Tanaka Ito")), "Taro", "Tanaka Ito", "T. Tanaka"},
+	}
+	for _, c := range casos {
+		n := parseNombre(c.registro, false)
+		if n.Nombres != c.nombres || n.Apellido != c.apellido || n.Original != c.original {
+			t.Errorf("%s: {%q, %q, %q}; se esperaba {%q, %q, %q}",
+				c.nombre, n.Nombres, n.Apellido, n.Original, c.nombres, c.apellido, c.original)
+		}
+	}
+
+	variante := parseNombre(Nuevo("ROMN", "Taro /Tanaka/", Nuevo("TYPE", "romaji")), true)
+	if variante.Tipo != "romaji" || variante.Idioma != "romaji" || !variante.Variante {
+		t.Errorf("variante ROMN = %+v", variante)
+	}
+}
+
+func TestEscribirYLeer(t *testing.T) {
+	larga := strings.Repeat("palabra ", 80)
+	nota := "Llegaron a Mazatlán.\n" + larga + "\nfin"
+	registros := []*Registro{
+		{XRef: "@I1@", Tag: "INDI", Hijos: []*Registro{
+			Nuevo("NAME", "Taro /Tanaka/", Nuevo("GIVN", "Taro"), Nuevo("SURN", "Tanaka")),
+			Nuevo("NAME", "/田中/太郎", Nuevo("TYPE", "aka")),
+			Nuevo("BIRT", "", Nuevo("DATE", "12 MAR 1931"), Nuevo("PLAC", "Hiroshima")),
+			Nuevo("NOTE", nota),
+		}},
+	}
+
+	for _, version := range []string{Version551, Version70} {
+		var buf bytes.Buffer
+		if err := Escribir(&buf, version, registros); err != nil {
+			t.Fatal(err)
+		}
+		if version == Version551 {
+			for _, linea := range strings.Split(buf.String(), "\n") {
+				if len([]rune(linea)) > largoMaximo551+10 {
+					t.Errorf("línea demasiado larga en 5.5.1: %d runas", len([]rune(linea)))
+				}
+				if strings.HasPrefix(linea, "3 CONC") {
+					t.Errorf("CONC de un CONT debe ir al nivel 2: %q", linea)
+				}
+			}
+		}
+
+		doc, err := Parse(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		if doc.Version != version {
+			t.Errorf("versión = %q; se esperaba %q", doc.Version, version)
+		}
+		indi := doc.Buscar("@I1@")
+		if indi == nil {
+			t.Fatalf("%s: no se encontró @I1@", version)
+		}
+		if got := indi.Primero("NOTE").Valor; got != nota {
+			t.Errorf("%s: NOTE no sobrevivió la ida y vuelta:\n%q\n%q", version, got, nota)
+		}
+		if got := indi.Texto("BIRT", "DATE"); got != "12 MAR 1931" {
+			t.Errorf("%s: BIRT DATE = %q", version, got)
+		}
+		nombres := indi.Nombres()
+		if len(nombres) != 2 || nombres[0].Apellido != "Tanaka" || nombres[1].Apellido != "田中" || nombres[1].Nombres != "太郎" {
+			t.Errorf("%s: nombres = %+v", version, nombres)
+		}
+		if len(doc.Tipo("TRLR")) != 1 {
+			t.Errorf("%s: falta TRLR", version)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package gedcom
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Nombre es un NAME o una de sus variantes (ROMN y FONE en 5.5.1, TRAN en
+// 7.0). Idioma viene de LANG o del TYPE de la variante cuando existe.
+type Nombre struct {
+	Original string
+	Nombres  string
+	Apellido string
+	Tipo     string
+	Idioma   string
+	Variante bool
+}
+
+// Completo devuelve el nombre en el orden en que se escribió, que en los
+// nombres japoneses suele ser apellido primero.
+func (n Nombre) Completo() string {
+	if n.Original != "" {
+		return n.Original
+	}
+	return strings.Join(strings.Fields(n.Nombres+" "+n.Apellido), " ")
+}
+
+// EsJapones indica si está escrito en kanji o kana.
+func (n Nombre) EsJapones() bool {
+	return TieneEscrituraJaponesa(n.Nombres + n.Apellido)
+}
+
+// Nombres devuelve todos los nombres de un INDI con sus variantes, en el
+// orden del archivo.
+func (r *Registro) Nombres() []Nombre {
+	var nombres []Nombre
+	for _, name := range r.Todos("NAME") {
+		nombres = append(nombres, parseNombre(name, false))
+		for _, h := range name.Hijos {
+			switch h.Tag {
+			case "ROMN", "FONE", "TRAN":
+				nombres = append(nombres, parseNombre(h, true))
+			}
+		}
+	}
+	return nombres
+}
+
+// parseNombre separa "Taro /Tanaka/" en nombres y apellido; GIVN y SURN,
+// si vienen, tienen prioridad.
+func parseNombre(r *Registro, variante bool) Nombre {
+	n := Nombre{
+		Tipo:     strings.ToLower(r.Texto("TYPE")),
+		Idioma:   r.Texto("LANG"),
+		Variante: variante,
+	}
+	if n.Idioma == "" && variante {
+		n.Idioma = n.Tipo
+	}
+
+	valor := strings.TrimSpace(r.Valor)
+	n.Original = strings.Join(strings.Fields(strings.ReplaceAll(valor, "/", " ")), " ")
+	if inicio := strings.Index(valor, "/"); inicio >= 0 {
+		fin := strings.Index(valor[inicio+1:], "/")
+		if fin < 0 {
+			fin = len(valor) - inicio - 1
+		}
+		n.Apellido = strings.TrimSpace(valor[inicio+1 : inicio+1+fin])
+		resto := valor[:inicio]
+		if inicio+2+fin <= len(valor) {
+			resto += " " + valor[inicio+2+fin:]
+		}
+		n.Nombres = strings.Join(strings.Fields(resto), " ")
+	} else {
+		n.Nombres = strings.Join(strings.Fields(valor), " ")
+	}
+
+	if givn := r.Texto("GIVN"); givn != "" {
+		n.Nombres = givn
+	}
+	if surn := r.Texto("SURN"); surn != "" {
+		n.Apellido = surn
+	}
+	return n
+}
+
+// TieneEscrituraJaponesa detecta kanji, hiragana o katakana.
+func TieneEscrituraJaponesa(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+			return true
+		}
+	}
+	return false
+}
+
+// TieneKanji detecta ideogramas; sirve para preferir kanji sobre kana.
+func TieneKanji(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+// Package geo ubica ciudades mexicanas y prefecturas japonesas y arma las
+// capas GeoJSON de los mapas genealógicos.
+package geo
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrUbicacionDesconocida = errors.New("ubicación desconocida")
+
+type Coordenadas struct {
+	Latitud  float64 `json:"latitud"`
+	Longitud float64 `json:"longitud"`
+}
+
+// Geocodificador convierte nombres de lugar en coordenadas. La precisión es
+// de ciudad: nunca se geocodifican direcciones de personas.
+type Geocodificador interface {
+	// Municipio ubica un municipio o ciudad de México. Con el estado vacío
+	// se prefiere Sinaloa; con el municipio vacío se usa la capital del
+	// estado.
+	Municipio(municipio, estado string) (Coordenadas, error)
+	// Prefectura ubica la capital de una prefectura de Japón.
+	Prefectura(nombre string) (Coordenadas, error)
+}
+
+// Caja es un área visible del mapa, en el orden de Leaflet
+// (toBBoxString): oeste, sur, este, norte.
+type Caja struct {
+	Oeste, Sur, Este, Norte float64
+}
+
+var errCajaInvalida = errors.New("debe tener el formato oeste,sur,este,norte")
+
+func ParseCaja(s string) (*Caja, error) {
+	partes := strings.Split(s, ",")
+	if len(partes) != 4 {
+		return nil, errCajaInvalida
+	}
+	var valores [4]float64
+	for i, p := range partes {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, errCajaInvalida
+		}
+		valores[i] = v
+	}
+	c := &Caja{Oeste: valores[0], Sur: valores[1], Este: valores[2], Norte: valores[3]}
+	if c.Sur > c.Norte || c.Sur < -90 || c.Norte > 90 {
+		return nil, errCajaInvalida
+	}
+	return c, nil
+}
+
+// Contiene admite cajas que cruzan el antimeridiano (oeste > este).
+func (c *Caja) Contiene(p Coordenadas) bool {
+	if p.Latitud < c.Sur || p.Latitud > c.Norte {
+		return false
+	}
+	if c.Oeste <= c.Este {
+		return p.Longitud >= c.Oeste && p.Longitud <= c.Este
+	}
+	return p.Longitud >= c.Oeste || p.Longitud <= c.Este
+}
+
+type Geometria struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type Feature struct {
+	Type       string         `json:"type"`
+	Geometry   Geometria      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Punto es un elemento de una capa. Peso es cuántos registros representa
+// (personas de una ciudad, familias de una prefectura, una empresa).
+type Punto struct {
+	Coordenadas
+	Peso        int64
+	Propiedades map[string]any
+}
+
+// ZoomSinAgrupar es el zoom de Leaflet a partir del cual se mandan los
+// puntos sueltos.
+const ZoomSinAgrupar = 9
+
+// Capa arma la FeatureCollection. Con un zoom menor a ZoomSinAgrupar junta
+// los puntos en celdas de una cuadrícula que se achica con cada nivel; cada
+// grupo queda en el centro ponderado de sus puntos con agrupado, total y
+// elementos. Una celda con un solo punto conserva sus propiedades.
+func Capa(puntos []Punto, zoom *int, caja *Caja) FeatureCollection {
+	visibles := puntos[:0:0]
+	for _, p := range puntos {
+		if caja == nil || caja.Contiene(p.Coordenadas) {
+			visibles = append(visibles, p)
+		}
+	}
+
+	capa := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	if zoom == nil || *zoom >= ZoomSinAgrupar {
+		for _, p := range visibles {
+			capa.Features = append(capa.Features, feature(p.Coordenadas, conTotal(p.Propiedades, p.Peso)))
+		}
+		return capa
+	}
+
+	tamanio := 60 / math.Pow(2, float64(*zoom))
+	type celda struct{ fila, columna int }
+	grupos := make(map[celda][]Punto)
+	var orden []celda
+	for _, p := range visibles {
+		c := celda{int(math.Floor(p.Latitud / tamanio)), int(math.Floor(p.Longitud / tamanio))}
+		if _, ok := grupos[c]; !ok {
+			orden = append(orden, c)
+		}
+		grupos[c] = append(grupos[c], p)
+	}
+
+	for _, c := range orden {
+		grupo := grupos[c]
+		if len(grupo) == 1 {
+			capa.Features = append(capa.Features, feature(grupo[0].Coordenadas, conTotal(grupo[0].Propiedades, grupo[0].Peso)))
+			continue
+		}
+		var total int64
+		var lat, lng, suma float64
+		for _, p := range grupo {
+			peso := float64(max(p.Peso, 1))
+			total += p.Peso
+			lat += p.Latitud * peso
+			lng += p.Longitud * peso
+			suma += peso
+		}
+		centro := Coordenadas{Latitud: redondear(lat / suma), Longitud: redondear(lng / suma)}
+		capa.Features = append(capa.Features, feature(centro, map[string]any{
+			"agrupado":  true,
+			"total":     total,
+			"elementos": len(grupo),
+		}))
+	}
+	sort.SliceStable(capa.Features, func(i, j int) bool {
+		return pesoFeature(capa.Features[i]) > pesoFeature(capa.Features[j])
+	})
+	return capa
+}
+
+// feature usa el orden de GeoJSON: longitud, latitud.
+func feature(c Coordenadas, propiedades map[string]any) Feature {
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Geometria{Type: "Point", Coordinates: [2]float64{c.Longitud, c.Latitud}},
+		Properties: propiedades,
+	}
+}
+
+func conTotal(propiedades map[string]any, peso int64) map[string]any {
+	copia := make(map[string]any, len(propiedades)+1)
+	for k, v := range propiedades {
+		copia[k] = v
+	}
+	copia["total"] = peso
+	return copia
+}
+
+func pesoFeature(f Feature) int64 {
+	total, _ := f.Properties["total"].(int64)
+	return total
+}
+
+func redondear(v float64) float64 {
+	return math.Round(v*1e5) / 1e5
+}
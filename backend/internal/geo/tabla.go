@@ -0,0 +1,207 @@
+package geo
+
+import (
+	"strings"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type lugar struct {
+	nombre   string
+	estado   string
+	lat, lng float64
+	alias    []string
+}
+
+// municipios cubre los 20 municipios de Sinaloa, las capitales de los demás
+// estados y las ciudades donde hay familias o puertos de llegada. Las
+// coordenadas son las de la cabecera municipal.
+var municipios = []lugar{
+	{"Culiacán", "Sinaloa", 24.8091, -107.3940, []string{"Culiacán Rosales"}},
+	{"Mazatlán", "Sinaloa", 23.2494, -106.4111, nil},
+	{"Ahome", "Sinaloa", 25.7904, -108.9858, []string{"Los Mochis"}},
+	{"Guasave", "Sinaloa", 25.5675, -108.4697, nil},
+	{"Navolato", "Sinaloa", 24.7656, -107.7017, nil},
+	{"Salvador Alvarado", "Sinaloa", 25.4600, -108.0797, []string{"Guamúchil"}},
+	{"El Fuerte", "Sinaloa", 26.4214, -108.6197, nil},
+	{"Escuinapa", "Sinaloa", 22.8336, -105.7753, []string{"Escuinapa de Hidalgo"}},
+	{"Rosario", "Sinaloa", 22.9917, -105.8572, []string{"El Rosario"}},
+	{"Concordia", "Sinaloa", 23.2878, -106.0667, nil},
+	{"Cosalá", "Sinaloa", 24.4128, -106.6911, nil},
+	{"Elota", "Sinaloa", 23.9208, -106.8925, []string{"La Cruz"}},
+	{"San Ignacio", "Sinaloa", 23.9394, -106.4244, nil},
+	{"Mocorito", "Sinaloa", 25.4833, -107.9167, nil},
+	{"Badiraguato", "Sinaloa", 25.3625, -107.5506, nil},
+	{"Angostura", "Sinaloa", 25.3650, -108.1625, nil},
+	{"Sinaloa", "Sinaloa", 25.8231, -108.2222, []string{"Sinaloa de Leyva"}},
+	{"Choix", "Sinaloa", 26.7097, -108.3211, nil},
+	{"Eldorado", "Sinaloa", 24.3233, -107.3642, nil},
+	{"Juan José Ríos", "Sinaloa", 25.7606, -108.8239, nil},
+	{"Topolobampo", "Sinaloa", 25.6000, -109.0500, nil},
+
+	{"Aguascalientes", "Aguascalientes", 21.8853, -102.2916, nil},
+	{"Tijuana", "Baja California", 32.5149, -117.0382, nil},
+	{"Mexicali", "Baja California", 32.6245, -115.4523, nil},
+	{"Ensenada", "Baja California", 31.8667, -116.5964, nil},
+	{"La Paz", "Baja California Sur", 24.1426, -110.3128, nil},
+	{"Los Cabos", "Baja California Sur", 23.0605, -109.6977, []string{"San José del Cabo", "Cabo San Lucas"}},
+	{"Campeche", "Campeche", 19.8301, -90.5349, nil},
+	{"Tuxtla Gutiérrez", "Chiapas", 16.7516, -93.1161, nil},
+	{"Tapachula", "Chiapas", 14.9039, -92.2575, nil},
+	{"Chihuahua", "Chihuahua", 28.6320, -106.0691, nil},
+	{"Juárez", "Chihuahua", 31.6904, -106.4245, []string{"Ciudad Juárez"}},
+	{"Ciudad de México", "Ciudad de México", 19.4326, -99.1332, []string{"CDMX", "México DF", "Distrito Federal"}},
+	{"Saltillo", "Coahuila", 25.4232, -101.0053, nil},
+	{"Torreón", "Coahuila", 25.5428, -103.4068, nil},
+	{"Colima", "Colima", 19.2452, -103.7241, nil},
+	{"Manzanillo", "Colima", 19.0522, -104.3158, nil},
+	{"Durango", "Durango", 24.0277, -104.6532, []string{"Victoria de Durango"}},
+	{"Guanajuato", "Guanajuato", 21.0190, -101.2574, nil},
+	{"León", "Guanajuato", 21.1250, -101.6860, []string{"León de los Aldama"}},
+	{"Chilpancingo", "Guerrero", 17.5515, -99.5006, []string{"Chilpancingo de los Bravo"}},
+	{"Acapulco", "Guerrero", 16.8531, -99.8237, []string{"Acapulco de Juárez"}},
+	{"Pachuca", "Hidalgo", 20.1011, -98.7591, []string{"Pachuca de Soto"}},
+	{"Guadalajara", "Jalisco", 20.6597, -103.3496, nil},
+	{"Zapopan", "Jalisco", 20.7236, -103.3848, nil},
+	{"Puerto Vallarta", "Jalisco", 20.6534, -105.2253, nil},
+	{"Toluca", "México", 19.2826, -99.6557, []string{"Toluca de Lerdo"}},
+	{"Morelia", "Michoacán", 19.7060, -101.1950, nil},
+	{"Cuernavaca", "Morelos", 18.9242, -99.2216, nil},
+	{"Tepic", "Nayarit", 21.5042, -104.8946, nil},
+	{"Monterrey", "Nuevo León", 25.6866, -100.3161, nil},
+	{"Oaxaca de Juárez", "Oaxaca", 17.0732, -96.7266, []string{"Oaxaca"}},
+	{"Salina Cruz", "Oaxaca", 16.1667, -95.2000, nil},
+	{"Puebla", "Puebla", 19.0414, -98.2063, []string{"Puebla de Zaragoza"}},
+	{"Querétaro", "Querétaro", 20.5888, -100.3899, []string{"Santiago de Querétaro"}},
+	{"Othón P. Blanco", "Quintana Roo", 18.5001, -88.2961, []string{"Chetumal"}},
+	{"Benito Juárez", "Quintana Roo", 21.1619, -86.8515, []string{"Cancún"}},
+	{"San Luis Potosí", "San Luis Potosí", 22.1565, -100.9855, nil},
+	{"Hermosillo", "Sonora", 29.0729, -110.9559, nil},
+	{"Cajeme", "Sonora", 27.4828, -109.9304, []string{"Ciudad Obregón"}},
+	{"Guaymas", "Sonora", 27.9179, -110.8989, nil},
+	{"Navojoa", "Sonora", 27.0811, -109.4456, nil},
+	{"Nogales", "Sonora", 31.3086, -110.9422, nil},
+	{"Centro", "Tabasco", 17.9895, -92.9475, []string{"Villahermosa"}},
+	{"Victoria", "Tamaulipas", 23.7369, -99.1411, []string{"Ciudad Victoria"}},
+	{"Tampico", "Tamaulipas", 22.2331, -97.8611, nil},
+	{"Reynosa", "Tamaulipas", 26.0508, -98.2979, nil},
+	{"Tlaxcala", "Tlaxcala", 19.3182, -98.2375, []string{"Tlaxcala de Xicohténcatl"}},
+	{"Xalapa", "Veracruz", 19.5438, -96.9102, []string{"Jalapa", "Xalapa-Enríquez"}},
+	{"Veracruz", "Veracruz", 19.1738, -96.1342, []string{"Heroica Veracruz"}},
+	{"Mérida", "Yucatán", 20.9674, -89.5926, nil},
+	{"Zacatecas", "Zacatecas", 22.7709, -102.5833, nil},
+}
+
+// capitales da la ciudad que representa a cada estado cuando solo se conoce
+// el estado.
+var capitales = map[string]string{
+	"Aguascalientes": "Aguascalientes", "Baja California": "Mexicali", "Baja California Sur": "La Paz",
+	"Campeche": "Campeche", "Chiapas": "Tuxtla Gutiérrez", "Chihuahua": "Chihuahua",
+	"Ciudad de México": "Ciudad de México", "Coahuila": "Saltillo", "Colima": "Colima",
+	"Durango": "Durango", "Guanajuato": "Guanajuato", "Guerrero": "Chilpancingo",
+	"Hidalgo": "Pachuca", "Jalisco": "Guadalajara", "México": "Toluca", "Michoacán": "Morelia",
+	"Morelos": "Cuernavaca", "Nayarit": "Tepic", "Nuevo León": "Monterrey", "Oaxaca": "Oaxaca de Juárez",
+	"Puebla": "Puebla", "Querétaro": "Querétaro", "Quintana Roo": "Othón P. Blanco",
+	"San Luis Potosí": "San Luis Potosí", "Sinaloa": "Culiacán", "Sonora": "Hermosillo",
+	"Tabasco": "Centro", "Tamaulipas": "Victoria", "Tlaxcala": "Tlaxcala", "Veracruz": "Xalapa",
+	"Yucatán": "Mérida", "Zacatecas": "Zacatecas",
+}
+
+// aliasEstados son las abreviaturas y nombres oficiales largos más comunes.
+var aliasEstados = map[string]string{
+	"sin": "Sinaloa", "bc": "Baja California", "bcs": "Baja California Sur", "son": "Sonora",
+	"cdmx": "Ciudad de México", "df": "Ciudad de México", "distrito federal": "Ciudad de México",
+	"estado de mexico": "México", "edomex": "México", "jal": "Jalisco", "nl": "Nuevo León",
+	"coahuila de zaragoza": "Coahuila", "michoacan de ocampo": "Michoacán",
+	"veracruz de ignacio de la llave": "Veracruz", "nay": "Nayarit", "dgo": "Durango", "chih": "Chihuahua",
+}
+
+// capitalesPrefectura son las coordenadas de la capital de cada prefectura,
+// por el nombre de japones.Prefecturas.
+var capitalesPrefectura = map[string]Coordenadas{
+	"Hokkaidō": {43.0642, 141.3469}, "Aomori": {40.8244, 140.7400}, "Iwate": {39.7036, 141.1527},
+	"Miyagi": {38.2688, 140.8721}, "Akita": {39.7186, 140.1024}, "Yamagata": {38.2404, 140.3633},
+	"Fukushima": {37.7500, 140.4678}, "Ibaraki": {36.3418, 140.4468}, "Tochigi": {36.5657, 139.8836},
+	"Gunma": {36.3911, 139.0608}, "Saitama": {35.8570, 139.6489}, "Chiba": {35.6050, 140.1233},
+	"Tōkyō": {35.6895, 139.6917}, "Kanagawa": {35.4478, 139.6425}, "Niigata": {37.9026, 139.0236},
+	"Toyama": {36.6953, 137.2113}, "Ishikawa": {36.5947, 136.6256}, "Fukui": {36.0652, 136.2216},
+	"Yamanashi": {35.6642, 138.5684}, "Nagano": {36.6513, 138.1810}, "Gifu": {35.3912, 136.7223},
+	"Shizuoka": {34.9769, 138.3831}, "Aichi": {35.1802, 136.9066}, "Mie": {34.7303, 136.5086},
+	"Shiga": {35.0045, 135.8686}, "Kyōto": {35.0116, 135.7681}, "Ōsaka": {34.6937, 135.5023},
+	"Hyōgo": {34.6913, 135.1830}, "Nara": {34.6851, 135.8048}, "Wakayama": {34.2260, 135.1675},
+	"Tottori": {35.5011, 134.2351}, "Shimane": {35.4723, 133.0505}, "Okayama": {34.6618, 133.9344},
+	"Hiroshima": {34.3853, 132.4553}, "Yamaguchi": {34.1859, 131.4714}, "Tokushima": {34.0658, 134.5593},
+	"Kagawa": {34.3401, 134.0434}, "Ehime": {33.8416, 132.7657}, "Kōchi": {33.5597, 133.5311},
+	"Fukuoka": {33.6064, 130.4181}, "Saga": {33.2494, 130.2988}, "Nagasaki": {32.7448, 129.8737},
+	"Kumamoto": {32.7898, 130.7417}, "Ōita": {33.2382, 131.6126}, "Miyazaki": {31.9111, 131.4239},
+	"Kagoshima": {31.5602, 130.5581}, "Okinawa": {26.2124, 127.6809},
+}
+
+// TablaLocal es el geocodificador sin conexión, con los datos de este
+// paquete.
+type TablaLocal struct {
+	porNombre map[string][]*lugar
+	estados   map[string]string
+}
+
+func NewTablaLocal() *TablaLocal {
+	t := &TablaLocal{porNombre: make(map[string][]*lugar), estados: make(map[string]string)}
+	for i := range municipios {
+		m := &municipios[i]
+		for _, nombre := range append([]string{m.nombre}, m.alias...) {
+			clave := normalizarLugar(nombre)
+			t.porNombre[clave] = append(t.porNombre[clave], m)
+		}
+	}
+	for estado := range capitales {
+		t.estados[utils.NormalizarTexto(estado)] = estado
+	}
+	for alias, estado := range aliasEstados {
+		t.estados[alias] = estado
+	}
+	return t
+}
+
+func (t *TablaLocal) Municipio(municipio, estado string) (Coordenadas, error) {
+	nombreEstado, estadoConocido := t.estados[utils.NormalizarTexto(estado)]
+	if strings.TrimSpace(municipio) == "" {
+		if !estadoConocido {
+			return Coordenadas{}, ErrUbicacionDesconocida
+		}
+		municipio, estado = capitales[nombreEstado], nombreEstado
+		return t.Municipio(municipio, estado)
+	}
+
+	candidatos := t.porNombre[normalizarLugar(municipio)]
+	if !estadoConocido {
+		nombreEstado = "Sinaloa"
+	}
+	for _, c := range candidatos {
+		if c.estado == nombreEstado {
+			return Coordenadas{c.lat, c.lng}, nil
+		}
+	}
+	if !estadoConocido && len(candidatos) == 1 {
+		return Coordenadas{candidatos[0].lat, candidatos[0].lng}, nil
+	}
+	return Coordenadas{}, ErrUbicacionDesconocida
+}
+
+func (t *TablaLocal) Prefectura(nombre string) (Coordenadas, error) {
+	p, ok := japones.BuscarPrefectura(nombre)
+	if !ok {
+		return Coordenadas{}, ErrUbicacionDesconocida
+	}
+	return capitalesPrefectura[p.Nombre], nil
+}
+
+// normalizarLugar quita acentos y expande "Cd." para que "Cd. Obregón" y
+// "Ciudad Obregón" coincidan.
+func normalizarLugar(s string) string {
+	texto := utils.NormalizarTexto(s)
+	if resto, ok := strings.CutPrefix(texto, "cd "); ok {
+		texto = "ciudad " + resto
+	}
+	return texto
+}
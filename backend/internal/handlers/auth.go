@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type AuthHandler struct {
+	authService *services.AuthService
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{authService: authService}
+}
+
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "datos_invalidos", "Se requiere un correo y una contraseña válidos")
+		return
+	}
+
+	tokens, err := h.authService.Login(req.Email, req.Password, services.ClienteInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Sesión iniciada", tokens)
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "datos_invalidos", "Se requiere el refresh_token")
+		return
+	}
+
+	tokens, err := h.authService.Refresh(req.RefreshToken)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Tokens renovados", tokens)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "datos_invalidos", "Se requiere el refresh_token")
+		return
+	}
+
+	if err := h.authService.Logout(req.RefreshToken); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Sesión cerrada", nil)
+}
+
+func (h *AuthHandler) Me(c *gin.Context) {
+	perfil, err := h.authService.GetPerfil(middleware.CurrentUser(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", perfil)
+}
+
+func (h *AuthHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCredencialesInvalidas):
+		utils.Error(c, http.StatusUnauthorized, "credenciales_invalidas", err.Error())
+	case errors.Is(err, services.ErrUsuarioInactivo):
+		utils.Error(c, http.StatusForbidden, "usuario_inactivo", err.Error())
+	case errors.Is(err, services.ErrSesionInvalida), errors.Is(err, utils.ErrTokenInvalido):
+		utils.Error(c, http.StatusUnauthorized, "token_invalido", err.Error())
+	default:
+		log.Printf("Error en autenticación: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type CheckinHandler struct {
+	checkinService  *services.CheckinService
+	participaciones *ParticipacionHandler
+}
+
+type CheckinRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func NewCheckinHandler(checkinService *services.CheckinService, participaciones *ParticipacionHandler) *CheckinHandler {
+	return &CheckinHandler{checkinService: checkinService, participaciones: participaciones}
+}
+
+// QR devuelve el código de acceso como PNG. ?size= ajusta los pixeles.
+func (h *CheckinHandler) QR(c *gin.Context) {
+	id, ok := h.participaciones.autorizar(c)
+	if !ok {
+		return
+	}
+
+	size, err := strconv.Atoi(c.DefaultQuery("size", "256"))
+	if err != nil || size < 128 || size > 1024 {
+		size = 256
+	}
+
+	png, err := h.checkinService.QR(id, size)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+func (h *CheckinHandler) Token(c *gin.Context) {
+	id, ok := h.participaciones.autorizar(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.checkinService.Token(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", gin.H{"token": token})
+}
+
+func (h *CheckinHandler) RegistrarAsistencia(c *gin.Context) {
+	idEvento, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req CheckinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	resultado, err := h.checkinService.RegistrarAsistencia(idEvento, req.Token)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	mensaje := "Asistencia registrada"
+	if resultado.YaRegistrado {
+		mensaje = "La asistencia ya estaba registrada"
+	}
+	utils.Success(c, http.StatusOK, mensaje, resultado)
+}
+
+func (h *CheckinHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrCheckinInvalido):
+		utils.Error(c, http.StatusBadRequest, "checkin_invalido", err.Error())
+	case errors.Is(err, services.ErrCheckinOtroEvento):
+		utils.Error(c, http.StatusConflict, "checkin_otro_evento", err.Error())
+	case errors.Is(err, services.ErrCheckinNoDisponible):
+		utils.Error(c, http.StatusConflict, "checkin_no_disponible", err.Error())
+	case errors.Is(err, services.ErrCheckinEventoCerrado):
+		utils.Error(c, http.StatusConflict, "evento_cerrado", err.Error())
+	case errors.Is(err, services.ErrParticipacionNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "participacion_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrEventoNoEncontrado):
+		utils.Error(c, http.StatusNotFound, "evento_no_encontrado", err.Error())
+	default:
+		log.Printf("Error en check-in: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type CuentaHandler struct {
+	cuentaService *services.CuentaService
+}
+
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+func NewCuentaHandler(cuentaService *services.CuentaService) *CuentaHandler {
+	return &CuentaHandler{cuentaService: cuentaService}
+}
+
+func (h *CuentaHandler) VerificarEmail(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "datos_invalidos", "Se requiere el token")
+		return
+	}
+
+	user, err := h.cuentaService.VerificarEmail(req.Token)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Correo verificado", user)
+}
+
+func (h *CuentaHandler) ReenviarVerificacion(c *gin.Context) {
+	if err := h.cuentaService.EnviarVerificacion(middleware.CurrentUser(c)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Correo de verificación enviado", nil)
+}
+
+func (h *CuentaHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "datos_invalidos", "Se requiere un correo válido")
+		return
+	}
+
+	if err := h.cuentaService.SolicitarResetPassword(req.Email); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Si el correo está registrado recibirás un enlace para restablecer tu contraseña", nil)
+}
+
+func (h *CuentaHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "datos_invalidos", "Se requiere el token y una contraseña de al menos 8 caracteres")
+		return
+	}
+
+	if err := h.cuentaService.ResetPassword(req.Token, req.Password); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Contraseña actualizada, inicia sesión de nuevo", nil)
+}
+
+func (h *CuentaHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTokenCuentaInvalido):
+		utils.Error(c, http.StatusBadRequest, "token_invalido", err.Error())
+	case errors.Is(err, services.ErrEmailYaVerificado):
+		utils.Error(c, http.StatusConflict, "email_ya_verificado", err.Error())
+	default:
+		log.Printf("Error en cuenta: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type DirectorioHandler struct {
+	directorioService *services.DirectorioService
+}
+
+func NewDirectorioHandler(directorioService *services.DirectorioService) *DirectorioHandler {
+	return &DirectorioHandler{directorioService: directorioService}
+}
+
+// Buscar solo muestra registros sin consentimiento a los administradores.
+func (h *DirectorioHandler) Buscar(c *gin.Context) {
+	var filtros services.DirectorioFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	resultado, err := h.directorioService.Buscar(filtros, user != nil && user.EsAdmin())
+	if err != nil {
+		log.Printf("Error en directorio: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", resultado)
+}
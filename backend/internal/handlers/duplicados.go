@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type DuplicadosHandler struct {
+	duplicadosService *services.DuplicadosService
+}
+
+func NewDuplicadosHandler(duplicadosService *services.DuplicadosService) *DuplicadosHandler {
+	return &DuplicadosHandler{duplicadosService: duplicadosService}
+}
+
+func (h *DuplicadosHandler) Candidatos(c *gin.Context) {
+	var filtros services.DuplicadosFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	candidatos, err := h.duplicadosService.Candidatos(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", candidatos)
+}
+
+// Fusionar conserva la persona de la URL y elimina la indicada en el cuerpo.
+func (h *DuplicadosHandler) Fusionar(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input services.FusionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	resultado, err := h.duplicadosService.Fusionar(id, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Personas fusionadas", resultado)
+}
+
+func (h *DuplicadosHandler) handleError(c *gin.Context, err error) {
+	var fields utils.FieldErrors
+	switch {
+	case errors.As(err, &fields):
+		utils.ValidationError(c, fields)
+	case errors.Is(err, services.ErrPersonaNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "persona_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrFusionUsuarios):
+		utils.Error(c, http.StatusConflict, "fusion_usuarios", err.Error())
+	case errors.Is(err, services.ErrFusionEmpresas):
+		utils.Error(c, http.StatusConflict, "fusion_empresas", err.Error())
+	case errors.Is(err, services.ErrPersonaConReferencias):
+		utils.Error(c, http.StatusConflict, "persona_con_referencias", err.Error())
+	default:
+		log.Printf("Error en duplicados: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
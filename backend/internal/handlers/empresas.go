@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type EmpresaHandler struct {
+	empresaService *services.EmpresaService
+}
+
+func NewEmpresaHandler(empresaService *services.EmpresaService) *EmpresaHandler {
+	return &EmpresaHandler{empresaService: empresaService}
+}
+
+func (h *EmpresaHandler) Directorio(c *gin.Context) {
+	var filtros services.EmpresaDirectorioFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	resultado, err := h.empresaService.Directorio(filtros, time.Now())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", resultado)
+}
+
+func (h *EmpresaHandler) GetDirectorio(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	empresa, err := h.empresaService.GetDirectorio(id, time.Now())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", empresa)
+}
+
+func (h *EmpresaHandler) Sectores(c *gin.Context) {
+	sectores, err := h.empresaService.Sectores()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", sectores)
+}
+
+func (h *EmpresaHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrEmpresaNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "empresa_no_encontrada", err.Error())
+	default:
+		log.Printf("Error en empresas: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type EventoHandler struct {
+	eventoService *services.EventoService
+}
+
+func NewEventoHandler(eventoService *services.EventoService) *EventoHandler {
+	return &EventoHandler{eventoService: eventoService}
+}
+
+func (h *EventoHandler) List(c *gin.Context) {
+	var filtros services.EventoFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	resultado, err := h.eventoService.List(filtros, middleware.CurrentUser(c).EsAdmin())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", resultado)
+}
+
+func (h *EventoHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	evento, err := h.eventoService.Get(id, middleware.CurrentUser(c).EsAdmin())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", evento)
+}
+
+func (h *EventoHandler) Create(c *gin.Context) {
+	var input services.EventoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	evento, err := h.eventoService.Create(middleware.CurrentUser(c).IDUser, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusCreated, "Evento creado como borrador", evento)
+}
+
+func (h *EventoHandler) Update(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input services.EventoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	evento, err := h.eventoService.Update(id, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Evento actualizado", evento)
+}
+
+func (h *EventoHandler) Delete(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.eventoService.Delete(id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Evento eliminado", nil)
+}
+
+func (h *EventoHandler) Publicar(c *gin.Context) {
+	h.transicion(c, h.eventoService.Publicar, "Evento publicado")
+}
+
+func (h *EventoHandler) Cancelar(c *gin.Context) {
+	h.transicion(c, h.eventoService.Cancelar, "Evento cancelado")
+}
+
+func (h *EventoHandler) Finalizar(c *gin.Context) {
+	h.transicion(c, h.eventoService.Finalizar, "Evento finalizado")
+}
+
+func (h *EventoHandler) transicion(c *gin.Context, accion func(uint) (*models.Evento, error), mensaje string) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	evento, err := accion(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, mensaje, evento)
+}
+
+func (h *EventoHandler) handleError(c *gin.Context, err error) {
+	var fields utils.FieldErrors
+	var transicion *services.ErrTransicionInvalida
+	switch {
+	case errors.As(err, &fields):
+		utils.ValidationError(c, fields)
+	case errors.As(err, &transicion):
+		utils.ErrorWithDetails(c, http.StatusConflict, "transicion_invalida", err.Error(),
+			gin.H{"status_actual": transicion.Desde, "status_solicitado": transicion.Hacia})
+	case errors.Is(err, services.ErrEventoNoEncontrado):
+		utils.Error(c, http.StatusNotFound, "evento_no_encontrado", err.Error())
+	case errors.Is(err, services.ErrEventoNoEditable):
+		utils.Error(c, http.StatusConflict, "evento_no_editable", err.Error())
+	case errors.Is(err, services.ErrEventoNoBorrable):
+		utils.Error(c, http.StatusConflict, "evento_no_borrable", err.Error())
+	default:
+		log.Printf("Error en eventos: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
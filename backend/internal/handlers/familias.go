@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type FamiliaHandler struct {
+	familiaService *services.FamiliaService
+}
+
+func NewFamiliaHandler(familiaService *services.FamiliaService) *FamiliaHandler {
+	return &FamiliaHandler{familiaService: familiaService}
+}
+
+func (h *FamiliaHandler) List(c *gin.Context) {
+	var filtros services.FamiliaFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	resultado, err := h.familiaService.List(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", resultado)
+}
+
+func (h *FamiliaHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	detalle, err := h.familiaService.GetDetalle(id, middleware.CurrentUser(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", detalle)
+}
+
+func (h *FamiliaHandler) Create(c *gin.Context) {
+	var input services.FamiliaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	familia, err := h.familiaService.Create(input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusCreated, "Familia creada", familia)
+}
+
+func (h *FamiliaHandler) Update(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input services.FamiliaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	familia, err := h.familiaService.Update(id, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Familia actualizada", familia)
+}
+
+func (h *FamiliaHandler) Delete(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.familiaService.Delete(id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Familia eliminada", nil)
+}
+
+func (h *FamiliaHandler) handleError(c *gin.Context, err error) {
+	var fields utils.FieldErrors
+	switch {
+	case errors.As(err, &fields):
+		utils.ValidationError(c, fields)
+	case errors.Is(err, services.ErrFamiliaNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "familia_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrFamiliaConPersonas):
+		utils.Error(c, http.StatusConflict, "familia_con_personas", err.Error())
+	default:
+		log.Printf("Error en familias: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
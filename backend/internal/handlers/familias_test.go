@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+)
+
+func TestEliminarFamilia(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	casos := []struct {
+		nombre   string
+		existe   bool
+		personas int64
+		status   int
+	}{
+		{"vacía", true, 0, http.StatusOK},
+		{"con personas", true, 3, http.StatusConflict},
+		{"inexistente", false, 0, http.StatusNotFound},
+	}
+	for _, c := range casos {
+		db, base := dbtest.Nueva(t)
+		if c.existe {
+			base.Responder(`FROM "familias"`, dbtest.Filas([]string{"id_familia", "apellido_jp"},
+				[]driver.Value{int64(4), "Tanaka"}))
+		}
+		base.Responder(`count\(\*\) FROM "personas" WHERE id_familia`, dbtest.Conteo(c.personas))
+		base.Responder(`DELETE FROM "familias"`, dbtest.Respuesta{Afectadas: 1})
+
+		router := gin.New()
+		router.DELETE("/familias/:id", NewFamiliaHandler(services.NewFamiliaService(db)).Delete)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/familias/4", nil))
+
+		if w.Code != c.status {
+			t.Errorf("%s: status = %d; se esperaba %d (%s)", c.nombre, w.Code, c.status, w.Body)
+		}
+		if borrada := base.Ejecutada(`DELETE FROM "familias"`); borrada != (c.status == http.StatusOK) {
+			t.Errorf("%s: DELETE ejecutado = %v", c.nombre, borrada)
+		}
+	}
+}
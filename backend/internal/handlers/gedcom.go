@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+const maxTamanoGedcom = 10 << 20
+
+type GedcomHandler struct {
+	gedcomService *services.GedcomService
+}
+
+func NewGedcomHandler(gedcomService *services.GedcomService) *GedcomHandler {
+	return &GedcomHandler{gedcomService: gedcomService}
+}
+
+// Importar acepta el archivo en el campo multipart "archivo" o como cuerpo
+// directo. Sin ?confirmar=true solo devuelve la vista previa.
+func (h *GedcomHandler) Importar(c *gin.Context) {
+	var opciones services.ImportarGedcomOpciones
+	if err := c.ShouldBindQuery(&opciones); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTamanoGedcom)
+
+	var lector io.Reader = c.Request.Body
+	if archivo, err := c.FormFile("archivo"); err == nil {
+		f, err := archivo.Open()
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, "datos_invalidos", "No se pudo leer el archivo")
+			return
+		}
+		defer f.Close()
+		lector = f
+	}
+
+	data, err := io.ReadAll(lector)
+	if err != nil {
+		utils.Error(c, http.StatusRequestEntityTooLarge, "archivo_muy_grande", "El archivo supera los 10 MB")
+		return
+	}
+	if len(data) == 0 {
+		utils.Error(c, http.StatusBadRequest, "datos_invalidos", "No se recibió ningún archivo")
+		return
+	}
+
+	reporte, err := h.gedcomService.Importar(data, opciones)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	mensaje := "Vista previa de la importación; nada se guardó"
+	status := http.StatusOK
+	if reporte.Confirmado {
+		mensaje = "Importación completada"
+		status = http.StatusCreated
+	}
+	utils.Success(c, status, mensaje, reporte)
+}
+
+func (h *GedcomHandler) ExportarFamilia(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var opciones services.GedcomExportOpciones
+	if err := c.ShouldBindQuery(&opciones); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	archivo, err := h.gedcomService.ExportarFamilia(middleware.CurrentUser(c), id, opciones)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.enviar(c, archivo)
+}
+
+func (h *GedcomHandler) ExportarPersona(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var opciones services.GedcomExportOpciones
+	if err := c.ShouldBindQuery(&opciones); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	archivo, err := h.gedcomService.ExportarPersona(middleware.CurrentUser(c), id, opciones)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.enviar(c, archivo)
+}
+
+// enviar entrega el archivo solo a un admin o a alguien que aparece en él.
+func (h *GedcomHandler) enviar(c *gin.Context, archivo *services.ArchivoGedcom) {
+	user := middleware.CurrentUser(c)
+	if !user.EsAdmin() && (user.IDPersona == nil || !slices.Contains(archivo.Personas, *user.IDPersona)) {
+		utils.Error(c, http.StatusForbidden, "acceso_denegado", "Solo puedes exportar árboles en los que apareces")
+		return
+	}
+
+	tipo := "application/x-gedcom; charset=utf-8"
+	if archivo.Version == "7.0" {
+		tipo = "text/vnd.familysearch.gedcom; charset=utf-8"
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+archivo.Nombre+`"`)
+	c.Data(http.StatusOK, tipo, archivo.Contenido)
+}
+
+func (h *GedcomHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrGedcomInvalido):
+		utils.Error(c, http.StatusUnprocessableEntity, "gedcom_invalido", err.Error())
+	case errors.Is(err, services.ErrFamiliaNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "familia_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrPersonaNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "persona_no_encontrada", err.Error())
+	default:
+		log.Printf("Error en GEDCOM: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type GenealogiaHandler struct {
+	genealogiaService *services.GenealogiaService
+}
+
+func NewGenealogiaHandler(genealogiaService *services.GenealogiaService) *GenealogiaHandler {
+	return &GenealogiaHandler{genealogiaService: genealogiaService}
+}
+
+func (h *GenealogiaHandler) ListarPorPersona(c *gin.Context) {
+	idPersona, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	relaciones, err := h.genealogiaService.ListarPorPersona(middleware.CurrentUser(c), idPersona)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", relaciones)
+}
+
+func (h *GenealogiaHandler) Arbol(c *gin.Context) {
+	idPersona, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var filtros services.ArbolFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	arbol, err := h.genealogiaService.Arbol(middleware.CurrentUser(c), idPersona, filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", arbol)
+}
+
+// Parentesco dice qué es la persona id_otro para la persona id.
+func (h *GenealogiaHandler) Parentesco(c *gin.Context) {
+	idPersona, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	idOtro, ok := parseIDParam(c, "id_otro")
+	if !ok {
+		return
+	}
+
+	parentesco, err := h.genealogiaService.Parentesco(middleware.CurrentUser(c), idPersona, idOtro)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", parentesco)
+}
+
+func (h *GenealogiaHandler) Inferencias(c *gin.Context) {
+	var filtros services.InferenciaFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	resultado, err := h.genealogiaService.Inferir(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", resultado)
+}
+
+// MaterializarInferencias guarda las relaciones faltantes sin confirmar.
+func (h *GenealogiaHandler) MaterializarInferencias(c *gin.Context) {
+	var filtros services.InferenciaFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	resultado, err := h.genealogiaService.MaterializarInferencias(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Relaciones sugeridas registradas", resultado)
+}
+
+// Crear lo puede usar un admin o una de las dos personas de la relación.
+func (h *GenealogiaHandler) Crear(c *gin.Context) {
+	var input services.GenealogiaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if !user.EsAdmin() && !esParte(user, input.IDPersona, input.IDPariente) {
+		utils.Error(c, http.StatusForbidden, "acceso_denegado", "Solo puedes registrar relaciones en las que participas")
+		return
+	}
+
+	par, err := h.genealogiaService.Crear(input, user.IDPersona)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusCreated, "Relación registrada, pendiente de confirmación", par)
+}
+
+func (h *GenealogiaHandler) Actualizar(c *gin.Context) {
+	id, ok := h.autorizar(c)
+	if !ok {
+		return
+	}
+
+	var input services.GenealogiaUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	par, err := h.genealogiaService.Actualizar(id, input, middleware.CurrentUser(c).IDPersona)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Relación actualizada, pendiente de confirmación", par)
+}
+
+func (h *GenealogiaHandler) Eliminar(c *gin.Context) {
+	id, ok := h.autorizar(c)
+	if !ok {
+		return
+	}
+
+	if err := h.genealogiaService.Eliminar(id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Relación eliminada", nil)
+}
+
+// Confirmar la aprueba la parte que no la solicitó; un admin puede confirmar
+// cualquier relación.
+func (h *GenealogiaHandler) Confirmar(c *gin.Context) {
+	id, ok := h.autorizar(c)
+	if !ok {
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	var idPersona *uint
+	if !user.EsAdmin() {
+		idPersona = user.IDPersona
+	}
+
+	par, err := h.genealogiaService.Confirmar(id, idPersona)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Relación confirmada", par)
+}
+
+// autorizar permite operar una relación solo a sus dos personas o a un admin.
+func (h *GenealogiaHandler) autorizar(c *gin.Context) (uint, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return 0, false
+	}
+
+	user := middleware.CurrentUser(c)
+	if user.EsAdmin() {
+		return id, true
+	}
+
+	relacion, err := h.genealogiaService.Get(id)
+	if err != nil {
+		h.handleError(c, err)
+		return 0, false
+	}
+	if !esParte(user, relacion.IDPersona, relacion.IDPariente) {
+		utils.Error(c, http.StatusForbidden, "acceso_denegado", "No participas en esta relación")
+		return 0, false
+	}
+	return id, true
+}
+
+func esParte(user *models.User, idPersona, idPariente uint) bool {
+	return user.IDPersona != nil && (*user.IDPersona == idPersona || *user.IDPersona == idPariente)
+}
+
+func (h *GenealogiaHandler) handleError(c *gin.Context, err error) {
+	var fields utils.FieldErrors
+	switch {
+	case errors.As(err, &fields):
+		utils.ValidationError(c, fields)
+	case errors.Is(err, services.ErrPersonaNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "persona_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrRelacionNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "relacion_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrRelacionDuplicada):
+		utils.Error(c, http.StatusConflict, "relacion_duplicada", err.Error())
+	case errors.Is(err, services.ErrSinParentesco):
+		utils.Error(c, http.StatusNotFound, "sin_parentesco", err.Error())
+	case errors.Is(err, services.ErrConfirmacionPropia):
+		utils.Error(c, http.StatusForbidden, "confirmacion_propia", err.Error())
+	default:
+		log.Printf("Error en genealogía: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
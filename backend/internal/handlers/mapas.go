@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/geo"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type MapaHandler struct {
+	geoService *services.GeoService
+}
+
+func NewMapaHandler(geoService *services.GeoService) *MapaHandler {
+	return &MapaHandler{geoService: geoService}
+}
+
+func (h *MapaHandler) Miembros(c *gin.Context) {
+	h.capa(c, h.geoService.Miembros)
+}
+
+func (h *MapaHandler) Prefecturas(c *gin.Context) {
+	h.capa(c, h.geoService.Prefecturas)
+}
+
+func (h *MapaHandler) Empresas(c *gin.Context) {
+	h.capa(c, h.geoService.Empresas)
+}
+
+func (h *MapaHandler) Geocodificar(c *gin.Context) {
+	resultado, err := h.geoService.CompletarCoordenadas()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Coordenadas actualizadas", resultado)
+}
+
+// capa responde el GeoJSON tal cual, sin el envoltorio de utils.Success,
+// para que Leaflet lo cargue directamente con L.geoJSON.
+func (h *MapaHandler) capa(c *gin.Context, obtener func(services.MapaFiltros) (*geo.FeatureCollection, error)) {
+	var filtros services.MapaFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	capa, err := obtener(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, capa)
+}
+
+func (h *MapaHandler) handleError(c *gin.Context, err error) {
+	var fields utils.FieldErrors
+	switch {
+	case errors.As(err, &fields):
+		utils.ValidationError(c, fields)
+	default:
+		log.Printf("Error en mapas: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type MigracionHandler struct {
+	migracionService *services.MigracionService
+}
+
+func NewMigracionHandler(migracionService *services.MigracionService) *MigracionHandler {
+	return &MigracionHandler{migracionService: migracionService}
+}
+
+func (h *MigracionHandler) Historia(c *gin.Context) {
+	var filtros services.MigracionFiltros
+	var params services.ExportacionParams
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	historia, err := h.migracionService.Historia(filtros, time.Now())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	if params.Format == "" || params.Format == "json" {
+		utils.Success(c, http.StatusOK, "", historia)
+		return
+	}
+
+	archivo, err := historia.Documento().Exportar(params.Format)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+archivo.Nombre+`"`)
+	c.Data(http.StatusOK, archivo.TipoContenido, archivo.Contenido)
+}
+
+func (h *MigracionHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrFormatoNoSoportado):
+		utils.Error(c, http.StatusBadRequest, "formato_no_soportado", err.Error())
+	default:
+		log.Printf("Error en historia de migración: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type ParticipacionHandler struct {
+	participacionService *services.ParticipacionService
+}
+
+type RegistroEventoRequest struct {
+	IDPersona             *uint   `json:"id_persona"`
+	Acompaniantes         int     `json:"acompaniantes" binding:"gte=0,lte=20"`
+	NotasParticipante     *string `json:"notas_participante"`
+	NecesidadesEspeciales *string `json:"necesidades_especiales"`
+}
+
+func NewParticipacionHandler(participacionService *services.ParticipacionService) *ParticipacionHandler {
+	return &ParticipacionHandler{participacionService: participacionService}
+}
+
+// Registrar inscribe al usuario actual; un admin puede inscribir a cualquier
+// persona indicando id_persona.
+func (h *ParticipacionHandler) Registrar(c *gin.Context) {
+	idEvento, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RegistroEventoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	var idPersona uint
+	switch {
+	case req.IDPersona != nil && user.EsAdmin():
+		idPersona = *req.IDPersona
+	case req.IDPersona != nil && (user.IDPersona == nil || *req.IDPersona != *user.IDPersona):
+		utils.Error(c, http.StatusForbidden, "acceso_denegado", "Solo puedes registrarte a ti mismo")
+		return
+	case user.IDPersona != nil:
+		idPersona = *user.IDPersona
+	default:
+		utils.Error(c, http.StatusUnprocessableEntity, "sin_persona", "Tu cuenta no está vinculada a una persona")
+		return
+	}
+
+	participacion, err := h.participacionService.Registrar(idEvento, services.RegistroEventoInput{
+		IDPersona:             idPersona,
+		Acompaniantes:         req.Acompaniantes,
+		NotasParticipante:     req.NotasParticipante,
+		NecesidadesEspeciales: req.NecesidadesEspeciales,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	mensaje := "Registro exitoso"
+	if participacion.EstaEnEspera() {
+		mensaje = "El evento está lleno, quedaste en lista de espera"
+	}
+	utils.Success(c, http.StatusCreated, mensaje, participacion)
+}
+
+func (h *ParticipacionHandler) ListByEvento(c *gin.Context) {
+	idEvento, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	participaciones, err := h.participacionService.ListByEvento(idEvento)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", participaciones)
+}
+
+func (h *ParticipacionHandler) Confirmar(c *gin.Context) {
+	id, ok := h.autorizar(c)
+	if !ok {
+		return
+	}
+
+	participacion, err := h.participacionService.Confirmar(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Asistencia confirmada", participacion)
+}
+
+func (h *ParticipacionHandler) Cancelar(c *gin.Context) {
+	id, ok := h.autorizar(c)
+	if !ok {
+		return
+	}
+
+	participacion, promovidos, err := h.participacionService.Cancelar(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if promovidos == nil {
+		promovidos = []models.ParticipacionEvento{}
+	}
+	utils.Success(c, http.StatusOK, "Registro cancelado", gin.H{
+		"participacion": participacion,
+		"promovidos":    promovidos,
+	})
+}
+
+// autorizar permite operar una participación solo a su titular o a un admin.
+func (h *ParticipacionHandler) autorizar(c *gin.Context) (uint, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return 0, false
+	}
+
+	user := middleware.CurrentUser(c)
+	if user.EsAdmin() {
+		return id, true
+	}
+
+	participacion, err := h.participacionService.Get(id)
+	if err != nil {
+		h.handleError(c, err)
+		return 0, false
+	}
+	if user.IDPersona == nil || *user.IDPersona != participacion.IDPersona {
+		utils.Error(c, http.StatusForbidden, "acceso_denegado", "Esta participación no te pertenece")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *ParticipacionHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrEventoNoEncontrado):
+		utils.Error(c, http.StatusNotFound, "evento_no_encontrado", err.Error())
+	case errors.Is(err, services.ErrPersonaNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "persona_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrParticipacionNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "participacion_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrEventoSinRegistro):
+		utils.Error(c, http.StatusConflict, "evento_sin_registro", err.Error())
+	case errors.Is(err, services.ErrYaRegistrado):
+		utils.Error(c, http.StatusConflict, "ya_registrado", err.Error())
+	case errors.Is(err, services.ErrParticipacionNoConfirmable):
+		utils.Error(c, http.StatusConflict, "participacion_no_confirmable", err.Error())
+	case errors.Is(err, services.ErrParticipacionNoCancelable):
+		utils.Error(c, http.StatusConflict, "participacion_no_cancelable", err.Error())
+	case errors.Is(err, services.ErrEventoIniciado):
+		utils.Error(c, http.StatusConflict, "evento_iniciado", err.Error())
+	case errors.Is(err, services.ErrPersonaNoParticipa):
+		utils.Error(c, http.StatusUnprocessableEntity, "persona_no_participa", err.Error())
+	default:
+		log.Printf("Error en participaciones: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
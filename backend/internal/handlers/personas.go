@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/middleware"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type PersonaHandler struct {
+	personaService *services.PersonaService
+}
+
+func NewPersonaHandler(personaService *services.PersonaService) *PersonaHandler {
+	return &PersonaHandler{personaService: personaService}
+}
+
+func (h *PersonaHandler) List(c *gin.Context) {
+	var filtros services.PersonaFiltros
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	filtros.RestringirPrivados = !user.EsAdmin()
+
+	resultado, err := h.personaService.List(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	vistas, err := h.personaService.Proyectar(user, resultado.Items)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", services.ListaPaginada[services.PersonaVista]{
+		Items:      vistas,
+		Pagination: resultado.Pagination,
+	})
+}
+
+func (h *PersonaHandler) Get(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	persona, err := h.personaService.GetVista(id, middleware.CurrentUser(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", persona)
+}
+
+func (h *PersonaHandler) Create(c *gin.Context) {
+	var input services.PersonaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	persona, err := h.personaService.Create(input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	h.responderVista(c, http.StatusCreated, "Persona creada", persona)
+}
+
+func (h *PersonaHandler) Update(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input services.PersonaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	persona, err := h.personaService.Update(id, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	h.responderVista(c, http.StatusOK, "Persona actualizada", persona)
+}
+
+// Patch lo puede usar un admin o la propia persona; en el segundo caso no se
+// permiten los campos administrativos.
+func (h *PersonaHandler) Patch(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	esPropia := user.IDPersona != nil && *user.IDPersona == id
+	if !user.EsAdmin() && !esPropia {
+		utils.Error(c, http.StatusForbidden, "acceso_denegado", "Solo puedes modificar tu propio registro")
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "datos_invalidos", "No se pudo leer el cuerpo de la petición")
+		return
+	}
+
+	var campos map[string]json.RawMessage
+	if err := json.Unmarshal(body, &campos); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	if !user.EsAdmin() {
+		denegados := utils.FieldErrors{}
+		for _, campo := range services.CamposSoloAdmin {
+			if _, presente := campos[campo]; presente {
+				denegados[campo] = "solo un administrador puede modificar este campo"
+			}
+		}
+		if len(denegados) > 0 {
+			utils.ErrorWithDetails(c, http.StatusForbidden, "acceso_denegado", "Algunos campos solo los puede modificar un administrador", denegados)
+			return
+		}
+	}
+
+	actual, err := h.personaService.Get(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	input := services.InputDesdePersona(actual)
+	if err := json.Unmarshal(body, &input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	persona, err := h.personaService.Update(id, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	h.responderVista(c, http.StatusOK, "Persona actualizada", persona)
+}
+
+func (h *PersonaHandler) Delete(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.personaService.Delete(id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Persona eliminada", nil)
+}
+
+// responderVista devuelve una persona recién guardada con la misma
+// proyección que GET, para que quien edita su propio registro no reciba las
+// notas administrativas.
+func (h *PersonaHandler) responderVista(c *gin.Context, status int, mensaje string, persona *models.Persona) {
+	vistas, err := h.personaService.Proyectar(middleware.CurrentUser(c), []models.Persona{*persona})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	utils.Success(c, status, mensaje, vistas[0])
+}
+
+func (h *PersonaHandler) handleError(c *gin.Context, err error) {
+	var fields utils.FieldErrors
+	switch {
+	case errors.As(err, &fields):
+		utils.ValidationError(c, fields)
+	case errors.Is(err, services.ErrPersonaNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "persona_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrPersonaConReferencias):
+		utils.Error(c, http.StatusConflict, "persona_con_referencias", err.Error())
+	default:
+		log.Printf("Error en personas: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+func TestEliminarPersona(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	casos := []struct {
+		nombre     string
+		existe     bool
+		referencia string
+		status     int
+		codigo     string
+	}{
+		{"sin referencias", true, "", http.StatusOK, ""},
+		{"con usuario", true, "users", http.StatusConflict, "persona_con_referencias"},
+		{"dueña de una empresa", true, "empresas", http.StatusConflict, "persona_con_referencias"},
+		{"con participaciones", true, "participacion_eventos", http.StatusConflict, "persona_con_referencias"},
+		{"con parientes", true, "genealogia", http.StatusConflict, "persona_con_referencias"},
+		{"inexistente", false, "", http.StatusNotFound, "persona_no_encontrada"},
+	}
+	for _, c := range casos {
+		db, base := dbtest.Nueva(t)
+		if c.existe {
+			base.Responder(`FROM "personas"`, dbtest.Filas([]string{"id_persona", "id_familia", "nombres"},
+				[]driver.Value{int64(7), int64(1), "Taro"}))
+		}
+		if c.referencia != "" {
+			base.Responder(`count\(\*\) FROM "`+c.referencia+`"`, dbtest.Conteo(1))
+		}
+		base.Responder(`count\(\*\)`, dbtest.Conteo(0))
+		base.Responder(`DELETE FROM "personas"`, dbtest.Respuesta{Afectadas: 1})
+
+		router := gin.New()
+		router.DELETE("/personas/:id", NewPersonaHandler(services.NewPersonaService(db, nil)).Delete)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/personas/7", nil))
+
+		if w.Code != c.status {
+			t.Errorf("%s: status = %d; se esperaba %d (%s)", c.nombre, w.Code, c.status, w.Body)
+			continue
+		}
+		if c.codigo != "" {
+			var cuerpo utils.APIResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &cuerpo); err != nil || cuerpo.Error == nil || cuerpo.Error.Code != c.codigo {
+				t.Errorf("%s: se esperaba el código %q: %s", c.nombre, c.codigo, w.Body)
+			}
+		}
+		if borrada := base.Ejecutada(`DELETE FROM "personas"`); borrada != (c.status == http.StatusOK) {
+			t.Errorf("%s: DELETE ejecutado = %v", c.nombre, borrada)
+		}
+	}
+}
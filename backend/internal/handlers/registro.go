@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type RegistroHandler struct {
+	registroService *services.RegistroService
+	cuentaService   *services.CuentaService
+}
+
+type RegistroRequest struct {
+	Email           string  `json:"email" binding:"required,email,max=255"`
+	Password        string  `json:"password" binding:"required,min=8,max=72"`
+	IDFamilia       uint    `json:"id_familia" binding:"required"`
+	Nombres         string  `json:"nombres" binding:"required,max=150"`
+	ApellidoPaterno string  `json:"apellido_paterno" binding:"required,max=100"`
+	ApellidoMaterno *string `json:"apellido_materno" binding:"omitempty,max=100"`
+	Generacion      string  `json:"generacion" binding:"required,oneof=issei nisei sansei yonsei gosei roksei"`
+	FechaNacimiento *string `json:"fecha_nacimiento" binding:"omitempty,fecha"`
+	Telefono        *string `json:"telefono" binding:"omitempty,max=20"`
+	Ciudad          *string `json:"ciudad" binding:"omitempty,max=100"`
+}
+
+func NewRegistroHandler(registroService *services.RegistroService, cuentaService *services.CuentaService) *RegistroHandler {
+	return &RegistroHandler{registroService: registroService, cuentaService: cuentaService}
+}
+
+func (h *RegistroHandler) Registrar(c *gin.Context) {
+	var req RegistroRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	fechaNacimiento, _ := utils.ParseFecha(req.FechaNacimiento)
+
+	user, err := h.registroService.Registrar(services.RegistroInput{
+		Email:           req.Email,
+		Password:        req.Password,
+		IDFamilia:       req.IDFamilia,
+		Nombres:         req.Nombres,
+		ApellidoPaterno: req.ApellidoPaterno,
+		ApellidoMaterno: req.ApellidoMaterno,
+		Generacion:      req.Generacion,
+		FechaNacimiento: fechaNacimiento,
+		Telefono:        req.Telefono,
+		Ciudad:          req.Ciudad,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	// El registro ya quedó guardado; si el correo falla se puede reenviar
+	// desde /auth/verify-email/resend.
+	if err := h.cuentaService.EnviarVerificacion(user); err != nil {
+		log.Printf("Error enviando verificación a %s: %v", user.Email, err)
+	}
+
+	utils.Success(c, http.StatusCreated, "Registro recibido, pendiente de aprobación", user)
+}
+
+func (h *RegistroHandler) ListarPendientes(c *gin.Context) {
+	solicitudes, err := h.registroService.ListarPendientes()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", solicitudes)
+}
+
+func (h *RegistroHandler) Aprobar(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := h.registroService.Aprobar(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Solicitud aprobada", user)
+}
+
+func (h *RegistroHandler) Rechazar(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := h.registroService.Rechazar(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "Solicitud rechazada", user)
+}
+
+func (h *RegistroHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrEmailRegistrado):
+		utils.Error(c, http.StatusConflict, "email_registrado", err.Error())
+	case errors.Is(err, services.ErrFamiliaNoEncontrada):
+		utils.Error(c, http.StatusUnprocessableEntity, "familia_no_encontrada", err.Error())
+	case errors.Is(err, services.ErrSolicitudNoEncontrada):
+		utils.Error(c, http.StatusNotFound, "solicitud_no_encontrada", err.Error())
+	default:
+		log.Printf("Error en registro: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/services"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+type ReporteHandler struct {
+	reporteService *services.ReporteService
+}
+
+func NewReporteHandler(reporteService *services.ReporteService) *ReporteHandler {
+	return &ReporteHandler{reporteService: reporteService}
+}
+
+func (h *ReporteHandler) Demografico(c *gin.Context) {
+	var filtros services.ReporteFiltros
+	var params services.ExportacionParams
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	reporte, err := h.reporteService.Demografico(filtros, time.Now())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.responder(c, params.Format, reporte, reporte.Documento)
+}
+
+func (h *ReporteHandler) Asistencia(c *gin.Context) {
+	var filtros services.AsistenciaFiltros
+	var params services.ExportacionParams
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	eventos, err := h.reporteService.Asistencia(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.responder(c, params.Format, eventos, func() *services.DocumentoReporte {
+		return services.DocumentoAsistencia(eventos, time.Now())
+	})
+}
+
+func (h *ReporteHandler) AsistenciaPorTipo(c *gin.Context) {
+	var filtros services.AsistenciaFiltros
+	var params services.ExportacionParams
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	tipos, err := h.reporteService.AsistenciaPorTipo(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.responder(c, params.Format, tipos, func() *services.DocumentoReporte {
+		return services.DocumentoTiposEvento(tipos, time.Now())
+	})
+}
+
+func (h *ReporteHandler) AnalisisEvento(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	analisis, err := h.reporteService.AnalisisEvento(id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.Success(c, http.StatusOK, "", analisis)
+}
+
+func (h *ReporteHandler) Compromiso(c *gin.Context) {
+	var filtros services.CompromisoFiltros
+	var params services.ExportacionParams
+	if err := c.ShouldBindQuery(&filtros); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	reporte, err := h.reporteService.Compromiso(filtros)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.responder(c, params.Format, reporte, func() *services.DocumentoReporte {
+		return services.DocumentoCompromiso(reporte, time.Now())
+	})
+}
+
+func (h *ReporteHandler) Empresas(c *gin.Context) {
+	var params services.ExportacionParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	empresas, err := h.reporteService.Empresas()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.responder(c, params.Format, empresas, func() *services.DocumentoReporte {
+		return services.DocumentoEmpresas(empresas, time.Now())
+	})
+}
+
+// General solo existe como archivo; sin format se entrega en Excel.
+func (h *ReporteHandler) General(c *gin.Context) {
+	var params services.ExportacionParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if params.Format == "" || params.Format == "json" {
+		params.Format = "xlsx"
+	}
+
+	documento, err := h.reporteService.General(time.Now())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.enviar(c, documento, params.Format)
+}
+
+// responder entrega los datos en JSON o, si se pidió un formato de archivo,
+// arma el documento y lo descarga.
+func (h *ReporteHandler) responder(c *gin.Context, formato string, datos any, documento func() *services.DocumentoReporte) {
+	if formato == "" || formato == "json" {
+		utils.Success(c, http.StatusOK, "", datos)
+		return
+	}
+	h.enviar(c, documento(), formato)
+}
+
+func (h *ReporteHandler) enviar(c *gin.Context, documento *services.DocumentoReporte, formato string) {
+	archivo, err := documento.Exportar(formato)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+archivo.Nombre+`"`)
+	c.Data(http.StatusOK, archivo.TipoContenido, archivo.Contenido)
+}
+
+func (h *ReporteHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrFormatoNoSoportado):
+		utils.Error(c, http.StatusBadRequest, "formato_no_soportado", err.Error())
+	case errors.Is(err, services.ErrEventoNoEncontrado):
+		utils.Error(c, http.StatusNotFound, "evento_no_encontrado", err.Error())
+	default:
+		log.Printf("Error en reportes: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "error_interno", "Error interno del servidor")
+	}
+}
@@ -0,0 +1,112 @@
+package japones
+
+import "testing"
+
+func TestKanaARomaji(t *testing.T) {
+	casos := []struct{ kana, romaji string }{
+		{"さとう", "satou"},
+		{"サトウ", "satou"},
+		{"たなか", "tanaka"},
+		{"しんじ", "shinji"},
+		{"ちゅうじ", "chuuji"},
+		{"きょうこ", "kyouko"},
+		{"じゅん", "jun"},
+		// Sokuon: dobla la consonante; antes de ch se escribe t.
+		{"はっとり", "hattori"},
+		{"いっしき", "isshiki"},
+		{"まっちゃ", "matcha"},
+		// ん antes de vocal o y lleva apóstrofo.
+		{"しんいち", "shin'ichi"},
+		{"けんや", "ken'ya"},
+		{"ほんだ", "honda"},
+		// ー repite la vocal anterior.
+		{"ユーコ", "yuuko"},
+		{"ターロー", "taaroo"},
+		{"ふぁん", "fan"},
+		{"田中たろう", "田中tarou"},
+	}
+	for _, c := range casos {
+		if got := KanaARomaji(c.kana); got != c.romaji {
+			t.Errorf("KanaARomaji(%q) = %q; se esperaba %q", c.kana, got, c.romaji)
+		}
+	}
+}
+
+func TestRomajiAKana(t *testing.T) {
+	casos := []struct{ romaji, kana string }{
+		{"Satou", "さとう"},
+		{"Satō", "さとう"},
+		{"Satoh", "さとう"},
+		{"tanaka", "たなか"},
+		// Hepburn y Kunrei dan el mismo kana.
+		{"shinji", "しんじ"},
+		{"sinzi", "しんじ"},
+		{"tsutomu", "つとむ"},
+		{"tutomu", "つとむ"},
+		{"fujita", "ふじた"},
+		{"huzita", "ふじた"},
+		{"chiyo", "ちよ"},
+		{"tiyo", "ちよ"},
+		{"kyouko", "きょうこ"},
+		{"jun", "じゅん"},
+		{"zyun", "じゅん"},
+		// Sokuon.
+		{"hattori", "はっとり"},
+		{"matcha", "まっちゃ"},
+		// ん: apóstrofo, nn y m ante labial.
+		{"shin'ichi", "しんいち"},
+		{"honnda", "ほんだ"},
+		{"shinnichi", "しんにち"},
+		{"kenya", "けにゃ"},
+		{"ken'ya", "けんや"},
+		{"kambara", "かんばら"},
+	}
+	for _, c := range casos {
+		if got := RomajiAKana(c.romaji); got != c.kana {
+			t.Errorf("RomajiAKana(%q) = %q; se esperaba %q", c.romaji, got, c.kana)
+		}
+	}
+}
+
+func TestNormalizar(t *testing.T) {
+	grupos := [][]string{
+		{"Satō", "Satou", "Satoh", "Sato", "さとう", "サトウ", "SATÔ"},
+		{"Shinji", "Sinzi", "しんじ", "シンジ"},
+		{"Tsutomu", "Tutomu", "つとむ"},
+		{"Fujita", "Huzita", "ふじた"},
+		{"Jun'ichi", "Junichi", "Zyun'iti", "じゅんいち"},
+		{"Hattori", "はっとり"},
+		{"Kambara", "Kanbara", "かんばら"},
+		{"Ōno", "Ohno", "Oono", "おおの"},
+		{"Yūko", "Yuuko", "ゆうこ", "ユーコ"},
+	}
+	for _, grupo := range grupos {
+		clave := Normalizar(grupo[0])
+		for _, v := range grupo[1:] {
+			if got := Normalizar(v); got != clave {
+				t.Errorf("Normalizar(%q) = %q; se esperaba %q como Normalizar(%q)", v, got, clave, grupo[0])
+			}
+		}
+	}
+
+	distintos := [][2]string{
+		{"Sato", "Saito"},
+		{"Ono", "Ueno"},
+	}
+	for _, par := range distintos {
+		if Normalizar(par[0]) == Normalizar(par[1]) {
+			t.Errorf("Normalizar no debe igualar %q y %q", par[0], par[1])
+		}
+	}
+
+	if got := Normalizar("  田中  Tarō "); got != "田中 taro" {
+		t.Errorf("Normalizar con kanji = %q", got)
+	}
+}
+
+func TestClaveBusqueda(t *testing.T) {
+	nombres, apellido, kana := "Tarō", "Satō", "さとう たろう"
+	if got := ClaveBusqueda(&nombres, &apellido, nil, &kana); got != "taro sato" {
+		t.Errorf("ClaveBusqueda = %q; se esperaba %q", got, "taro sato")
+	}
+}
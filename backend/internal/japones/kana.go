@@ -0,0 +1,168 @@
+// Package japones convierte entre hiragana, katakana y romaji y normaliza las
+// variantes de romanización para que los nombres se puedan comparar y buscar
+// sin importar cómo se capturaron (Satō, Satou, Satoh, さとう, サトウ).
+package japones
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	inicioHiragana  = 'ぁ'
+	finHiragana     = 'ゖ'
+	inicioKatakana  = 'ァ'
+	finKatakana     = 'ヶ'
+	desfaseKatakana = inicioKatakana - inicioHiragana
+	sokuon          = 'っ'
+	marcaLarga      = 'ー'
+)
+
+// silabas es la romanización Hepburn de cada kana suelto.
+var silabas = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n",
+	'ゔ': "vu",
+}
+
+// pequenias modifican al kana anterior: きゃ → kya, ふぁ → fa.
+var pequenias = map[rune]string{
+	'ゃ': "a", 'ゅ': "u", 'ょ': "o",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o", 'ゎ': "a",
+}
+
+// KatakanaAHiragana deja intacto todo lo que no sea katakana.
+func KatakanaAHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= inicioKatakana && r <= finKatakana {
+			return r - desfaseKatakana
+		}
+		return r
+	}, s)
+}
+
+// HiraganaAKatakana deja intacto todo lo que no sea hiragana.
+func HiraganaAKatakana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= inicioHiragana && r <= finHiragana {
+			return r + desfaseKatakana
+		}
+		return r
+	}, s)
+}
+
+// EsKana indica si el texto tiene al menos un carácter en hiragana o katakana.
+func EsKana(s string) bool {
+	for _, r := range s {
+		if esKana(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func esKana(r rune) bool {
+	return (r >= inicioHiragana && r <= finHiragana) || (r >= inicioKatakana && r <= finKatakana) || r == marcaLarga
+}
+
+// KanaARomaji transcribe hiragana y katakana a Hepburn. Las vocales largas
+// quedan como se escriben en kana (さとう → satou) y el kanji se conserva.
+func KanaARomaji(s string) string {
+	runas := []rune(KatakanaAHiragana(s))
+	var b strings.Builder
+	doblar := false
+
+	for i := 0; i < len(runas); i++ {
+		r := runas[i]
+		silaba, ok := silabas[r]
+
+		switch {
+		case r == sokuon:
+			doblar = true
+			continue
+		case r == marcaLarga:
+			if v := ultimaVocal(b.String()); v != 0 {
+				b.WriteRune(v)
+			}
+			continue
+		case !ok:
+			if vocal, esPequenia := pequenias[r]; esPequenia {
+				if r == 'ゃ' || r == 'ゅ' || r == 'ょ' {
+					b.WriteByte('y')
+				}
+				b.WriteString(vocal)
+			} else {
+				b.WriteRune(r)
+			}
+			doblar = false
+			continue
+		}
+
+		if i+1 < len(runas) {
+			if vocal, esPequenia := pequenias[runas[i+1]]; esPequenia {
+				silaba = combinarPequenia(silaba, runas[i+1], vocal)
+				i++
+			}
+		}
+
+		if r == 'ん' && i+1 < len(runas) {
+			if siguiente := silabas[runas[i+1]]; siguiente != "" && strings.ContainsAny(siguiente[:1], "aeiouy") {
+				silaba = "n'"
+			}
+		}
+
+		if doblar {
+			if strings.HasPrefix(silaba, "ch") {
+				b.WriteByte('t')
+			} else if c := silaba[0]; !strings.ContainsRune("aeioun", rune(c)) {
+				b.WriteByte(c)
+			}
+			doblar = false
+		}
+		b.WriteString(silaba)
+	}
+	return b.String()
+}
+
+// combinarPequenia une un kana con la ya, yu, yo o vocal pequeña que lo sigue.
+func combinarPequenia(silaba string, pequenia rune, vocal string) string {
+	base := strings.TrimRight(silaba, "aeiou")
+	if base == "" {
+		return "w" + vocal
+	}
+	switch pequenia {
+	case 'ゃ', 'ゅ', 'ょ':
+		if base == "sh" || base == "ch" || base == "j" {
+			return base + vocal
+		}
+		return base + "y" + vocal
+	}
+	return base + vocal
+}
+
+func ultimaVocal(s string) rune {
+	for len(s) > 0 {
+		r, tam := utf8.DecodeLastRuneInString(s)
+		if strings.ContainsRune("aeiou", r) {
+			return r
+		}
+		if !strings.ContainsRune("bcdfghjkmnprstvwyz'", r) {
+			return 0
+		}
+		s = s[:len(s)-tam]
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package japones
+
+import (
+	"strings"
+)
+
+// Prefectura es una de las 47 prefecturas con el nombre en Hepburn con
+// macrones, como se escribe en los registros de la asociación.
+type Prefectura struct {
+	Nombre string `json:"nombre"`
+	Kanji  string `json:"kanji"`
+	Region string `json:"region"`
+}
+
+var Prefecturas = []Prefectura{
+	{"Hokkaidō", "北海道", "Hokkaidō"},
+	{"Aomori", "青森県", "Tōhoku"},
+	{"Iwate", "岩手県", "Tōhoku"},
+	{"Miyagi", "宮城県", "Tōhoku"},
+	{"Akita", "秋田県", "Tōhoku"},
+	{"Yamagata", "山形県", "Tōhoku"},
+	{"Fukushima", "福島県", "Tōhoku"},
+	{"Ibaraki", "茨城県", "Kantō"},
+	{"Tochigi", "栃木県", "Kantō"},
+	{"Gunma", "群馬県", "Kantō"},
+	{"Saitama", "埼玉県", "Kantō"},
+	{"Chiba", "千葉県", "Kantō"},
+	{"Tōkyō", "東京都", "Kantō"},
+	{"Kanagawa", "神奈川県", "Kantō"},
+	{"Niigata", "新潟県", "Chūbu"},
+	{"Toyama", "富山県", "Chūbu"},
+	{"Ishikawa", "石川県", "Chūbu"},
+	{"Fukui", "福井県", "Chūbu"},
+	{"Yamanashi", "山梨県", "Chūbu"},
+	{"Nagano", "長野県", "Chūbu"},
+	{"Gifu", "岐阜県", "Chūbu"},
+	{"Shizuoka", "静岡県", "Chūbu"},
+	{"Aichi", "愛知県", "Chūbu"},
+	{"Mie", "三重県", "Kansai"},
+	{"Shiga", "滋賀県", "Kansai"},
+	{"Kyōto", "京都府", "Kansai"},
+	{"Ōsaka", "大阪府", "Kansai"},
+	{"Hyōgo", "兵庫県", "Kansai"},
+	{"Nara", "奈良県", "Kansai"},
+	{"Wakayama", "和歌山県", "Kansai"},
+	{"Tottori", "鳥取県", "Chūgoku"},
+	{"Shimane", "島根県", "Chūgoku"},
+	{"Okayama", "岡山県", "Chūgoku"},
+	{"Hiroshima", "広島県", "Chūgoku"},
+	{"Yamaguchi", "山口県", "Chūgoku"},
+	{"Tokushima", "徳島県", "Shikoku"},
+	{"Kagawa", "香川県", "Shikoku"},
+	{"Ehime", "愛媛県", "Shikoku"},
+	{"Kōchi", "高知県", "Shikoku"},
+	{"Fukuoka", "福岡県", "Kyūshū y Okinawa"},
+	{"Saga", "佐賀県", "Kyūshū y Okinawa"},
+	{"Nagasaki", "長崎県", "Kyūshū y Okinawa"},
+	{"Kumamoto", "熊本県", "Kyūshū y Okinawa"},
+	{"Ōita", "大分県", "Kyūshū y Okinawa"},
+	{"Miyazaki", "宮崎県", "Kyūshū y Okinawa"},
+	{"Kagoshima", "鹿児島県", "Kyūshū y Okinawa"},
+	{"Okinawa", "沖縄県", "Kyūshū y Okinawa"},
+}
+
+// palabrasPrefectura son las que acompañan al nombre en los registros
+// ("Hiroshima-ken", "Prefectura de Kumamoto", "Tokyo-to") y se ignoran.
+var palabrasPrefectura = map[string]bool{
+	"ken": true, "fu": true, "hu": true, "to": true, "do": true,
+	"prefectura": true, "prefecture": true, "de": true, "japon": true, "japan": true,
+}
+
+var prefecturaPorClave = func() map[string]*Prefectura {
+	claves := make(map[string]*Prefectura, len(Prefecturas)*3)
+	for i := range Prefecturas {
+		p := &Prefecturas[i]
+		claves[Normalizar(p.Nombre)] = p
+		claves[p.Kanji] = p
+		claves[sinSufijoKanji(p.Kanji)] = p
+	}
+	return claves
+}()
+
+// BuscarPrefectura reconoce una prefectura escrita en romaji (con o sin
+// macrones, Kunrei o Hepburn), en kana o en kanji, con o sin el sufijo -ken.
+func BuscarPrefectura(s string) (*Prefectura, bool) {
+	clave := Normalizar(s)
+	if p, ok := prefecturaPorClave[clave]; ok {
+		return p, true
+	}
+
+	var palabras []string
+	for _, palabra := range strings.Fields(clave) {
+		if !palabrasPrefectura[palabra] {
+			palabras = append(palabras, palabra)
+		}
+	}
+	if p, ok := prefecturaPorClave[strings.Join(palabras, "")]; ok {
+		return p, true
+	}
+	for _, palabra := range palabras {
+		if p, ok := prefecturaPorClave[palabra]; ok {
+			return p, true
+		}
+		if p, ok := prefecturaPorClave[sinSufijoKanji(palabra)]; ok {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+func sinSufijoKanji(s string) string {
+	for _, sufijo := range []string{"県", "府", "都"} {
+		if recortado, ok := strings.CutSuffix(s, sufijo); ok {
+			return recortado
+		}
+	}
+	return s
+}
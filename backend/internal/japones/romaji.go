@@ -0,0 +1,191 @@
+package japones
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+// kanaDeRomaji incluye, además de Hepburn, las variantes Kunrei y las que se
+// ven en actas antiguas (si, tu, hu, zya, jya).
+var kanaDeRomaji = map[string]string{
+	"a": "あ", "i": "い", "u": "う", "e": "え", "o": "お",
+	"ka": "か", "ki": "き", "ku": "く", "ke": "け", "ko": "こ",
+	"ga": "が", "gi": "ぎ", "gu": "ぐ", "ge": "げ", "go": "ご",
+	"sa": "さ", "shi": "し", "si": "し", "su": "す", "se": "せ", "so": "そ",
+	"za": "ざ", "ji": "じ", "zi": "じ", "zu": "ず", "ze": "ぜ", "zo": "ぞ",
+	"ta": "た", "chi": "ち", "ti": "ち", "tsu": "つ", "tu": "つ", "te": "て", "to": "と",
+	"da": "だ", "di": "ぢ", "du": "づ", "de": "で", "do": "ど",
+	"na": "な", "ni": "に", "nu": "ぬ", "ne": "ね", "no": "の",
+	"ha": "は", "hi": "ひ", "fu": "ふ", "hu": "ふ", "he": "へ", "ho": "ほ",
+	"ba": "ば", "bi": "び", "bu": "ぶ", "be": "べ", "bo": "ぼ",
+	"pa": "ぱ", "pi": "ぴ", "pu": "ぷ", "pe": "ぺ", "po": "ぽ",
+	"ma": "ま", "mi": "み", "mu": "む", "me": "め", "mo": "も",
+	"ya": "や", "yu": "ゆ", "yo": "よ",
+	"ra": "ら", "ri": "り", "ru": "る", "re": "れ", "ro": "ろ",
+	"wa": "わ", "wo": "を", "vu": "ゔ",
+	"kya": "きゃ", "kyu": "きゅ", "kyo": "きょ",
+	"gya": "ぎゃ", "gyu": "ぎゅ", "gyo": "ぎょ",
+	"sha": "しゃ", "shu": "しゅ", "sho": "しょ", "sya": "しゃ", "syu": "しゅ", "syo": "しょ",
+	"ja": "じゃ", "ju": "じゅ", "jo": "じょ", "zya": "じゃ", "zyu": "じゅ", "zyo": "じょ",
+	"jya": "じゃ", "jyu": "じゅ", "jyo": "じょ",
+	"cha": "ちゃ", "chu": "ちゅ", "cho": "ちょ", "tya": "ちゃ", "tyu": "ちゅ", "tyo": "ちょ",
+	"nya": "にゃ", "nyu": "にゅ", "nyo": "にょ",
+	"hya": "ひゃ", "hyu": "ひゅ", "hyo": "ひょ",
+	"bya": "びゃ", "byu": "びゅ", "byo": "びょ",
+	"pya": "ぴゃ", "pyu": "ぴゅ", "pyo": "ぴょ",
+	"mya": "みゃ", "myu": "みゅ", "myo": "みょ",
+	"rya": "りゃ", "ryu": "りゅ", "ryo": "りょ",
+	"fa": "ふぁ", "fi": "ふぃ", "fe": "ふぇ", "fo": "ふぉ",
+	"che": "ちぇ", "she": "しぇ", "je": "じぇ",
+}
+
+// vocalesLargas expande macrones y circunflejos a la forma en kana.
+var vocalesLargas = strings.NewReplacer(
+	"ā", "aa", "ī", "ii", "ū", "uu", "ē", "ee", "ō", "ou",
+	"â", "aa", "î", "ii", "û", "uu", "ê", "ee", "ô", "ou",
+)
+
+// RomajiAKana convierte romaji a hiragana. Lo que no forma sílaba se deja
+// como está, así que un texto mixto conserva sus partes no japonesas.
+func RomajiAKana(s string) string {
+	texto := []rune(vocalesLargas.Replace(strings.ToLower(norm.NFC.String(s))))
+	var b strings.Builder
+
+	for i := 0; i < len(texto); {
+		c := texto[i]
+		siguiente := rune(0)
+		if i+1 < len(texto) {
+			siguiente = texto[i+1]
+		}
+
+		switch {
+		case c == 'n' && (siguiente == '\'' || siguiente == 'n'):
+			// En "shinnichi" la segunda n abre la sílaba siguiente; en
+			// "honnda" las dos son una sola ん.
+			despues := rune(0)
+			if i+2 < len(texto) {
+				despues = texto[i+2]
+			}
+			b.WriteRune('ん')
+			i++
+			if siguiente == '\'' || (!esVocal(despues) && despues != 'y') {
+				i++
+			}
+			continue
+		case c == 'n' && !esVocal(siguiente) && siguiente != 'y':
+			b.WriteRune('ん')
+			i++
+			continue
+		case c == 'm' && (siguiente == 'b' || siguiente == 'p' || siguiente == 'm'):
+			b.WriteRune('ん')
+			i++
+			continue
+		case c == 'h' && i > 0 && texto[i-1] == 'o' && !esVocal(siguiente) && siguiente != 'y':
+			// Satoh, Ohno: la h alarga la o.
+			b.WriteRune('う')
+			i++
+			continue
+		case esConsonante(c) && (c == siguiente || (c == 't' && siguiente == 'c')):
+			b.WriteRune(sokuon)
+			i++
+			continue
+		}
+
+		encontrada := false
+		for largo := 3; largo >= 1; largo-- {
+			if i+largo > len(texto) {
+				continue
+			}
+			if kana, ok := kanaDeRomaji[string(texto[i:i+largo])]; ok {
+				b.WriteString(kana)
+				i += largo
+				encontrada = true
+				break
+			}
+		}
+		if !encontrada {
+			b.WriteRune(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// variantesHepburn lleva las grafías Hepburn a una sola forma. El orden
+// importa: a igual posición gana el primer patrón de la lista.
+var variantesHepburn = strings.NewReplacer(
+	"shi", "si", "chi", "ti", "tsu", "tu", "fu", "hu", "ji", "zi",
+	"jy", "zy", "sh", "sy", "ch", "ty", "j", "zy",
+	"'", "",
+)
+
+var (
+	nAntesDeLabial = regexp.MustCompile(`m([bpm])`)
+	hDeVocalLarga  = regexp.MustCompile(`oh([^aeiouy]|$)`)
+)
+
+// Normalizar devuelve la clave con la que se comparan dos nombres: minúsculas,
+// sin acentos, el kana pasado a romaji y las variantes de romanización
+// unificadas (Satō, Satou, Satoh y さとう quedan como "sato"). El kanji se
+// conserva tal cual porque no tiene una lectura única.
+func Normalizar(s string) string {
+	texto := norm.NFKC.String(s)
+	if EsKana(texto) {
+		texto = KanaARomaji(texto)
+	}
+	texto = utils.NormalizarTexto(texto)
+
+	palabras := strings.Fields(texto)
+	for i, p := range palabras {
+		p = variantesHepburn.Replace(p)
+		p = nAntesDeLabial.ReplaceAllString(p, "n$1")
+		p = hDeVocalLarga.ReplaceAllString(p, "o$1")
+		p = strings.ReplaceAll(p, "ou", "o")
+		palabras[i] = colapsarVocales(p)
+	}
+	return strings.Join(palabras, " ")
+}
+
+// ClaveBusqueda arma el texto que se guarda para buscar: las palabras
+// normalizadas de todos los valores, sin repetir.
+func ClaveBusqueda(valores ...*string) string {
+	vistas := make(map[string]bool)
+	var palabras []string
+	for _, v := range valores {
+		if v == nil {
+			continue
+		}
+		for _, p := range strings.Fields(Normalizar(*v)) {
+			if !vistas[p] {
+				vistas[p] = true
+				palabras = append(palabras, p)
+			}
+		}
+	}
+	return strings.Join(palabras, " ")
+}
+
+func colapsarVocales(s string) string {
+	var b strings.Builder
+	var anterior rune
+	for _, r := range s {
+		if r == anterior && esVocal(r) {
+			continue
+		}
+		b.WriteRune(r)
+		anterior = r
+	}
+	return b.String()
+}
+
+func esVocal(r rune) bool {
+	return strings.ContainsRune("aeiou", r)
+}
+
+func esConsonante(r rune) bool {
+	return r >= 'a' && r <= 'z' && !esVocal(r) && r != 'n'
+}
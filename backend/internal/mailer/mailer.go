@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer abstrae el envío de correos para poder usar SMTP real, un outbox en
+// disco durante el desarrollo o memoria en pruebas.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New elige el driver según MAIL_DRIVER. En producción solo se acepta smtp:
+// los otros dejarían los tokens de verificación y recuperación en disco o en
+// memoria sin llegar a nadie.
+func New(cfg *config.Config) Mailer {
+	if cfg.IsProduction() && cfg.MailDriver != "smtp" {
+		log.Fatalf("MAIL_DRIVER debe ser smtp en producción (actual: %q)", cfg.MailDriver)
+	}
+	switch cfg.MailDriver {
+	case "smtp":
+		log.Printf("Correo: usando SMTP en %s:%s", cfg.SMTPHost, cfg.SMTPPort)
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom)
+	case "memory":
+		log.Println("Correo: usando buzón en memoria")
+		return NewMemoryMailer()
+	default:
+		log.Printf("Correo: guardando mensajes en %s", cfg.MailOutboxDir)
+		return NewOutboxMailer(cfg.MailOutboxDir, cfg.MailFrom)
+	}
+}
+
+func buildMessage(from string, msg Message) []byte {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		from = addr.String()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", encodeHeader(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func encodeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", value)
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+type SMTPMailer struct {
+	addr string
+	host string
+	user string
+	pass string
+	from string
+}
+
+func NewSMTPMailer(host, port, user, pass, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		host: host,
+		user: user,
+		pass: pass,
+		from: from,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	// Servidores de desarrollo como MailHog no requieren autenticación.
+	var auth smtp.Auth
+	if m.user != "" {
+		auth = smtp.PlainAuth("", m.user, m.pass, m.host)
+	}
+	envelopeFrom := m.from
+	if addr, err := mail.ParseAddress(m.from); err == nil {
+		envelopeFrom = addr.Address
+	}
+	return smtp.SendMail(m.addr, auth, envelopeFrom, []string{msg.To}, buildMessage(m.from, msg))
+}
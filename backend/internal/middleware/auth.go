@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/config"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+const (
+	ContextUserKey   = "currentUser"
+	ContextClaimsKey = "claims"
+)
+
+type AuthMiddleware struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewAuthMiddleware(db *gorm.DB, cfg *config.Config) *AuthMiddleware {
+	return &AuthMiddleware{db: db, cfg: cfg}
+}
+
+// RequireAuth valida el access token, comprueba que la sesión siga abierta y
+// carga el usuario actual en el contexto.
+func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || strings.TrimSpace(tokenString) == "" {
+			utils.Error(c, http.StatusUnauthorized, "no_autenticado", "Se requiere iniciar sesión")
+			return
+		}
+
+		claims, err := utils.ParseToken(m.cfg.JWTSecret, strings.TrimSpace(tokenString), utils.TokenTypeAccess)
+		if err != nil {
+			utils.Error(c, http.StatusUnauthorized, "token_invalido", err.Error())
+			return
+		}
+
+		var sesion models.Sesion
+		if err := m.db.First(&sesion, claims.SesionID).Error; err != nil || !sesion.EstaActiva() || sesion.IDUser != claims.UserID {
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Error consultando sesión %d: %v", claims.SesionID, err)
+			}
+			utils.Error(c, http.StatusUnauthorized, "sesion_invalida", "La sesión no es válida o ya fue cerrada")
+			return
+		}
+
+		var user models.User
+		if err := m.db.First(&user, claims.UserID).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Error consultando usuario %d: %v", claims.UserID, err)
+			}
+			utils.Error(c, http.StatusUnauthorized, "usuario_no_encontrado", "El usuario de la sesión no existe")
+			return
+		}
+		if !user.IsActive {
+			utils.Error(c, http.StatusForbidden, "usuario_inactivo", "La cuenta está desactivada")
+			return
+		}
+
+		c.Set(ContextUserKey, &user)
+		c.Set(ContextClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// RequireRole debe ir después de RequireAuth. El rol se toma del usuario
+// recién cargado y no del token, para que un cambio de rol aplique de
+// inmediato.
+func (m *AuthMiddleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil {
+			utils.Error(c, http.StatusUnauthorized, "no_autenticado", "Se requiere iniciar sesión")
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		utils.ErrorWithDetails(c, http.StatusForbidden, "acceso_denegado",
+			"No tienes permisos para acceder a este recurso",
+			gin.H{"rol_actual": user.Role, "roles_permitidos": roles})
+	}
+}
+
+// RequireMiembro permite el paso a miembros aprobados y administradores.
+func (m *AuthMiddleware) RequireMiembro() gin.HandlerFunc {
+	return m.RequireRole("miembro", "admin")
+}
+
+func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
+	return m.RequireRole("admin")
+}
+
+func CurrentUser(c *gin.Context) *models.User {
+	value, exists := c.Get(ContextUserKey)
+	if !exists {
+		return nil
+	}
+	user, _ := value.(*models.User)
+	return user
+}
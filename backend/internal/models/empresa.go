@@ -2,6 +2,10 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
 )
 
 type Empresa struct {
@@ -20,6 +24,8 @@ type Empresa struct {
 	Ciudad                    *string    `gorm:"size:100" json:"ciudad"`
 	Estado                    string     `gorm:"default:Sinaloa;size:100" json:"estado"`
 	CodigoPostal              *string    `gorm:"size:10" json:"codigo_postal"`
+	Latitud                   *float64   `gorm:"type:double precision" json:"latitud"`
+	Longitud                  *float64   `gorm:"type:double precision" json:"longitud"`
 	FechaFundacion            *time.Time `gorm:"type:date" json:"fecha_fundacion"`
 	NumeroEmpleados           *int       `json:"numero_empleados"`
 	AceptaPromocionDirectorio bool       `gorm:"default:true" json:"acepta_promocion_directorio"`
@@ -28,6 +34,7 @@ type Empresa struct {
 	RedesSociales             *string    `gorm:"type:jsonb" json:"redes_sociales"`
 	HorariosAtencion          *string    `gorm:"type:jsonb" json:"horarios_atencion"`
 	ServiciosProductos        *string    `gorm:"type:text" json:"servicios_productos"`
+	ClaveBusqueda             string     `gorm:"type:text" json:"-"`
 	CreatedAt                 time.Time  `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt                 time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 
@@ -39,6 +46,17 @@ func (Empresa) TableName() string {
 	return "empresas"
 }
 
+// CalcularClaveBusqueda normaliza el nombre comercial y la razón social para
+// la búsqueda aproximada del directorio.
+func (e *Empresa) CalcularClaveBusqueda() string {
+	return japones.ClaveBusqueda(&e.NombreEmpresa, e.RazonSocial)
+}
+
+func (e *Empresa) BeforeSave(tx *gorm.DB) error {
+	e.ClaveBusqueda = e.CalcularClaveBusqueda()
+	return nil
+}
+
 func (e *Empresa) EsRestaurante() bool {
 	return e.Sector != nil && *e.Sector == "Restaurantes"
 }
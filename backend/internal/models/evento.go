@@ -15,6 +15,8 @@ type Evento struct {
 	Ubicacion           *string    `gorm:"size:300" json:"ubicacion"`
 	Direccion           *string    `gorm:"type:text" json:"direccion"`
 	Ciudad              *string    `gorm:"size:100" json:"ciudad"`
+	Latitud             *float64   `gorm:"type:double precision" json:"latitud"`
+	Longitud            *float64   `gorm:"type:double precision" json:"longitud"`
 	CapacidadMaxima     *int       `json:"capacidad_maxima"`
 	RequiereRegistro    bool       `gorm:"default:true" json:"requiere_registro"`
 	EsPublico           bool       `gorm:"default:true" json:"es_publico"`
@@ -67,9 +69,59 @@ func (e *Evento) EstaPublicado() bool {
 	return e.Status == "publicado"
 }
 
+func (e *Evento) AceptaRegistros() bool {
+	return e.Status == "publicado" || e.Status == "en_curso"
+}
+
 func (e *Evento) TieneCapacidadDisponible(participantesActuales int) bool {
 	if e.CapacidadMaxima == nil {
 		return true
 	}
 	return participantesActuales < *e.CapacidadMaxima
 }
+
+// TieneCapacidadPara indica si caben personasNuevas más sin exceder el cupo.
+func (e *Evento) TieneCapacidadPara(participantesActuales, personasNuevas int) bool {
+	if e.CapacidadMaxima == nil {
+		return true
+	}
+	return participantesActuales+personasNuevas <= *e.CapacidadMaxima
+}
+
+// transicionesEvento describe el ciclo de vida permitido de un evento.
+var transicionesEvento = map[string][]string{
+	"borrador":   {"publicado", "cancelado"},
+	"publicado":  {"en_curso", "finalizado", "cancelado"},
+	"en_curso":   {"finalizado", "cancelado"},
+	"finalizado": {},
+	"cancelado":  {},
+}
+
+func (e *Evento) PuedeTransicionarA(nuevoStatus string) bool {
+	for _, permitido := range transicionesEvento[e.Status] {
+		if permitido == nuevoStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// YaInicio indica si el evento empezó, por su status o porque llegó su fecha
+// de inicio aunque ActualizarEstados aún no lo haya movido a en_curso.
+func (e *Evento) YaInicio() bool {
+	return e.Status == "en_curso" || e.Status == "finalizado" || !e.FechaInicio.After(time.Now())
+}
+
+func (e *Evento) EsEditable() bool {
+	return e.Status == "borrador" || e.Status == "publicado"
+}
+
+// GetFechaCierre devuelve cuándo termina el evento; si no tiene fecha de fin
+// se considera que dura todo el día de inicio, igual que EsEnCurso.
+func (e *Evento) GetFechaCierre() time.Time {
+	if e.FechaFin != nil {
+		return *e.FechaFin
+	}
+	inicioDelDia := time.Date(e.FechaInicio.Year(), e.FechaInicio.Month(), e.FechaInicio.Day(), 0, 0, 0, 0, e.FechaInicio.Location())
+	return inicioDelDia.Add(24 * time.Hour)
+}
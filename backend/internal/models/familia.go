@@ -2,6 +2,10 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
 )
 
 type Familia struct {
@@ -17,6 +21,7 @@ type Familia struct {
 	HistoriaFamiliar     *string   `gorm:"type:text" json:"historia_familiar"`
 	FotoFamiliar         *string   `gorm:"size:500" json:"foto_familiar"`
 	DocumentosHistoricos *string   `gorm:"type:jsonb" json:"documentos_historicos"`
+	ClaveBusqueda        string    `gorm:"type:text" json:"-"`
 	CreatedAt            time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt            time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 
@@ -28,3 +33,14 @@ type Familia struct {
 func (Familia) TableName() string {
 	return "familias"
 }
+
+// CalcularClaveBusqueda normaliza los tres apellidos para que se encuentre la
+// familia escribiendo Satou, Satō o さとう.
+func (f *Familia) CalcularClaveBusqueda() string {
+	return japones.ClaveBusqueda(&f.ApellidoJP, f.ApellidoRomanji, f.ApellidoKanji)
+}
+
+func (f *Familia) BeforeSave(tx *gorm.DB) error {
+	f.ClaveBusqueda = f.CalcularClaveBusqueda()
+	return nil
+}
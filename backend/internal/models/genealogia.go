@@ -8,8 +8,9 @@ type Genealogia struct {
 	IDGenealogia          uint       `gorm:"primaryKey;column:id_genealogia;autoIncrement" json:"id_genealogia"`
 	IDPersona             uint       `gorm:"not null" json:"id_persona"`
 	IDPariente            uint       `gorm:"not null" json:"id_pariente"`
-	TipoRelacion          string     `gorm:"not null;size:50;check:tipo_relacion IN ('padre','madre','hijo','hija','esposo','esposa','hermano','hermana','abuelo','abuela','nieto','nieta','tio','tia','primo','prima','cuniado','cuniada','yerno','nuera','suegro','suegra')" json:"tipo_relacion"`
+	TipoRelacion          string     `gorm:"not null;size:50;check:tipo_relacion IN ('padre','madre','hijo','hija','esposo','esposa','hermano','hermana','abuelo','abuela','nieto','nieta','tio','tia','primo','prima','cuniado','cuniada','yerno','nuera','suegro','suegra','sobrino','sobrina')" json:"tipo_relacion"`
 	ConfirmadoAmbasPartes bool       `gorm:"default:false" json:"confirmado_ambas_partes"`
+	IDPersonaSolicitante  *uint      `json:"id_persona_solicitante"`
 	FechaConfirmacion     *time.Time `json:"fecha_confirmacion"`
 	Notas                 *string    `gorm:"type:text" json:"notas"`
 	CreatedAt             time.Time  `gorm:"autoCreateTime" json:"created_at"`
@@ -47,7 +48,8 @@ func (g *Genealogia) EsRelacionAbueloNieto() bool {
 }
 
 func (g *Genealogia) EsRelacionTioSobrino() bool {
-	return g.TipoRelacion == "tio" || g.TipoRelacion == "tia"
+	return g.TipoRelacion == "tio" || g.TipoRelacion == "tia" ||
+		g.TipoRelacion == "sobrino" || g.TipoRelacion == "sobrina"
 }
 
 func (g *Genealogia) EsRelacionPrimos() bool {
@@ -74,8 +76,10 @@ func (g *Genealogia) GetRelacionInversa() string {
 		"abuela":  "nieto",
 		"nieto":   "abuelo",
 		"nieta":   "abuelo",
-		"tio":     "primo",
-		"tia":     "primo",
+		"tio":     "sobrino",
+		"tia":     "sobrino",
+		"sobrino": "tio",
+		"sobrina": "tio",
 		"primo":   "primo",
 		"prima":   "primo",
 		"cuniado": "cuniado",
@@ -112,9 +116,64 @@ func (g *Genealogia) GetNivelGeneracional() int {
 		return 2
 	case "tio", "tia", "suegro", "suegra":
 		return -1
-	case "yerno", "nuera":
+	case "yerno", "nuera", "sobrino", "sobrina":
 		return 1
 	default:
 		return 0
 	}
 }
+
+// relacionesFemeninas asocia la forma masculina de cada relación con su forma
+// femenina.
+var relacionesFemeninas = map[string]string{
+	"padre":   "madre",
+	"hijo":    "hija",
+	"esposo":  "esposa",
+	"hermano": "hermana",
+	"abuelo":  "abuela",
+	"nieto":   "nieta",
+	"tio":     "tia",
+	"sobrino": "sobrina",
+	"primo":   "prima",
+	"cuniado": "cuniada",
+	"yerno":   "nuera",
+	"suegro":  "suegra",
+}
+
+// GetRelacionInversaPorGenero devuelve la relación inversa en la forma que
+// corresponde al género de quien la recibe. Sin género conocido se usa la
+// forma masculina, que en español funciona como genérica.
+func (g *Genealogia) GetRelacionInversaPorGenero(genero *string) string {
+	return RelacionConGenero(g.GetRelacionInversa(), genero)
+}
+
+// RelacionConGenero ajusta una relación (en cualquier forma) al género dado.
+func RelacionConGenero(relacion string, genero *string) string {
+	base := RelacionBase(relacion)
+	if genero != nil && *genero == "femenino" {
+		if femenina, ok := relacionesFemeninas[base]; ok {
+			return femenina
+		}
+	}
+	return base
+}
+
+// RelacionBase devuelve la forma masculina de una relación.
+func RelacionBase(relacion string) string {
+	for masculina, femenina := range relacionesFemeninas {
+		if relacion == femenina {
+			return masculina
+		}
+	}
+	return relacion
+}
+
+// VariantesRelacion devuelve la forma masculina y, si existe, la femenina de
+// una relación.
+func VariantesRelacion(relacion string) []string {
+	base := RelacionBase(relacion)
+	if femenina, ok := relacionesFemeninas[base]; ok {
+		return []string{base, femenina}
+	}
+	return []string{base}
+}
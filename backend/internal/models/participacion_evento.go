@@ -9,7 +9,7 @@ type ParticipacionEvento struct {
 	IDPersona             uint       `gorm:"not null" json:"id_persona"`
 	IDEvento              uint       `gorm:"not null" json:"id_evento"`
 	FechaRegistro         time.Time  `gorm:"autoCreateTime" json:"fecha_registro"`
-	StatusParticipacion   string     `gorm:"default:registrado;size:50;check:status_participacion IN ('registrado','confirmado','asistio','no_asistio','cancelado')" json:"status_participacion"`
+	StatusParticipacion   string     `gorm:"default:registrado;size:50;check:status_participacion IN ('registrado','confirmado','asistio','no_asistio','cancelado','en_espera')" json:"status_participacion"`
 	FechaConfirmacion     *time.Time `json:"fecha_confirmacion"`
 	NotasParticipante     *string    `gorm:"type:text" json:"notas_participante"`
 	CalificacionEvento    *int       `gorm:"check:calificacion_evento >= 1 AND calificacion_evento <= 5" json:"calificacion_evento"`
@@ -39,6 +39,16 @@ func (pe *ParticipacionEvento) EstaCancelado() bool {
 	return pe.StatusParticipacion == "cancelado"
 }
 
+func (pe *ParticipacionEvento) EstaEnEspera() bool {
+	return pe.StatusParticipacion == "en_espera"
+}
+
+// OcupaLugar indica si la participación cuenta contra la capacidad del evento.
+func (pe *ParticipacionEvento) OcupaLugar() bool {
+	return pe.StatusParticipacion == "registrado" || pe.StatusParticipacion == "confirmado" ||
+		pe.StatusParticipacion == "asistio"
+}
+
 func (pe *ParticipacionEvento) TieneAcompaniantes() bool {
 	return pe.Acompaniantes > 0
 }
@@ -69,6 +79,10 @@ func (pe *ParticipacionEvento) MarcarNoAsistencia() {
 	pe.StatusParticipacion = "no_asistio"
 }
 
+func (pe *ParticipacionEvento) PonerEnEspera() {
+	pe.StatusParticipacion = "en_espera"
+}
+
 func (pe *ParticipacionEvento) Cancelar() {
 	pe.StatusParticipacion = "cancelado"
 }
@@ -2,6 +2,10 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
 )
 
 type Persona struct {
@@ -24,6 +28,8 @@ type Persona struct {
 	Ciudad                  *string    `gorm:"size:100" json:"ciudad"`
 	Estado                  string     `gorm:"default:Sinaloa;size:100" json:"estado"`
 	CodigoPostal            *string    `gorm:"size:10" json:"codigo_postal"`
+	Latitud                 *float64   `gorm:"type:double precision" json:"latitud"`
+	Longitud                *float64   `gorm:"type:double precision" json:"longitud"`
 	FotoPerfil              *string    `gorm:"size:500" json:"foto_perfil"`
 	EsMiembroActivo         bool       `gorm:"default:false" json:"es_miembro_activo"`
 	FechaIngresoAsociacion  *time.Time `gorm:"type:date" json:"fecha_ingreso_asociacion"`
@@ -34,6 +40,7 @@ type Persona struct {
 	NotasAdministrativas    *string    `gorm:"type:text" json:"notas_administrativas"`
 	IDEmpresaEmpleadora     *uint      `json:"id_empresa_empleadora"`
 	Puesto                  *string    `gorm:"size:150" json:"puesto"`
+	ClaveBusqueda           string     `gorm:"type:text" json:"-"`
 	CreatedAt               time.Time  `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt               time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 
@@ -63,6 +70,17 @@ func (p *Persona) GetNombreCompleto() string {
 	return nombreCompleto
 }
 
+// CalcularClaveBusqueda junta los nombres en todas sus escrituras ya
+// normalizados; es lo que compara la búsqueda por nombre.
+func (p *Persona) CalcularClaveBusqueda() string {
+	return japones.ClaveBusqueda(&p.Nombres, &p.ApellidoPaterno, p.ApellidoMaterno, p.NombreJapones, p.NombreKanji)
+}
+
+func (p *Persona) BeforeSave(tx *gorm.DB) error {
+	p.ClaveBusqueda = p.CalcularClaveBusqueda()
+	return nil
+}
+
 func (p *Persona) EsIssei() bool {
 	return p.Generacion == "issei"
 }
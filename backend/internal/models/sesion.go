@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type Sesion struct {
+	IDSesion         uint       `gorm:"primaryKey;column:id_sesion;autoIncrement" json:"id_sesion"`
+	IDUser           uint       `gorm:"not null;index" json:"id_user"`
+	RefreshTokenHash string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	ExpiraEn         time.Time  `gorm:"not null" json:"expira_en"`
+	RevocadaEn       *time.Time `json:"revocada_en"`
+	UserAgent        *string    `gorm:"size:300" json:"user_agent"`
+	IP               *string    `gorm:"size:45" json:"ip"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Sesion) TableName() string {
+	return "sesiones"
+}
+
+func (s *Sesion) EstaActiva() bool {
+	return s.RevocadaEn == nil && s.ExpiraEn.After(time.Now())
+}
+
+func (s *Sesion) Revocar() {
+	now := time.Now()
+	s.RevocadaEn = &now
+}
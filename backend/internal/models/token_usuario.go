@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+type TokenUsuario struct {
+	IDToken   uint       `gorm:"primaryKey;column:id_token;autoIncrement" json:"id_token"`
+	IDUser    uint       `gorm:"not null;index" json:"id_user"`
+	Tipo      string     `gorm:"not null;size:50;check:tipo IN ('verificacion_email','reset_password')" json:"tipo"`
+	TokenHash string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	ExpiraEn  time.Time  `gorm:"not null" json:"expira_en"`
+	UsadoEn   *time.Time `json:"usado_en"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (TokenUsuario) TableName() string {
+	return "tokens_usuario"
+}
+
+func (t *TokenUsuario) EsValido() bool {
+	return t.UsadoEn == nil && t.ExpiraEn.After(time.Now())
+}
+
+func (t *TokenUsuario) MarcarUsado() {
+	now := time.Now()
+	t.UsadoEn = &now
+}
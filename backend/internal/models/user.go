@@ -23,3 +23,15 @@ type User struct {
 func (User) TableName() string {
 	return "users"
 }
+
+func (u *User) EsAdmin() bool {
+	return u.Role == "admin"
+}
+
+func (u *User) EsMiembro() bool {
+	return u.Role == "miembro"
+}
+
+func (u *User) EstaPendiente() bool {
+	return u.Role == "pendiente"
+}
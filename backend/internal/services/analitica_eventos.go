@@ -0,0 +1,374 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+const (
+	eventosCompromisoDefault = 10
+	limiteCompromisoDefault  = 50
+)
+
+type AsistenciaFiltros struct {
+	TipoEvento *string    `form:"tipo_evento" binding:"omitempty,oneof=matsuri reunion cultural deportivo educativo empresarial ceremonia"`
+	Desde      *time.Time `form:"desde" time_format:"2006-01-02"`
+	Hasta      *time.Time `form:"hasta" time_format:"2006-01-02"`
+}
+
+// ParticipacionResumen son los conteos que comparten el análisis por evento
+// y por tipo de evento. Registrados excluye cancelados y la lista de espera,
+// que solo se reporta en EnEspera; Confirmados incluye a quienes ya tienen
+// asistencia o inasistencia marcada. La tasa de inasistencia es sobre quienes
+// tenían asistencia por marcar, así que solo tiene sentido en eventos
+// finalizados. DistribucionCalificaciones va de una a cinco estrellas.
+type ParticipacionResumen struct {
+	Registrados                int64    `json:"registrados"`
+	Confirmados                int64    `json:"confirmados"`
+	Asistieron                 int64    `json:"asistieron"`
+	NoAsistieron               int64    `json:"no_asistieron"`
+	Cancelados                 int64    `json:"cancelados"`
+	EnEspera                   int64    `json:"en_espera"`
+	Acompaniantes              int64    `json:"acompaniantes"`
+	AforoEsperado              int64    `json:"aforo_esperado"`
+	AforoReal                  int64    `json:"aforo_real"`
+	TasaConfirmacion           float64  `json:"tasa_confirmacion"`
+	TasaAsistencia             float64  `json:"tasa_asistencia"`
+	TasaInasistencia           float64  `json:"tasa_inasistencia"`
+	Calificaciones             int64    `json:"calificaciones"`
+	CalificacionPromedio       *float64 `json:"calificacion_promedio"`
+	DistribucionCalificaciones []int64  `json:"distribucion_calificaciones"`
+}
+
+// AsistenciaEvento es el análisis de participación de un evento.
+type AsistenciaEvento struct {
+	IDEvento    uint      `json:"id_evento"`
+	Titulo      string    `json:"titulo"`
+	TipoEvento  string    `json:"tipo_evento"`
+	FechaInicio time.Time `json:"fecha_inicio"`
+	Status      string    `json:"status"`
+	ParticipacionResumen
+}
+
+type ComentarioEvento struct {
+	Calificacion *int      `json:"calificacion"`
+	Comentario   string    `json:"comentario"`
+	Fecha        time.Time `json:"fecha"`
+}
+
+// DetalleAsistenciaEvento agrega los comentarios, sin decir de quién son.
+type DetalleAsistenciaEvento struct {
+	AsistenciaEvento
+	Comentarios []ComentarioEvento `json:"comentarios"`
+}
+
+// AsistenciaTipoEvento compara los formatos de evento. Los promedios son
+// por evento.
+type AsistenciaTipoEvento struct {
+	TipoEvento           string  `json:"tipo_evento"`
+	Eventos              int64   `json:"eventos"`
+	PromedioRegistrados  float64 `json:"promedio_registrados"`
+	PromedioAsistentes   float64 `json:"promedio_asistentes"`
+	PromedioAforoReal    float64 `json:"promedio_aforo_real"`
+	ParticipacionResumen `json:"totales"`
+}
+
+type CompromisoFiltros struct {
+	Eventos   int   `form:"eventos" binding:"omitempty,min=1,max=100"`
+	IDPersona *uint `form:"id_persona"`
+	Limite    int   `form:"limite" binding:"omitempty,min=1,max=500"`
+}
+
+// CompromisoPersona mide la participación en los últimos eventos
+// finalizados. Puntaje va de 0 a 100.
+type CompromisoPersona struct {
+	IDPersona      uint   `json:"id_persona"`
+	Nombre         string `json:"nombre"`
+	Registros      int64  `json:"registros"`
+	Asistencias    int64  `json:"asistencias"`
+	Inasistencias  int64  `json:"inasistencias"`
+	Cancelaciones  int64  `json:"cancelaciones"`
+	Calificaciones int64  `json:"calificaciones"`
+	Puntaje        int    `json:"puntaje"`
+}
+
+type ReporteCompromiso struct {
+	EventosConsiderados int64               `json:"eventos_considerados"`
+	Personas            []CompromisoPersona `json:"personas"`
+}
+
+// filaParticipacion trae los conteos crudos; las estrellas van en columnas
+// porque Scan no llena slices.
+type filaParticipacion struct {
+	IDEvento      uint
+	Titulo        string
+	TipoEvento    string
+	FechaInicio   time.Time
+	Status        string
+	Registrados   int64
+	Confirmados   int64
+	Asistieron    int64
+	NoAsistieron  int64
+	Cancelados    int64
+	EnEspera      int64
+	Acompaniantes int64
+	AforoEsperado int64
+	Estrellas1    int64
+	Estrellas2    int64
+	Estrellas3    int64
+	Estrellas4    int64
+	Estrellas5    int64
+}
+
+func (f filaParticipacion) evento() AsistenciaEvento {
+	resumen := ParticipacionResumen{
+		Registrados:                f.Registrados,
+		Confirmados:                f.Confirmados,
+		Asistieron:                 f.Asistieron,
+		NoAsistieron:               f.NoAsistieron,
+		Cancelados:                 f.Cancelados,
+		EnEspera:                   f.EnEspera,
+		Acompaniantes:              f.Acompaniantes,
+		AforoEsperado:              f.AforoEsperado,
+		DistribucionCalificaciones: []int64{f.Estrellas1, f.Estrellas2, f.Estrellas3, f.Estrellas4, f.Estrellas5},
+	}
+	resumen.calcular()
+	return AsistenciaEvento{
+		IDEvento:             f.IDEvento,
+		Titulo:               f.Titulo,
+		TipoEvento:           f.TipoEvento,
+		FechaInicio:          f.FechaInicio,
+		Status:               f.Status,
+		ParticipacionResumen: resumen,
+	}
+}
+
+// calcular llena las tasas, el aforo real y el promedio a partir de los
+// conteos.
+func (r *ParticipacionResumen) calcular() {
+	r.AforoReal = r.Asistieron + r.Acompaniantes
+	r.TasaConfirmacion = porcentaje(r.Confirmados, r.Registrados)
+	r.TasaAsistencia = porcentaje(r.Asistieron, r.Registrados)
+	r.TasaInasistencia = porcentaje(r.NoAsistieron, r.Asistieron+r.NoAsistieron)
+
+	r.Calificaciones = 0
+	var suma int64
+	for i, n := range r.DistribucionCalificaciones {
+		r.Calificaciones += n
+		suma += int64(i+1) * n
+	}
+	r.CalificacionPromedio = nil
+	if r.Calificaciones > 0 {
+		promedio := math.Round(float64(suma)*100/float64(r.Calificaciones)) / 100
+		r.CalificacionPromedio = &promedio
+	}
+}
+
+func (r *ParticipacionResumen) sumar(otro ParticipacionResumen) {
+	r.Registrados += otro.Registrados
+	r.Confirmados += otro.Confirmados
+	r.Asistieron += otro.Asistieron
+	r.NoAsistieron += otro.NoAsistieron
+	r.Cancelados += otro.Cancelados
+	r.EnEspera += otro.EnEspera
+	r.Acompaniantes += otro.Acompaniantes
+	r.AforoEsperado += otro.AforoEsperado
+	if r.DistribucionCalificaciones == nil {
+		r.DistribucionCalificaciones = make([]int64, 5)
+	}
+	for i, n := range otro.DistribucionCalificaciones {
+		r.DistribucionCalificaciones[i] += n
+	}
+}
+
+// consultaParticipacion agrupa las participaciones por evento. El aforo
+// esperado cuenta a quienes ocupan lugar con sus acompañantes; los
+// acompañantes solo a los de quienes asistieron.
+func (s *ReporteService) consultaParticipacion() *gorm.DB {
+	return s.db.Table("eventos e").
+		Select(`e.id_evento, e.titulo, e.tipo_evento, e.fecha_inicio, e.status,
+			COUNT(p.id_participacion) FILTER (WHERE p.status_participacion NOT IN ('cancelado', 'en_espera')) AS registrados,
+			COUNT(p.id_participacion) FILTER (WHERE p.status_participacion IN ('confirmado', 'asistio', 'no_asistio')) AS confirmados,
+			COUNT(p.id_participacion) FILTER (WHERE p.status_participacion = 'asistio') AS asistieron,
+			COUNT(p.id_participacion) FILTER (WHERE p.status_participacion = 'no_asistio') AS no_asistieron,
+			COUNT(p.id_participacion) FILTER (WHERE p.status_participacion = 'cancelado') AS cancelados,
+			COUNT(p.id_participacion) FILTER (WHERE p.status_participacion = 'en_espera') AS en_espera,
+			COALESCE(SUM(p.acompaniantes) FILTER (WHERE p.status_participacion = 'asistio'), 0) AS acompaniantes,
+			COALESCE(SUM(1 + p.acompaniantes) FILTER (WHERE p.status_participacion IN ('registrado', 'confirmado', 'asistio')), 0) AS aforo_esperado,
+			COUNT(p.id_participacion) FILTER (WHERE p.calificacion_evento = 1) AS estrellas1,
+			COUNT(p.id_participacion) FILTER (WHERE p.calificacion_evento = 2) AS estrellas2,
+			COUNT(p.id_participacion) FILTER (WHERE p.calificacion_evento = 3) AS estrellas3,
+			COUNT(p.id_participacion) FILTER (WHERE p.calificacion_evento = 4) AS estrellas4,
+			COUNT(p.id_participacion) FILTER (WHERE p.calificacion_evento = 5) AS estrellas5`).
+		Joins("LEFT JOIN participacion_eventos p ON p.id_evento = e.id_evento").
+		Group("e.id_evento")
+}
+
+// Asistencia analiza los eventos publicados o ya realizados, del más
+// reciente al más antiguo.
+func (s *ReporteService) Asistencia(filtros AsistenciaFiltros) ([]AsistenciaEvento, error) {
+	query := s.consultaParticipacion().Where("e.status NOT IN ?", []string{"borrador", "cancelado"})
+	if filtros.TipoEvento != nil {
+		query = query.Where("e.tipo_evento = ?", *filtros.TipoEvento)
+	}
+	if filtros.Desde != nil {
+		query = query.Where("e.fecha_inicio >= ?", *filtros.Desde)
+	}
+	if filtros.Hasta != nil {
+		query = query.Where("e.fecha_inicio < ?", filtros.Hasta.AddDate(0, 0, 1))
+	}
+
+	var filas []filaParticipacion
+	if err := query.Order("e.fecha_inicio DESC, e.id_evento DESC").Scan(&filas).Error; err != nil {
+		return nil, err
+	}
+
+	eventos := make([]AsistenciaEvento, len(filas))
+	for i, f := range filas {
+		eventos[i] = f.evento()
+	}
+	return eventos, nil
+}
+
+// AnalisisEvento analiza un evento en cualquier estado e incluye los
+// comentarios de los participantes.
+func (s *ReporteService) AnalisisEvento(id uint) (*DetalleAsistenciaEvento, error) {
+	var filas []filaParticipacion
+	if err := s.consultaParticipacion().Where("e.id_evento = ?", id).Scan(&filas).Error; err != nil {
+		return nil, err
+	}
+	if len(filas) == 0 {
+		return nil, ErrEventoNoEncontrado
+	}
+
+	comentarios := []ComentarioEvento{}
+	err := s.db.Model(&models.ParticipacionEvento{}).
+		Select("calificacion_evento AS calificacion, comentario_evento AS comentario, created_at AS fecha").
+		Where("id_evento = ? AND TRIM(COALESCE(comentario_evento, '')) <> ''", id).
+		Order("created_at DESC").
+		Scan(&comentarios).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &DetalleAsistenciaEvento{AsistenciaEvento: filas[0].evento(), Comentarios: comentarios}, nil
+}
+
+// AsistenciaPorTipo suma el análisis de los eventos de cada tipo para
+// comparar formatos. Solo cuenta eventos finalizados, que son los que ya
+// tienen asistencia e inasistencias marcadas.
+func (s *ReporteService) AsistenciaPorTipo(filtros AsistenciaFiltros) ([]AsistenciaTipoEvento, error) {
+	eventos, err := s.Asistencia(filtros)
+	if err != nil {
+		return nil, err
+	}
+
+	porTipo := make(map[string]*AsistenciaTipoEvento)
+	for _, e := range eventos {
+		if e.Status != "finalizado" {
+			continue
+		}
+		tipo := porTipo[e.TipoEvento]
+		if tipo == nil {
+			tipo = &AsistenciaTipoEvento{TipoEvento: e.TipoEvento}
+			porTipo[e.TipoEvento] = tipo
+		}
+		tipo.Eventos++
+		tipo.sumar(e.ParticipacionResumen)
+	}
+
+	tipos := make([]AsistenciaTipoEvento, 0, len(porTipo))
+	for _, tipo := range porTipo {
+		tipo.calcular()
+		n := float64(tipo.Eventos)
+		tipo.PromedioRegistrados = math.Round(float64(tipo.Registrados)*10/n) / 10
+		tipo.PromedioAsistentes = math.Round(float64(tipo.Asistieron)*10/n) / 10
+		tipo.PromedioAforoReal = math.Round(float64(tipo.AforoReal)*10/n) / 10
+		tipos = append(tipos, *tipo)
+	}
+	sort.Slice(tipos, func(i, j int) bool {
+		if tipos[i].TasaAsistencia != tipos[j].TasaAsistencia {
+			return tipos[i].TasaAsistencia > tipos[j].TasaAsistencia
+		}
+		return tipos[i].TipoEvento < tipos[j].TipoEvento
+	})
+	return tipos, nil
+}
+
+// Compromiso puntúa a las personas según su participación en los últimos
+// eventos finalizados: cada asistencia vale 1, calificar el evento 0.1 y
+// cada inasistencia resta 0.5. El puntaje es ese total sobre el número de
+// eventos considerados, de 0 a 100. Cancelar a tiempo no penaliza.
+func (s *ReporteService) Compromiso(filtros CompromisoFiltros) (*ReporteCompromiso, error) {
+	eventos := filtros.Eventos
+	if eventos == 0 {
+		eventos = eventosCompromisoDefault
+	}
+	limite := filtros.Limite
+	if limite == 0 {
+		limite = limiteCompromisoDefault
+	}
+
+	var finalizados int64
+	if err := s.db.Model(&models.Evento{}).Where("status = ?", "finalizado").Count(&finalizados).Error; err != nil {
+		return nil, err
+	}
+	reporte := &ReporteCompromiso{EventosConsiderados: min(finalizados, int64(eventos)), Personas: []CompromisoPersona{}}
+	if reporte.EventosConsiderados == 0 {
+		return reporte, nil
+	}
+
+	sql := `
+		WITH ultimos AS (
+			SELECT id_evento FROM eventos
+			WHERE status = 'finalizado'
+			ORDER BY fecha_inicio DESC, id_evento DESC
+			LIMIT ?
+		)
+		SELECT p.id_persona,
+			per.nombres || ' ' || per.apellido_paterno || COALESCE(' ' || per.apellido_materno, '') AS nombre,
+			COUNT(*) FILTER (WHERE p.status_participacion <> 'cancelado') AS registros,
+			COUNT(*) FILTER (WHERE p.status_participacion = 'asistio') AS asistencias,
+			COUNT(*) FILTER (WHERE p.status_participacion = 'no_asistio') AS inasistencias,
+			COUNT(*) FILTER (WHERE p.status_participacion = 'cancelado') AS cancelaciones,
+			COUNT(p.calificacion_evento) AS calificaciones
+		FROM participacion_eventos p
+		JOIN ultimos u ON u.id_evento = p.id_evento
+		JOIN personas per ON per.id_persona = p.id_persona`
+	args := []any{eventos}
+	if filtros.IDPersona != nil {
+		sql += " WHERE p.id_persona = ?"
+		args = append(args, *filtros.IDPersona)
+	}
+	sql += " GROUP BY p.id_persona, per.nombres, per.apellido_paterno, per.apellido_materno"
+
+	var personas []CompromisoPersona
+	if err := s.db.Raw(sql, args...).Scan(&personas).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range personas {
+		p := &personas[i]
+		puntos := float64(p.Asistencias) + 0.1*float64(p.Calificaciones) - 0.5*float64(p.Inasistencias)
+		p.Puntaje = int(math.Round(max(0, min(100, 100*puntos/float64(reporte.EventosConsiderados)))))
+	}
+	sort.Slice(personas, func(i, j int) bool {
+		if personas[i].Puntaje != personas[j].Puntaje {
+			return personas[i].Puntaje > personas[j].Puntaje
+		}
+		if personas[i].Asistencias != personas[j].Asistencias {
+			return personas[i].Asistencias > personas[j].Asistencias
+		}
+		return personas[i].Nombre < personas[j].Nombre
+	})
+	if len(personas) > limite {
+		personas = personas[:limite]
+	}
+	reporte.Personas = personas
+	return reporte, nil
+}
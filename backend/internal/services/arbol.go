@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+const (
+	generacionesArbolDefault = 3
+	limiteNodosArbol         = 2000
+)
+
+type ArbolFiltros struct {
+	Ancestros     *int `form:"ancestros" binding:"omitempty,gte=0,lte=10"`
+	Descendientes *int `form:"descendientes" binding:"omitempty,gte=0,lte=10"`
+}
+
+// PersonaArbol es la ficha mínima que necesita el árbol interactivo. Sale
+// de ProyectarPersona, así que los campos que el visor no puede ver no
+// aparecen.
+type PersonaArbol struct {
+	IDPersona       uint    `json:"id_persona"`
+	IDFamilia       uint    `json:"id_familia"`
+	Nombres         string  `json:"nombres"`
+	ApellidoPaterno string  `json:"apellido_paterno"`
+	ApellidoMaterno *string `json:"apellido_materno,omitempty"`
+	NombreJapones   *string `json:"nombre_japones,omitempty"`
+	NombreKanji     *string `json:"nombre_kanji,omitempty"`
+	Genero          *string `json:"genero,omitempty"`
+	Generacion      string  `json:"generacion"`
+	AnioNacimiento  *int    `json:"anio_nacimiento,omitempty"`
+	FotoPerfil      *string `json:"foto_perfil,omitempty"`
+	Acceso          string  `json:"acceso"`
+}
+
+// NodoArbol es una persona del árbol. Nivel es negativo para ancestros y
+// positivo para descendientes. Repetido indica que la persona ya aparece en
+// otra rama y no se vuelve a expandir.
+type NodoArbol struct {
+	PersonaArbol
+	Nivel    int            `json:"nivel"`
+	Repetido bool           `json:"repetido,omitempty"`
+	Conyuges []PersonaArbol `json:"conyuges"`
+	Padres   []*NodoArbol   `json:"padres,omitempty"`
+	Hijos    []*NodoArbol   `json:"hijos,omitempty"`
+}
+
+type ArbolFamiliar struct {
+	Raiz          *NodoArbol `json:"raiz"`
+	Ancestros     int        `json:"ancestros"`
+	Descendientes int        `json:"descendientes"`
+	TotalPersonas int        `json:"total_personas"`
+	Advertencias  []string   `json:"advertencias"`
+}
+
+type constructorArbol struct {
+	grafo        *grafoGenealogico
+	personas     map[uint]*PersonaVista
+	vistos       map[int]map[uint]bool
+	nodos        int
+	recortado    bool
+	advertencias []string
+}
+
+// Arbol arma el árbol de ancestros y descendientes de una persona. Los datos
+// pueden tener ciclos (alguien registrado como su propio abuelo), así que cada
+// rama lleva el camino recorrido y se corta al repetirse una persona.
+func (s *GenealogiaService) Arbol(visor *models.User, idPersona uint, filtros ArbolFiltros) (*ArbolFamiliar, error) {
+	ancestros := generacionesArbolDefault
+	if filtros.Ancestros != nil {
+		ancestros = *filtros.Ancestros
+	}
+	descendientes := generacionesArbolDefault
+	if filtros.Descendientes != nil {
+		descendientes = *filtros.Descendientes
+	}
+
+	if _, err := cargarPersona(s.db, idPersona); err != nil {
+		return nil, err
+	}
+
+	grafo := grafoParaVisor(s.db, visor)
+	if err := precargarNiveles(grafo, idPersona, ancestros, grafo.padresDe); err != nil {
+		return nil, err
+	}
+	if err := precargarNiveles(grafo, idPersona, descendientes, grafo.hijosDe); err != nil {
+		return nil, err
+	}
+
+	ids := append(grafo.personas(), idPersona)
+	personas, err := vistasPorID(s.db, visor, ids)
+	if err != nil {
+		return nil, err
+	}
+	if personas[idPersona] == nil {
+		return nil, ErrPersonaNoEncontrada
+	}
+
+	b := &constructorArbol{
+		grafo:    grafo,
+		personas: personas,
+		vistos:   map[int]map[uint]bool{-1: {}, 1: {}},
+	}
+	for _, r := range grafo.invalidas {
+		b.advertencias = append(b.advertencias,
+			fmt.Sprintf("La relación %d vincula a la persona %d consigo misma y se ignoró", r.IDGenealogia, r.IDPersona))
+	}
+
+	raiz := b.nodo(idPersona, 0)
+	camino := map[uint]bool{idPersona: true}
+	raiz.Padres = b.rama(idPersona, -1, ancestros, camino)
+	raiz.Hijos = b.rama(idPersona, 1, descendientes, camino)
+
+	if b.advertencias == nil {
+		b.advertencias = []string{}
+	}
+	return &ArbolFamiliar{
+		Raiz:          raiz,
+		Ancestros:     ancestros,
+		Descendientes: descendientes,
+		TotalPersonas: b.nodos,
+		Advertencias:  b.advertencias,
+	}, nil
+}
+
+// precargarNiveles expande el grafo generación por generación para hacer una
+// consulta por nivel en lugar de una por persona.
+func precargarNiveles(g *grafoGenealogico, raiz uint, niveles int, siguientes func(uint) []uint) error {
+	frontera := []uint{raiz}
+	vistos := map[uint]bool{raiz: true}
+	for nivel := 0; nivel <= niveles && len(frontera) > 0; nivel++ {
+		if err := g.expandir(frontera); err != nil {
+			return err
+		}
+		if nivel == niveles {
+			break
+		}
+		var proxima []uint
+		for _, id := range frontera {
+			for _, sig := range siguientes(id) {
+				if !vistos[sig] {
+					vistos[sig] = true
+					proxima = append(proxima, sig)
+				}
+			}
+		}
+		frontera = proxima
+	}
+	return nil
+}
+
+// rama construye los padres (direccion -1) o los hijos (direccion 1) de id.
+func (b *constructorArbol) rama(id uint, direccion, restantes int, camino map[uint]bool) []*NodoArbol {
+	if restantes == 0 {
+		return nil
+	}
+
+	siguientes := b.grafo.hijosDe(id)
+	if direccion < 0 {
+		siguientes = b.grafo.padresDe(id)
+	}
+
+	// La raíz está en el camino, así que su largo es la distancia a ella.
+	nivel := len(camino) * direccion
+	var nodos []*NodoArbol
+	for _, sig := range siguientes {
+		if camino[sig] {
+			b.advertencias = append(b.advertencias,
+				fmt.Sprintf("La persona %d aparece como su propio %s; se cortó la rama", sig, nombreDireccion(direccion)))
+			continue
+		}
+		if b.personas[sig] == nil {
+			b.advertencias = append(b.advertencias,
+				fmt.Sprintf("La relación entre %d y %d apunta a una persona que ya no existe", id, sig))
+			continue
+		}
+		if b.nodos >= limiteNodosArbol {
+			if !b.recortado {
+				b.recortado = true
+				b.advertencias = append(b.advertencias,
+					fmt.Sprintf("El árbol se recortó al llegar a %d personas", limiteNodosArbol))
+			}
+			break
+		}
+
+		nodo := b.nodo(sig, nivel)
+		if b.vistos[direccion][sig] {
+			nodo.Repetido = true
+		} else {
+			b.vistos[direccion][sig] = true
+			camino[sig] = true
+			if direccion < 0 {
+				nodo.Padres = b.rama(sig, direccion, restantes-1, camino)
+			} else {
+				nodo.Hijos = b.rama(sig, direccion, restantes-1, camino)
+			}
+			delete(camino, sig)
+		}
+		nodos = append(nodos, nodo)
+	}
+	return nodos
+}
+
+func (b *constructorArbol) nodo(id uint, nivel int) *NodoArbol {
+	b.nodos++
+	nodo := &NodoArbol{
+		PersonaArbol: personaArbol(b.personas[id]),
+		Nivel:        nivel,
+		Conyuges:     []PersonaArbol{},
+	}
+	for _, c := range b.grafo.conyugesDe(id) {
+		if p := b.personas[c]; p != nil {
+			nodo.Conyuges = append(nodo.Conyuges, personaArbol(p))
+		}
+	}
+	return nodo
+}
+
+func nombreDireccion(direccion int) string {
+	if direccion < 0 {
+		return "ancestro"
+	}
+	return "descendiente"
+}
+
+func personaArbol(p *PersonaVista) PersonaArbol {
+	return PersonaArbol{
+		IDPersona:       p.IDPersona,
+		IDFamilia:       p.IDFamilia,
+		Nombres:         p.Nombres,
+		ApellidoPaterno: p.ApellidoPaterno,
+		ApellidoMaterno: p.ApellidoMaterno,
+		NombreJapones:   p.NombreJapones,
+		NombreKanji:     p.NombreKanji,
+		Genero:          p.Genero,
+		Generacion:      p.Generacion,
+		AnioNacimiento:  anioVista(p),
+		FotoPerfil:      p.FotoPerfil,
+		Acceso:          p.Acceso,
+	}
+}
+
+// anioVista toma el año de la fecha completa cuando el nivel la incluye.
+func anioVista(p *PersonaVista) *int {
+	if p.AnioNacimiento == nil && p.FechaNacimiento != nil {
+		anio := p.FechaNacimiento.Year()
+		return &anio
+	}
+	return p.AnioNacimiento
+}
+
+func cargarPersonasPorID(db *gorm.DB, ids []uint) (map[uint]*models.Persona, error) {
+	var lista []models.Persona
+	if err := db.Where("id_persona IN ?", ids).Find(&lista).Error; err != nil {
+		return nil, err
+	}
+	personas := make(map[uint]*models.Persona, len(lista))
+	for i := range lista {
+		personas[lista[i].IDPersona] = &lista[i]
+	}
+	return personas, nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+// visoresGrafo son los usuarios con los que se revisa qué relaciones lee el
+// grafo: solo el admin ve las que aún no se confirman.
+var visoresGrafo = []struct {
+	nombre      string
+	visor       *models.User
+	confirmadas bool
+}{
+	{"admin", &models.User{IDUser: 1, Role: "admin"}, false},
+	{"miembro", &models.User{IDUser: 2, Role: "miembro"}, true},
+	{"sin sesión", nil, true},
+}
+
+// baseConPersonas responde a cualquier consulta de personas con la persona
+// 1, visible para todos.
+func baseConPersonas(t *testing.T) (*GenealogiaService, *dbtest.Base) {
+	db, base := dbtest.Nueva(t)
+	base.Responder(`FROM "personas"`, dbtest.Filas([]string{"id_persona", "id_familia", "nombres", "acepta_directorio_publico"},
+		[]driver.Value{int64(1), int64(1), "Taro", true}))
+	return NewGenealogiaService(db), base
+}
+
+// revisarConsultasGrafo falla si alguna lectura de relaciones del grafo no
+// coincide con lo que el visor puede ver.
+func revisarConsultasGrafo(t *testing.T, base *dbtest.Base, nombre string, confirmadas bool) {
+	t.Helper()
+	consultas := base.Buscar(`FROM "genealogia" WHERE tipo_relacion IN`)
+	if len(consultas) == 0 {
+		t.Errorf("%s: no se leyeron relaciones", nombre)
+	}
+	for _, q := range consultas {
+		if strings.Contains(q, "confirmado_ambas_partes") != confirmadas {
+			t.Errorf("%s: confirmadas = %v en %q", nombre, !confirmadas, q)
+		}
+	}
+}
+
+func TestArbolSoloConfirmadasParaNoAdmins(t *testing.T) {
+	for _, v := range visoresGrafo {
+		s, base := baseConPersonas(t)
+		if _, err := s.Arbol(v.visor, 1, ArbolFiltros{}); err != nil {
+			t.Errorf("%s: %v", v.nombre, err)
+		}
+		revisarConsultasGrafo(t, base, v.nombre, v.confirmadas)
+	}
+}
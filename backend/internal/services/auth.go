@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/config"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var (
+	ErrCredencialesInvalidas = errors.New("correo o contraseña incorrectos")
+	ErrUsuarioInactivo       = errors.New("la cuenta está desactivada")
+	ErrSesionInvalida        = errors.New("la sesión no es válida o ya fue cerrada")
+)
+
+// dummyHash se compara cuando el correo no existe para que el tiempo de
+// respuesta no revele qué cuentas están registradas.
+var dummyHash, _ = utils.HashPassword(utils.RandomToken(16))
+
+type AuthService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+type AuthTokens struct {
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+	TokenType    string       `json:"token_type"`
+	ExpiresIn    int64        `json:"expires_in"`
+	User         *models.User `json:"user"`
+}
+
+type Perfil struct {
+	User    *models.User  `json:"user"`
+	Persona *PersonaVista `json:"persona"`
+}
+
+type ClienteInfo struct {
+	UserAgent string
+	IP        string
+}
+
+func NewAuthService(db *gorm.DB, cfg *config.Config) *AuthService {
+	return &AuthService{db: db, cfg: cfg}
+}
+
+func (s *AuthService) Login(email, password string, cliente ClienteInfo) (*AuthTokens, error) {
+	var user models.User
+	err := s.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.CheckPassword(dummyHash, password)
+		return nil, ErrCredencialesInvalidas
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.CheckPassword(user.PasswordHash, password) {
+		return nil, ErrCredencialesInvalidas
+	}
+	if !user.IsActive {
+		return nil, ErrUsuarioInactivo
+	}
+
+	var tokens *AuthTokens
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		sesion := models.Sesion{
+			IDUser:           user.IDUser,
+			RefreshTokenHash: utils.HashToken(utils.RandomToken(32)),
+			ExpiraEn:         time.Now().Add(s.cfg.JWTRefreshExpiresIn),
+			UserAgent:        optionalString(cliente.UserAgent, 300),
+			IP:               optionalString(cliente.IP, 45),
+		}
+		if err := tx.Create(&sesion).Error; err != nil {
+			return err
+		}
+
+		var err error
+		tokens, err = s.emitirTokens(tx, &user, &sesion)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		user.LastLogin = &now
+		return tx.Model(&user).Update("last_login", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *AuthService) Refresh(refreshToken string) (*AuthTokens, error) {
+	claims, err := utils.ParseToken(s.cfg.JWTSecret, refreshToken, utils.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	var sesion models.Sesion
+	if err := s.db.First(&sesion, claims.SesionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSesionInvalida
+		}
+		return nil, err
+	}
+	if !sesion.EstaActiva() || sesion.IDUser != claims.UserID {
+		return nil, ErrSesionInvalida
+	}
+
+	// Un refresh token ya rotado que vuelve a presentarse indica que fue
+	// robado: se cierra la sesión completa.
+	tokenHash := utils.HashToken(refreshToken)
+	if sesion.RefreshTokenHash != tokenHash {
+		s.db.Model(&sesion).Update("revocada_en", time.Now())
+		return nil, ErrSesionInvalida
+	}
+
+	var user models.User
+	if err := s.db.First(&user, claims.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSesionInvalida
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrUsuarioInactivo
+	}
+
+	var tokens *AuthTokens
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Solo una petición concurrente puede rotar el mismo refresh token.
+		result := tx.Model(&models.Sesion{}).
+			Where("id_sesion = ? AND refresh_token_hash = ? AND revocada_en IS NULL", sesion.IDSesion, tokenHash).
+			Update("refresh_token_hash", utils.HashToken(utils.RandomToken(32)))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSesionInvalida
+		}
+
+		var err error
+		tokens, err = s.emitirTokens(tx, &user, &sesion)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *AuthService) Logout(refreshToken string) error {
+	claims, err := utils.ParseToken(s.cfg.JWTSecret, refreshToken, utils.TokenTypeRefresh)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Model(&models.Sesion{}).
+		Where("id_sesion = ? AND id_user = ? AND revocada_en IS NULL", claims.SesionID, claims.UserID).
+		Update("revocada_en", time.Now()).Error
+}
+
+func (s *AuthService) GetPerfil(user *models.User) (*Perfil, error) {
+	perfil := &Perfil{User: user}
+	if user.IDPersona == nil {
+		return perfil, nil
+	}
+
+	var persona models.Persona
+	err := s.db.First(&persona, *user.IDPersona).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		nivel := AccesoPropio
+		if user.EsAdmin() {
+			nivel = AccesoAdmin
+		}
+		perfil.Persona = ProyectarPersona(&persona, nivel)
+	}
+	return perfil, nil
+}
+
+func (s *AuthService) emitirTokens(tx *gorm.DB, user *models.User, sesion *models.Sesion) (*AuthTokens, error) {
+	accessToken, _, err := utils.GenerateToken(s.cfg.JWTSecret, user.IDUser, user.Email, user.Role,
+		sesion.IDSesion, utils.TokenTypeAccess, s.cfg.JWTExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshExpira, err := utils.GenerateToken(s.cfg.JWTSecret, user.IDUser, user.Email, user.Role,
+		sesion.IDSesion, utils.TokenTypeRefresh, s.cfg.JWTRefreshExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Model(sesion).Updates(map[string]interface{}{
+		"refresh_token_hash": utils.HashToken(refreshToken),
+		"expira_en":          refreshExpira,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("actualizando sesión: %w", err)
+	}
+
+	return &AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.cfg.JWTExpiresIn.Seconds()),
+		User:         user,
+	}, nil
+}
+
+func optionalString(value string, maxLen int) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	return &value
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/config"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var (
+	ErrCheckinNoDisponible  = errors.New("solo las participaciones confirmadas tienen código de acceso")
+	ErrCheckinOtroEvento    = errors.New("el código corresponde a otro evento")
+	ErrCheckinEventoCerrado = errors.New("el evento no está abierto para registrar asistencia")
+)
+
+type ResultadoCheckin struct {
+	Participacion *models.ParticipacionEvento `json:"participacion"`
+	Persona       *models.Persona             `json:"persona"`
+	// YaRegistrado es true cuando el mismo código se escaneó antes; la
+	// respuesta es la misma para que la puerta no muestre un error.
+	YaRegistrado bool `json:"ya_registrado"`
+}
+
+type CheckinService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewCheckinService(db *gorm.DB, cfg *config.Config) *CheckinService {
+	return &CheckinService{db: db, cfg: cfg}
+}
+
+func (s *CheckinService) Token(idParticipacion uint) (string, error) {
+	var participacion models.ParticipacionEvento
+	if err := s.db.First(&participacion, idParticipacion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrParticipacionNoEncontrada
+		}
+		return "", err
+	}
+	if !participacion.EstaConfirmado() && !participacion.Asistio() {
+		return "", ErrCheckinNoDisponible
+	}
+
+	return utils.FirmarCheckin(s.cfg.JWTSecret, participacion.IDParticipacion, participacion.IDEvento), nil
+}
+
+func (s *CheckinService) QR(idParticipacion uint, size int) ([]byte, error) {
+	token, err := s.Token(idParticipacion)
+	if err != nil {
+		return nil, err
+	}
+	return qrcode.Encode(token, qrcode.Medium, size)
+}
+
+// RegistrarAsistencia valida el código escaneado en la puerta y marca la
+// asistencia. Es idempotente: un segundo escaneo devuelve el mismo resultado.
+func (s *CheckinService) RegistrarAsistencia(idEvento uint, token string) (*ResultadoCheckin, error) {
+	idParticipacion, idEventoToken, err := utils.VerificarCheckin(s.cfg.JWTSecret, token)
+	if err != nil {
+		return nil, err
+	}
+	if idEventoToken != idEvento {
+		return nil, ErrCheckinOtroEvento
+	}
+
+	resultado := &ResultadoCheckin{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var evento models.Evento
+		if err := tx.First(&evento, idEvento).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrEventoNoEncontrado
+			}
+			return err
+		}
+		if !evento.AceptaRegistros() {
+			return ErrCheckinEventoCerrado
+		}
+
+		var participacion models.ParticipacionEvento
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id_participacion = ? AND id_evento = ?", idParticipacion, idEvento).
+			First(&participacion).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrParticipacionNoEncontrada
+			}
+			return err
+		}
+
+		switch {
+		case participacion.Asistio():
+			resultado.YaRegistrado = true
+		case participacion.EstaConfirmado():
+			participacion.MarcarAsistencia()
+			if err := tx.Model(&participacion).Update("status_participacion", participacion.StatusParticipacion).Error; err != nil {
+				return err
+			}
+		default:
+			return ErrCheckinNoDisponible
+		}
+
+		var persona models.Persona
+		if err := tx.First(&persona, participacion.IDPersona).Error; err != nil {
+			return err
+		}
+
+		resultado.Participacion = &participacion
+		resultado.Persona = &persona
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resultado, nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/config"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/mailer"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+const (
+	tokenVerificacionEmail = "verificacion_email"
+	tokenResetPassword     = "reset_password"
+
+	verificacionTTL = 48 * time.Hour
+	resetTTL        = time.Hour
+)
+
+var (
+	ErrTokenCuentaInvalido = errors.New("el enlace no es válido, ya fue usado o expiró")
+	ErrEmailYaVerificado   = errors.New("el correo ya fue verificado")
+)
+
+// CuentaService maneja los flujos de correo de una cuenta: verificación del
+// email y recuperación de contraseña mediante tokens de un solo uso.
+type CuentaService struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	mailer mailer.Mailer
+}
+
+func NewCuentaService(db *gorm.DB, cfg *config.Config, m mailer.Mailer) *CuentaService {
+	return &CuentaService{db: db, cfg: cfg, mailer: m}
+}
+
+func (s *CuentaService) EnviarVerificacion(user *models.User) error {
+	if user.EmailVerified {
+		return ErrEmailYaVerificado
+	}
+
+	token, err := s.crearToken(user.IDUser, tokenVerificacionEmail, verificacionTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verificar-email?token=%s", strings.TrimRight(s.cfg.FrontendURL, "/"), token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirma tu correo - Asociación Nikkei de Sinaloa",
+		Body: "Hola,\n\n" +
+			"Gracias por registrarte en el Sistema Nikkei. Para confirmar tu correo abre el siguiente enlace:\n\n" +
+			link + "\n\n" +
+			"El enlace vence en 48 horas. Si no creaste esta cuenta puedes ignorar este mensaje.\n",
+	})
+}
+
+func (s *CuentaService) VerificarEmail(token string) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		registro, err := s.consumirToken(tx, token, tokenVerificacionEmail)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&user, registro.IDUser).Error; err != nil {
+			return err
+		}
+		user.EmailVerified = true
+		return tx.Model(&user).Update("email_verified", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SolicitarResetPassword nunca revela si el correo existe: las cuentas
+// desconocidas o inactivas simplemente no reciben mensaje.
+func (s *CuentaService) SolicitarResetPassword(email string) error {
+	var user models.User
+	err := s.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	token, err := s.crearToken(user.IDUser, tokenResetPassword, resetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/restablecer-password?token=%s", strings.TrimRight(s.cfg.FrontendURL, "/"), token)
+	if err := s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Restablece tu contraseña - Asociación Nikkei de Sinaloa",
+		Body: "Hola,\n\n" +
+			"Recibimos una solicitud para restablecer la contraseña de tu cuenta. Abre el siguiente enlace para elegir una nueva:\n\n" +
+			link + "\n\n" +
+			"El enlace vence en 1 hora. Si no fuiste tú, ignora este mensaje y tu contraseña seguirá igual.\n",
+	}); err != nil {
+		log.Printf("Error enviando correo de recuperación a %s: %v", user.Email, err)
+	}
+	return nil
+}
+
+// ResetPassword cambia la contraseña y cierra todas las sesiones abiertas.
+func (s *CuentaService) ResetPassword(token, nuevaPassword string) error {
+	hash, err := utils.HashPassword(nuevaPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		registro, err := s.consumirToken(tx, token, tokenResetPassword)
+		if err != nil {
+			return err
+		}
+
+		// Quien recibe el enlace demuestra control del correo.
+		err = tx.Model(&models.User{}).Where("id_user = ?", registro.IDUser).
+			Updates(map[string]interface{}{
+				"password_hash":  hash,
+				"email_verified": true,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Sesion{}).
+			Where("id_user = ? AND revocada_en IS NULL", registro.IDUser).
+			Update("revocada_en", time.Now()).Error
+	})
+}
+
+// crearToken invalida los tokens anteriores del mismo tipo para que solo el
+// enlace más reciente funcione.
+func (s *CuentaService) crearToken(idUser uint, tipo string, ttl time.Duration) (string, error) {
+	token := utils.RandomToken(32)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.TokenUsuario{}).
+			Where("id_user = ? AND tipo = ? AND usado_en IS NULL", idUser, tipo).
+			Update("usado_en", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.TokenUsuario{
+			IDUser:    idUser,
+			Tipo:      tipo,
+			TokenHash: utils.HashToken(token),
+			ExpiraEn:  time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *CuentaService) consumirToken(tx *gorm.DB, token, tipo string) (*models.TokenUsuario, error) {
+	var registro models.TokenUsuario
+	err := tx.Where("token_hash = ? AND tipo = ?", utils.HashToken(token), tipo).First(&registro).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenCuentaInvalido
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !registro.EsValido() {
+		return nil, ErrTokenCuentaInvalido
+	}
+
+	// La condición sobre usado_en evita que dos peticiones simultáneas
+	// consuman el mismo token.
+	result := tx.Model(&models.TokenUsuario{}).
+		Where("id_token = ? AND usado_en IS NULL", registro.IDToken).
+		Update("usado_en", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTokenCuentaInvalido
+	}
+
+	return &registro, nil
+}
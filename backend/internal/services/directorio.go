@@ -0,0 +1,150 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
+)
+
+const limiteDirectorioDefault = 20
+
+type DirectorioFiltros struct {
+	Q      string  `form:"q" binding:"required,min=2,max=100"`
+	Tipo   *string `form:"tipo" binding:"omitempty,oneof=persona familia empresa"`
+	Limite *int    `form:"limite" binding:"omitempty,min=1,max=50"`
+}
+
+// ResultadoDirectorio es una coincidencia de cualquiera de las tres tablas.
+// Fragmento trae las palabras encontradas entre <mark> y </mark>.
+type ResultadoDirectorio struct {
+	Tipo      string  `json:"tipo"`
+	ID        uint    `json:"id"`
+	Titulo    string  `json:"titulo"`
+	Subtitulo *string `json:"subtitulo"`
+	Fragmento string  `json:"fragmento"`
+	Rango     float64 `json:"rango"`
+}
+
+type BusquedaDirectorio struct {
+	Consulta   string                `json:"consulta"`
+	Resultados []ResultadoDirectorio `json:"resultados"`
+}
+
+// fuenteDirectorio describe cómo buscar en una tabla. Las expresiones son
+// SQL fijo; solo la consulta del usuario viaja como parámetro.
+type fuenteDirectorio struct {
+	tipo           string
+	tabla          string
+	id             string
+	titulo         string
+	subtitulo      string
+	texto          string
+	consentimiento string
+}
+
+var fuentesDirectorio = []fuenteDirectorio{
+	{
+		tipo:           "persona",
+		tabla:          "personas",
+		id:             "id_persona",
+		titulo:         "nombres || ' ' || apellido_paterno || coalesce(' ' || apellido_materno, '')",
+		subtitulo:      "ciudad",
+		texto:          "nombres || ' ' || apellido_paterno || coalesce(' ' || apellido_materno, '') || coalesce(' · ' || ciudad, '') || coalesce(' · ' || puesto, '')",
+		consentimiento: "acepta_directorio_publico = true",
+	},
+	{
+		tipo:      "familia",
+		tabla:     "familias",
+		id:        "id_familia",
+		titulo:    "apellido_jp || coalesce(' (' || apellido_kanji || ')', '')",
+		subtitulo: "prefectura_origen",
+		texto:     "apellido_jp || coalesce(' · ' || apellido_romanji, '') || coalesce(' · ' || prefectura_origen, '') || coalesce(' · ' || historia_familiar, '')",
+	},
+	{
+		tipo:           "empresa",
+		tabla:          "empresas",
+		id:             "id_empresa",
+		titulo:         "nombre_empresa",
+		subtitulo:      "giro_comercial",
+		texto:          "nombre_empresa || coalesce(' · ' || giro_comercial, '') || coalesce(' · ' || ciudad, '') || coalesce(' · ' || descripcion, '')",
+		consentimiento: "acepta_promocion_directorio = true",
+	},
+}
+
+type DirectorioService struct {
+	db *gorm.DB
+}
+
+func NewDirectorioService(db *gorm.DB) *DirectorioService {
+	return &DirectorioService{db: db}
+}
+
+// Buscar combina la búsqueda de texto completo (en español y sin acentos)
+// con la similitud por trigramas sobre la clave normalizada, que es la que
+// encuentra "Satou" o "さとう" cuando la familia se registró como Satō.
+// Sin incluirPrivados solo aparecen las personas y empresas que aceptaron
+// salir en el directorio.
+func (s *DirectorioService) Buscar(filtros DirectorioFiltros, incluirPrivados bool) (*BusquedaDirectorio, error) {
+	consulta := strings.TrimSpace(filtros.Q)
+	limite := limiteDirectorioDefault
+	if filtros.Limite != nil {
+		limite = *filtros.Limite
+	}
+
+	clave := japones.Normalizar(consulta)
+	resultados := []ResultadoDirectorio{}
+	if clave == "" {
+		return &BusquedaDirectorio{Consulta: consulta, Resultados: resultados}, nil
+	}
+	for _, fuente := range fuentesDirectorio {
+		if filtros.Tipo != nil && *filtros.Tipo != fuente.tipo {
+			continue
+		}
+
+		var filas []ResultadoDirectorio
+		err := s.db.Raw(fuente.sql(incluirPrivados), clave, consulta, clave, "%"+clave+"%", limite).
+			Scan(&filas).Error
+		if err != nil {
+			return nil, err
+		}
+		for i := range filas {
+			filas[i].Tipo = fuente.tipo
+		}
+		resultados = append(resultados, filas...)
+	}
+
+	sort.SliceStable(resultados, func(i, j int) bool {
+		return resultados[i].Rango > resultados[j].Rango
+	})
+	if len(resultados) > limite {
+		resultados = resultados[:limite]
+	}
+
+	return &BusquedaDirectorio{Consulta: consulta, Resultados: resultados}, nil
+}
+
+// sql recibe, en orden: la clave normalizada para el rango, la consulta, otra
+// vez la clave para el filtro por trigramas, la clave con comodines y el
+// límite.
+func (f fuenteDirectorio) sql(incluirPrivados bool) string {
+	condicion := "(documento_busqueda @@ consulta OR ? <% clave_busqueda OR clave_busqueda LIKE ?)"
+	if !incluirPrivados && f.consentimiento != "" {
+		condicion += " AND " + f.consentimiento
+	}
+	return fmt.Sprintf(`
+		SELECT %[1]s AS id,
+			%[2]s AS titulo,
+			%[3]s AS subtitulo,
+			ts_headline('es_unaccent', %[4]s, consulta,
+				'StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=8, MaxFragments=2') AS fragmento,
+			ts_rank(documento_busqueda, consulta) + word_similarity(?, clave_busqueda) AS rango
+		FROM %[5]s, websearch_to_tsquery('es_unaccent', ?) AS consulta
+		WHERE %[6]s
+		ORDER BY rango DESC, %[1]s ASC
+		LIMIT ?`,
+		f.id, f.titulo, f.subtitulo, f.texto, f.tabla, condicion)
+}
@@ -0,0 +1,587 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/mailer"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var (
+	ErrFusionUsuarios = errors.New("ambas personas tienen una cuenta de usuario; desvincule una antes de fusionar")
+	ErrFusionEmpresas = errors.New("ambas personas son propietarias de una empresa; reasigne una antes de fusionar")
+)
+
+const (
+	puntajeDuplicadoDefault = 60
+	limiteDuplicadosDefault = 100
+)
+
+type DuplicadosFiltros struct {
+	MinPuntaje *int  `form:"min_puntaje" binding:"omitempty,min=1,max=100"`
+	IDFamilia  *uint `form:"id_familia"`
+	Limite     *int  `form:"limite" binding:"omitempty,min=1,max=500"`
+}
+
+// CandidatoDuplicado es un par de personas que probablemente son la misma.
+// Motivos explica de dónde sale el puntaje para que el administrador decida.
+type CandidatoDuplicado struct {
+	Persona   models.Persona `json:"persona"`
+	Duplicado models.Persona `json:"duplicado"`
+	Puntaje   int            `json:"puntaje"`
+	Motivos   []string       `json:"motivos"`
+}
+
+type FusionInput struct {
+	IDDuplicado uint `json:"id_duplicado" binding:"required"`
+}
+
+type ResultadoFusion struct {
+	Persona                    models.Persona `json:"persona"`
+	IDEliminada                uint           `json:"id_eliminada"`
+	RelacionesMovidas          int64          `json:"relaciones_movidas"`
+	RelacionesDescartadas      int64          `json:"relaciones_descartadas"`
+	ParticipacionesMovidas     int64          `json:"participaciones_movidas"`
+	ParticipacionesDescartadas int64          `json:"participaciones_descartadas"`
+	UsuarioMovido              bool           `json:"usuario_movido"`
+	EmpresaMovida              bool           `json:"empresa_movida"`
+	CamposCompletados          []string       `json:"campos_completados"`
+}
+
+type DuplicadosService struct {
+	db     *gorm.DB
+	mailer mailer.Mailer
+}
+
+func NewDuplicadosService(db *gorm.DB, m mailer.Mailer) *DuplicadosService {
+	return &DuplicadosService{db: db, mailer: m}
+}
+
+// perfilComparable guarda los datos de una persona ya normalizados para no
+// repetir el trabajo en cada par.
+type perfilComparable struct {
+	persona  *models.Persona
+	nombres  []string
+	apellido string
+	materno  string
+	japones  string
+	kanji    string
+}
+
+func nuevoPerfil(p *models.Persona) *perfilComparable {
+	perfil := &perfilComparable{
+		persona:  p,
+		nombres:  strings.Fields(japones.Normalizar(p.Nombres)),
+		apellido: japones.Normalizar(p.ApellidoPaterno),
+	}
+	if p.ApellidoMaterno != nil {
+		perfil.materno = japones.Normalizar(*p.ApellidoMaterno)
+	}
+	if p.NombreJapones != nil {
+		perfil.japones = japones.Normalizar(*p.NombreJapones)
+	}
+	if p.NombreKanji != nil {
+		perfil.kanji = strings.Join(strings.Fields(*p.NombreKanji), "")
+	}
+	return perfil
+}
+
+// Candidatos compara las personas que comparten apellido, nombre en kanji o
+// fecha de nacimiento y devuelve los pares que alcanzan el puntaje mínimo,
+// del más probable al menos probable.
+func (s *DuplicadosService) Candidatos(filtros DuplicadosFiltros) ([]CandidatoDuplicado, error) {
+	minimo := puntajeDuplicadoDefault
+	if filtros.MinPuntaje != nil {
+		minimo = *filtros.MinPuntaje
+	}
+	limite := limiteDuplicadosDefault
+	if filtros.Limite != nil {
+		limite = *filtros.Limite
+	}
+
+	query := s.db.Order("id_persona ASC")
+	if filtros.IDFamilia != nil {
+		query = query.Where("id_familia = ?", *filtros.IDFamilia)
+	}
+	var personas []models.Persona
+	if err := query.Find(&personas).Error; err != nil {
+		return nil, err
+	}
+
+	// Solo se comparan personas que coinciden en al menos una clave; así no
+	// hace falta revisar todos contra todos. El apellido se agrupa por sus
+	// primeras letras para que Sato y Satoh caigan en el mismo bloque.
+	bloques := make(map[string][]*perfilComparable)
+	for i := range personas {
+		perfil := nuevoPerfil(&personas[i])
+		claves := []string{"a:" + prefijoRunas(perfil.apellido, 3)}
+		if perfil.kanji != "" {
+			claves = append(claves, "k:"+perfil.kanji)
+		}
+		if p := perfil.persona; p.FechaNacimiento != nil {
+			claves = append(claves, "f:"+p.FechaNacimiento.Format(utils.FechaLayout))
+		}
+		for _, clave := range claves {
+			bloques[clave] = append(bloques[clave], perfil)
+		}
+	}
+
+	vistos := make(map[parPersonas]bool)
+	candidatos := []CandidatoDuplicado{}
+	for _, bloque := range bloques {
+		for i := 0; i < len(bloque); i++ {
+			for j := i + 1; j < len(bloque); j++ {
+				a, b := bloque[i], bloque[j]
+				par := parPersonas{a.persona.IDPersona, b.persona.IDPersona}
+				if vistos[par] {
+					continue
+				}
+				vistos[par] = true
+
+				puntaje, motivos := puntuarDuplicado(a, b)
+				if puntaje >= minimo {
+					candidatos = append(candidatos, CandidatoDuplicado{
+						Persona:   *a.persona,
+						Duplicado: *b.persona,
+						Puntaje:   puntaje,
+						Motivos:   motivos,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidatos, func(i, j int) bool {
+		if candidatos[i].Puntaje != candidatos[j].Puntaje {
+			return candidatos[i].Puntaje > candidatos[j].Puntaje
+		}
+		if candidatos[i].Persona.IDPersona != candidatos[j].Persona.IDPersona {
+			return candidatos[i].Persona.IDPersona < candidatos[j].Persona.IDPersona
+		}
+		return candidatos[i].Duplicado.IDPersona < candidatos[j].Duplicado.IDPersona
+	})
+	if len(candidatos) > limite {
+		candidatos = candidatos[:limite]
+	}
+	return candidatos, nil
+}
+
+// puntuarDuplicado devuelve un puntaje de 0 a 100. Los nombres y la fecha de
+// nacimiento pesan más; una fecha o un género distintos restan porque casi
+// siempre indican personas diferentes con el mismo nombre.
+func puntuarDuplicado(a, b *perfilComparable) (int, []string) {
+	puntaje := 0
+	var motivos []string
+	sumar := func(puntos int, motivo string) {
+		puntaje += puntos
+		motivos = append(motivos, motivo)
+	}
+
+	switch {
+	case a.apellido == b.apellido:
+		sumar(30, "mismo apellido paterno")
+	case palabrasParecidas(a.apellido, b.apellido):
+		sumar(20, "apellido paterno parecido")
+	}
+
+	if similitud := similitudNombres(a.nombres, b.nombres); similitud > 0 {
+		if similitud == 1 {
+			sumar(30, "mismos nombres")
+		} else {
+			sumar(int(30*similitud), "nombres parecidos o abreviados")
+		}
+	}
+
+	if a.kanji != "" && a.kanji == b.kanji {
+		sumar(15, "mismo nombre en kanji")
+	}
+	if a.japones != "" && a.japones == b.japones {
+		sumar(10, "mismo nombre japonés")
+	}
+
+	if fa, fb := a.persona.FechaNacimiento, b.persona.FechaNacimiento; fa != nil && fb != nil {
+		switch {
+		case fa.Equal(*fb):
+			sumar(20, "misma fecha de nacimiento")
+		case fa.Year() == fb.Year():
+			sumar(5, "mismo año de nacimiento")
+		default:
+			sumar(-25, "fechas de nacimiento distintas")
+		}
+	}
+
+	if a.materno != "" && b.materno != "" {
+		if a.materno == b.materno {
+			sumar(5, "mismo apellido materno")
+		} else if !palabrasParecidas(a.materno, b.materno) {
+			sumar(-10, "apellidos maternos distintos")
+		}
+	}
+
+	if a.persona.IDFamilia == b.persona.IDFamilia {
+		sumar(5, "misma familia")
+	}
+
+	if ga, gb := a.persona.Genero, b.persona.Genero; ga != nil && gb != nil && *ga != *gb &&
+		esGeneroBinario(*ga) && esGeneroBinario(*gb) {
+		sumar(-30, "géneros distintos")
+	}
+
+	return max(0, min(100, puntaje)), motivos
+}
+
+// similitudNombres mide qué parte de los nombres más cortos aparece en los
+// otros. Una abreviatura ("Ma." por "María") o un error de una letra cuentan
+// como coincidencia parcial.
+func similitudNombres(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	usados := make([]bool, len(b))
+	total := 0.0
+	for _, palabra := range a {
+		mejor, indice := 0.0, -1
+		for j, otra := range b {
+			if usados[j] {
+				continue
+			}
+			valor := 0.0
+			switch {
+			case palabra == otra:
+				valor = 1
+			case strings.HasPrefix(otra, palabra) || strings.HasPrefix(palabra, otra):
+				valor = 0.75
+			case palabrasParecidas(palabra, otra):
+				valor = 0.75
+			}
+			if valor > mejor {
+				mejor, indice = valor, j
+			}
+		}
+		if indice >= 0 {
+			usados[indice] = true
+			total += mejor
+		}
+	}
+	return total / float64(len(a))
+}
+
+// palabrasParecidas tolera una letra de diferencia en palabras de cuatro
+// letras o más (Sato/Satoh, Gonzalez/Gonzales).
+func palabrasParecidas(a, b string) bool {
+	if len([]rune(a)) < 4 || len([]rune(b)) < 4 {
+		return false
+	}
+	return distanciaEdicion(a, b) <= 1
+}
+
+func distanciaEdicion(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previa := make([]int, len(rb)+1)
+	actual := make([]int, len(rb)+1)
+	for j := range previa {
+		previa[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		actual[0] = i
+		for j := 1; j <= len(rb); j++ {
+			costo := 1
+			if ra[i-1] == rb[j-1] {
+				costo = 0
+			}
+			actual[j] = min(previa[j]+1, actual[j-1]+1, previa[j-1]+costo)
+		}
+		previa, actual = actual, previa
+	}
+	return previa[len(rb)]
+}
+
+func prefijoRunas(s string, n int) string {
+	runas := []rune(s)
+	if len(runas) > n {
+		runas = runas[:n]
+	}
+	return string(runas)
+}
+
+func esGeneroBinario(genero string) bool {
+	return genero == "masculino" || genero == "femenino"
+}
+
+// Fusionar pasa todas las referencias del duplicado a la persona que se
+// conserva y elimina el duplicado. Los datos vacíos de la persona conservada
+// se completan con los del duplicado; los que ya tiene no se tocan.
+func (s *DuplicadosService) Fusionar(idConservar uint, input FusionInput) (*ResultadoFusion, error) {
+	if idConservar == input.IDDuplicado {
+		return nil, utils.FieldErrors{"id_duplicado": "debe ser distinta de la persona que se conserva"}
+	}
+
+	resultado := &ResultadoFusion{IDEliminada: input.IDDuplicado, CamposCompletados: []string{}}
+	var promovidos []models.ParticipacionEvento
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		conservar, err := cargarPersona(tx.Clauses(clause.Locking{Strength: "UPDATE"}), idConservar)
+		if err != nil {
+			return err
+		}
+		duplicado, err := cargarPersona(tx.Clauses(clause.Locking{Strength: "UPDATE"}), input.IDDuplicado)
+		if err != nil {
+			return err
+		}
+
+		if resultado.UsuarioMovido, err = moverReferenciaUnica(tx, "users", "id_persona", conservar.IDPersona, duplicado.IDPersona, ErrFusionUsuarios); err != nil {
+			return err
+		}
+		if resultado.EmpresaMovida, err = moverReferenciaUnica(tx, "empresas", "id_propietario", conservar.IDPersona, duplicado.IDPersona, ErrFusionEmpresas); err != nil {
+			return err
+		}
+		if promovidos, err = moverParticipaciones(tx, conservar.IDPersona, duplicado.IDPersona, resultado); err != nil {
+			return err
+		}
+		if err := moverRelaciones(tx, conservar.IDPersona, duplicado.IDPersona, resultado); err != nil {
+			return err
+		}
+
+		resultado.CamposCompletados = completarPersona(conservar, duplicado)
+		nota := fmt.Sprintf("Fusionada con la persona %d (%s)", duplicado.IDPersona, duplicado.GetNombreCompleto())
+		if conservar.NotasAdministrativas != nil && *conservar.NotasAdministrativas != "" {
+			nota = *conservar.NotasAdministrativas + "\n" + nota
+		}
+		conservar.NotasAdministrativas = &nota
+		if err := tx.Save(conservar).Error; err != nil {
+			return traducirErrorPersona(err)
+		}
+
+		if err := tx.Delete(&models.Persona{}, duplicado.IDPersona).Error; err != nil {
+			if esViolacionFK(err) {
+				return ErrPersonaConReferencias
+			}
+			return err
+		}
+
+		resultado.Persona = *conservar
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notificarPromociones(s.db, s.mailer, promovidos)
+	return resultado, nil
+}
+
+// moverReferenciaUnica reasigna una columna con índice único. Si las dos
+// personas ya tienen un registro no hay forma de unirlos sin perder datos, y
+// se devuelve conflicto.
+func moverReferenciaUnica(tx *gorm.DB, tabla, columna string, conservar, duplicado uint, conflicto error) (bool, error) {
+	var propios, ajenos int64
+	if err := tx.Table(tabla).Where(columna+" = ?", conservar).Count(&propios).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Table(tabla).Where(columna+" = ?", duplicado).Count(&ajenos).Error; err != nil {
+		return false, err
+	}
+	if ajenos == 0 {
+		return false, nil
+	}
+	if propios > 0 {
+		return false, conflicto
+	}
+	if err := tx.Table(tabla).Where(columna+" = ?", duplicado).Update(columna, conservar).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// rangoParticipacion ordena los estados de una participación para decidir
+// cuál conservar al fusionar; gana el que indica más compromiso.
+var rangoParticipacion = map[string]int{
+	"asistio":    5,
+	"confirmado": 4,
+	"registrado": 3,
+	"no_asistio": 2,
+	"en_espera":  1,
+	"cancelado":  0,
+}
+
+// moverParticipaciones pasa los registros del duplicado a la persona que
+// queda. Cuando ambas se registraron al mismo evento se conserva el registro
+// con el estado más fuerte (el de la persona conservada si empatan) y, si el
+// descartado ocupaba lugar, se promueve la lista de espera del evento.
+func moverParticipaciones(tx *gorm.DB, conservar, duplicado uint, resultado *ResultadoFusion) ([]models.ParticipacionEvento, error) {
+	var participaciones []models.ParticipacionEvento
+	err := tx.Where("id_persona IN ?", []uint{conservar, duplicado}).
+		Order("id_evento ASC").
+		Find(&participaciones).Error
+	if err != nil {
+		return nil, err
+	}
+
+	porEvento := make(map[uint][]models.ParticipacionEvento)
+	var eventos []uint
+	for _, p := range participaciones {
+		if len(porEvento[p.IDEvento]) == 0 {
+			eventos = append(eventos, p.IDEvento)
+		}
+		porEvento[p.IDEvento] = append(porEvento[p.IDEvento], p)
+	}
+
+	// Los eventos se bloquean en orden de ID, igual que al registrarse, antes
+	// de tocar sus participaciones.
+	var liberados []*models.Evento
+	for _, idEvento := range eventos {
+		par := porEvento[idEvento]
+		if len(par) < 2 {
+			continue
+		}
+		evento, err := bloquearEvento(tx, idEvento)
+		if err != nil {
+			return nil, err
+		}
+
+		propia, ajena := par[0], par[1]
+		if propia.IDPersona != conservar {
+			propia, ajena = ajena, propia
+		}
+		descartada := ajena
+		if rangoParticipacion[ajena.StatusParticipacion] > rangoParticipacion[propia.StatusParticipacion] {
+			descartada = propia
+		}
+		if err := tx.Delete(&models.ParticipacionEvento{}, descartada.IDParticipacion).Error; err != nil {
+			return nil, err
+		}
+		resultado.ParticipacionesDescartadas++
+		if descartada.OcupaLugar() && evento.AceptaRegistros() {
+			liberados = append(liberados, evento)
+		}
+	}
+
+	res := tx.Model(&models.ParticipacionEvento{}).Where("id_persona = ?", duplicado).Update("id_persona", conservar)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	resultado.ParticipacionesMovidas = res.RowsAffected
+
+	var promovidos []models.ParticipacionEvento
+	for _, evento := range liberados {
+		nuevos, err := promoverListaEspera(tx, evento)
+		if err != nil {
+			return nil, err
+		}
+		promovidos = append(promovidos, nuevos...)
+	}
+	return promovidos, nil
+}
+
+// moverRelaciones pasa las aristas del duplicado a la persona conservada.
+// Las que unían a ambas se borran, igual que las que la persona conservada ya
+// tenía con el mismo pariente y tipo, sin importar la forma de género.
+func moverRelaciones(tx *gorm.DB, conservar, duplicado uint, resultado *ResultadoFusion) error {
+	res := tx.Where("(id_persona = ? AND id_pariente = ?) OR (id_persona = ? AND id_pariente = ?)",
+		conservar, duplicado, duplicado, conservar).Delete(&models.Genealogia{})
+	if res.Error != nil {
+		return res.Error
+	}
+	resultado.RelacionesDescartadas += res.RowsAffected
+
+	for _, propia := range []string{"id_persona", "id_pariente"} {
+		// Se compara con existeArista para que "padre" y "madre" (o "hijo" e
+		// "hija") cuenten como la misma relación.
+		var aristas []models.Genealogia
+		if err := tx.Where(propia+" = ?", duplicado).Find(&aristas).Error; err != nil {
+			return err
+		}
+		var repetidas []uint
+		for _, a := range aristas {
+			movida := a
+			if propia == "id_persona" {
+				movida.IDPersona = conservar
+			} else {
+				movida.IDPariente = conservar
+			}
+			existe, err := existeArista(tx, &movida, a.IDGenealogia)
+			if err != nil {
+				return err
+			}
+			if existe {
+				repetidas = append(repetidas, a.IDGenealogia)
+			}
+		}
+		if len(repetidas) > 0 {
+			res = tx.Delete(&models.Genealogia{}, repetidas)
+			if res.Error != nil {
+				return res.Error
+			}
+			resultado.RelacionesDescartadas += res.RowsAffected
+		}
+
+		res = tx.Model(&models.Genealogia{}).Where(propia+" = ?", duplicado).Update(propia, conservar)
+		if res.Error != nil {
+			return traducirErrorGenealogia(res.Error)
+		}
+		resultado.RelacionesMovidas += res.RowsAffected
+	}
+
+	// Si quedara el ID borrado como solicitante, la persona conservada podría
+	// confirmar su propia solicitud.
+	return tx.Model(&models.Genealogia{}).
+		Where("id_persona_solicitante = ?", duplicado).
+		Update("id_persona_solicitante", conservar).Error
+}
+
+// completarPersona copia del duplicado los campos que la persona conservada
+// tiene vacíos y devuelve sus nombres.
+func completarPersona(p, d *models.Persona) []string {
+	completados := []string{}
+	completar := func(campo string, destino **string, origen *string) {
+		if (*destino == nil || **destino == "") && origen != nil && *origen != "" {
+			*destino = origen
+			completados = append(completados, campo)
+		}
+	}
+
+	completar("apellido_materno", &p.ApellidoMaterno, d.ApellidoMaterno)
+	completar("nombre_japones", &p.NombreJapones, d.NombreJapones)
+	completar("nombre_kanji", &p.NombreKanji, d.NombreKanji)
+	completar("genero", &p.Genero, d.Genero)
+	completar("lugar_nacimiento", &p.LugarNacimiento, d.LugarNacimiento)
+	completar("estado_civil", &p.EstadoCivil, d.EstadoCivil)
+	completar("telefono_principal", &p.TelefonoPrincipal, d.TelefonoPrincipal)
+	completar("telefono_alternativo", &p.TelefonoAlternativo, d.TelefonoAlternativo)
+	completar("email_personal", &p.EmailPersonal, d.EmailPersonal)
+	completar("direccion_completa", &p.DireccionCompleta, d.DireccionCompleta)
+	completar("ciudad", &p.Ciudad, d.Ciudad)
+	completar("codigo_postal", &p.CodigoPostal, d.CodigoPostal)
+	completar("foto_perfil", &p.FotoPerfil, d.FotoPerfil)
+	completar("nivel_japones", &p.NivelJapones, d.NivelJapones)
+	completar("puesto", &p.Puesto, d.Puesto)
+
+	if p.FechaNacimiento == nil && d.FechaNacimiento != nil {
+		p.FechaNacimiento = d.FechaNacimiento
+		completados = append(completados, "fecha_nacimiento")
+	}
+	if p.FechaIngresoAsociacion == nil && d.FechaIngresoAsociacion != nil {
+		p.FechaIngresoAsociacion = d.FechaIngresoAsociacion
+		completados = append(completados, "fecha_ingreso_asociacion")
+	}
+	if p.IDEmpresaEmpleadora == nil && d.IDEmpresaEmpleadora != nil {
+		p.IDEmpresaEmpleadora = d.IDEmpresaEmpleadora
+		completados = append(completados, "id_empresa_empleadora")
+	}
+	if !p.EsMiembroActivo && d.EsMiembroActivo {
+		p.EsMiembroActivo = true
+		completados = append(completados, "es_miembro_activo")
+	}
+	return completados
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/mailer"
+)
+
+func TestFusionarParticipaciones(t *testing.T) {
+	inicio := time.Now().Add(7 * 24 * time.Hour)
+	columnas := []string{"id_participacion", "id_evento", "id_persona", "status_participacion", "acompaniantes"}
+
+	casos := []struct {
+		nombre     string
+		propia     string // status de la persona conservada; "" si no se registró
+		ajena      string // status del duplicado
+		descartada int64  // id_participacion borrada; 0 si ninguna
+		promovido  bool
+	}{
+		{"el duplicado asistió y la conservada estaba en espera", "en_espera", "asistio", 10, false},
+		{"la conservada confirmó y el duplicado estaba en espera", "confirmado", "en_espera", 20, false},
+		{"ambos registrados libera un lugar", "registrado", "registrado", 20, true},
+		{"el duplicado confirmó y la conservada estaba registrada", "registrado", "confirmado", 10, true},
+		{"solo el duplicado se registró", "", "registrado", 0, false},
+	}
+	for _, c := range casos {
+		db, base := dbtest.Nueva(t)
+		base.ResponderVeces(`FROM "personas"`, 1, dbtest.Filas([]string{"id_persona", "id_familia", "nombres"},
+			[]driver.Value{int64(1), int64(1), "Taro"}))
+		base.Responder(`FROM "personas"`, dbtest.Filas([]string{"id_persona", "id_familia", "nombres", "email_personal"},
+			[]driver.Value{int64(2), int64(1), "Taro", "taro@example.com"}))
+		base.Responder(`count\(\*\)`, dbtest.Conteo(0))
+
+		filas := [][]driver.Value{{int64(20), int64(3), int64(2), c.ajena, int64(0)}}
+		if c.propia != "" {
+			filas = append(filas, []driver.Value{int64(10), int64(3), int64(1), c.propia, int64(0)})
+		}
+		base.Responder(`FROM "participacion_eventos" WHERE id_persona IN`, dbtest.Filas(columnas, filas...))
+		base.Responder(`FROM "eventos"`, dbtest.Filas([]string{"id_evento", "status", "titulo", "fecha_inicio", "capacidad_maxima"},
+			[]driver.Value{int64(3), "publicado", "Undokai", inicio, int64(10)}))
+		base.Responder(`COALESCE\(SUM`, dbtest.Filas([]string{"total"}, []driver.Value{int64(9)}))
+		base.Responder(`ORDER BY fecha_registro`, dbtest.Filas(columnas,
+			[]driver.Value{int64(30), int64(3), int64(6), "en_espera", int64(0)}))
+		base.Responder(`UPDATE "personas"`, dbtest.Respuesta{Afectadas: 1})
+		base.Responder(`UPDATE "participacion_eventos" SET "id_persona"`, dbtest.Respuesta{Afectadas: 1})
+
+		correo := mailer.NewMemoryMailer()
+		resultado, err := NewDuplicadosService(db, correo).Fusionar(1, FusionInput{IDDuplicado: 2})
+		if err != nil {
+			t.Errorf("%s: %v", c.nombre, err)
+			continue
+		}
+
+		borradas := base.Buscar(`DELETE FROM "participacion_eventos"`)
+		if c.descartada == 0 {
+			if len(borradas) > 0 || resultado.ParticipacionesDescartadas != 0 {
+				t.Errorf("%s: no se esperaba descartar participaciones: %q", c.nombre, borradas)
+			}
+		} else {
+			patron := fmt.Sprintf(`DELETE FROM "participacion_eventos" WHERE "participacion_eventos"."id_participacion" = %d`, c.descartada)
+			if len(borradas) != 1 || !base.Ejecutada(patron) {
+				t.Errorf("%s: se esperaba borrar la participación %d: %q", c.nombre, c.descartada, borradas)
+			}
+			if resultado.ParticipacionesDescartadas != 1 {
+				t.Errorf("%s: descartadas = %d; se esperaba 1", c.nombre, resultado.ParticipacionesDescartadas)
+			}
+			if !base.Ejecutada(`FOR UPDATE`) {
+				t.Errorf("%s: el evento debe bloquearse", c.nombre)
+			}
+		}
+		if !base.Ejecutada(`UPDATE "participacion_eventos" SET "id_persona"=1`) {
+			t.Errorf("%s: las participaciones del duplicado deben pasar a la persona conservada", c.nombre)
+		}
+
+		promovido := base.Ejecutada(`UPDATE "participacion_eventos" SET "status_participacion"=registrado`)
+		if promovido != c.promovido {
+			t.Errorf("%s: promovido = %v; se esperaba %v", c.nombre, promovido, c.promovido)
+		}
+		if enviado := len(correo.Messages()) > 0; enviado != c.promovido {
+			t.Errorf("%s: correo enviado = %v; se esperaba %v", c.nombre, enviado, c.promovido)
+		}
+	}
+}
+
+func TestFusionarConflictos(t *testing.T) {
+	casos := []struct {
+		nombre string
+		tabla  string
+		err    error
+	}{
+		{"ambas con usuario", "users", ErrFusionUsuarios},
+		{"ambas con empresa", "empresas", ErrFusionEmpresas},
+	}
+	for _, c := range casos {
+		db, base := dbtest.Nueva(t)
+		base.Responder(`FROM "personas"`, dbtest.Filas([]string{"id_persona", "id_familia", "nombres"},
+			[]driver.Value{int64(1), int64(1), "Taro"}))
+		base.Responder(`count\(\*\) FROM "`+c.tabla+`"`, dbtest.Conteo(1))
+
+		_, err := NewDuplicadosService(db, nil).Fusionar(1, FusionInput{IDDuplicado: 2})
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: error = %v; se esperaba %v", c.nombre, err, c.err)
+		}
+		if base.Ejecutada(`DELETE FROM "personas"`) {
+			t.Errorf("%s: no debe borrarse el duplicado", c.nombre)
+		}
+	}
+
+	if _, err := NewDuplicadosService(nil, nil).Fusionar(1, FusionInput{IDDuplicado: 1}); err == nil {
+		t.Error("se esperaba un error al fusionar una persona consigo misma")
+	}
+}
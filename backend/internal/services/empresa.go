@@ -0,0 +1,223 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+var ErrEmpresaNoEncontrada = errors.New("la empresa no existe")
+
+type EmpresaDirectorioFiltros struct {
+	Sector       *string `form:"sector"`
+	Ciudad       *string `form:"ciudad"`
+	AbiertoAhora bool    `form:"abierto_ahora"`
+	Q            string  `form:"q"`
+	Page         int     `form:"page"`
+	PageSize     int     `form:"page_size"`
+}
+
+// FamiliaEnlace identifica la familia del propietario sin exponer datos de
+// la persona.
+type FamiliaEnlace struct {
+	IDFamilia     uint    `json:"id_familia"`
+	ApellidoJP    string  `json:"apellido_jp"`
+	ApellidoKanji *string `json:"apellido_kanji"`
+}
+
+// EmpresaDirectorio es la ficha pública de un negocio. AbiertoAhora es nil
+// cuando el negocio no registró horario o no se pudo interpretar.
+type EmpresaDirectorio struct {
+	IDEmpresa          uint                `json:"id_empresa"`
+	NombreEmpresa      string              `json:"nombre_empresa"`
+	GiroComercial      *string             `json:"giro_comercial"`
+	Sector             *string             `json:"sector"`
+	Descripcion        *string             `json:"descripcion"`
+	ServiciosProductos *string             `json:"servicios_productos"`
+	Telefono           *string             `json:"telefono"`
+	Email              *string             `json:"email"`
+	SitioWeb           *string             `json:"sitio_web"`
+	Direccion          *string             `json:"direccion"`
+	Ciudad             *string             `json:"ciudad"`
+	Estado             string              `json:"estado"`
+	Latitud            *float64            `json:"latitud"`
+	Longitud           *float64            `json:"longitud"`
+	LogoEmpresa        *string             `json:"logo_empresa"`
+	FotosEmpresa       json.RawMessage     `json:"fotos_empresa"`
+	RedesSociales      json.RawMessage     `json:"redes_sociales"`
+	Horario            map[string][]string `json:"horario"`
+	AbiertoAhora       *bool               `json:"abierto_ahora"`
+	Propietario        *string             `json:"propietario"`
+	Familia            *FamiliaEnlace      `json:"familia"`
+}
+
+type SectorDirectorio struct {
+	Sector string `json:"sector"`
+	Total  int64  `json:"total"`
+}
+
+type EmpresaService struct {
+	db *gorm.DB
+}
+
+func NewEmpresaService(db *gorm.DB) *EmpresaService {
+	return &EmpresaService{db: db}
+}
+
+// Directorio lista los negocios que aceptaron la promoción. El filtro de
+// abierto ahora depende del horario en JSON, así que se aplica después de
+// leer y la paginación se hace en memoria.
+func (s *EmpresaService) Directorio(filtros EmpresaDirectorioFiltros, ahora time.Time) (*ListaPaginada[EmpresaDirectorio], error) {
+	query := s.db.Where("acepta_promocion_directorio = ?", true)
+	if filtros.Sector != nil && *filtros.Sector != "" {
+		query = query.Where("LOWER(sector) = LOWER(?)", strings.TrimSpace(*filtros.Sector))
+	}
+	if filtros.Ciudad != nil && *filtros.Ciudad != "" {
+		query = query.Where("LOWER(ciudad) = LOWER(?)", strings.TrimSpace(*filtros.Ciudad))
+	}
+	if q := strings.TrimSpace(filtros.Q); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(nombre_empresa) LIKE ? OR LOWER(COALESCE(giro_comercial, '')) LIKE ?", like, like)
+	}
+
+	var empresas []models.Empresa
+	if err := query.Order("nombre_empresa ASC, id_empresa ASC").Find(&empresas).Error; err != nil {
+		return nil, err
+	}
+
+	fichas, err := s.fichas(empresas, ahora)
+	if err != nil {
+		return nil, err
+	}
+	if filtros.AbiertoAhora {
+		abiertas := fichas[:0]
+		for _, f := range fichas {
+			if f.AbiertoAhora != nil && *f.AbiertoAhora {
+				abiertas = append(abiertas, f)
+			}
+		}
+		fichas = abiertas
+	}
+
+	pag := nuevaPaginacion(filtros.Page, filtros.PageSize)
+	pag.setTotal(int64(len(fichas)))
+	inicio := min((pag.Page-1)*pag.PageSize, len(fichas))
+	fin := min(inicio+pag.PageSize, len(fichas))
+
+	return &ListaPaginada[EmpresaDirectorio]{Items: fichas[inicio:fin], Pagination: pag}, nil
+}
+
+func (s *EmpresaService) GetDirectorio(id uint, ahora time.Time) (*EmpresaDirectorio, error) {
+	var empresa models.Empresa
+	err := s.db.Where("id_empresa = ? AND acepta_promocion_directorio = ?", id, true).First(&empresa).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmpresaNoEncontrada
+		}
+		return nil, err
+	}
+
+	fichas, err := s.fichas([]models.Empresa{empresa}, ahora)
+	if err != nil {
+		return nil, err
+	}
+	return &fichas[0], nil
+}
+
+// Sectores devuelve los sectores con negocios en el directorio, para armar
+// el filtro.
+func (s *EmpresaService) Sectores() ([]SectorDirectorio, error) {
+	sectores := []SectorDirectorio{}
+	err := s.db.Model(&models.Empresa{}).
+		Select("sector, COUNT(*) AS total").
+		Where("acepta_promocion_directorio = ? AND sector IS NOT NULL AND sector <> ''", true).
+		Group("sector").
+		Order("sector ASC").
+		Scan(&sectores).Error
+	if err != nil {
+		return nil, err
+	}
+	return sectores, nil
+}
+
+// fichas arma las fichas con el horario interpretado y la familia del
+// propietario. El nombre del propietario solo aparece si aceptó el
+// directorio.
+func (s *EmpresaService) fichas(empresas []models.Empresa, ahora time.Time) ([]EmpresaDirectorio, error) {
+	idsPropietarios := make([]uint, len(empresas))
+	for i, e := range empresas {
+		idsPropietarios[i] = e.IDPropietario
+	}
+	propietarios, err := cargarPersonasPorID(s.db, idsPropietarios)
+	if err != nil {
+		return nil, err
+	}
+
+	idsFamilias := make([]uint, 0, len(propietarios))
+	for _, p := range propietarios {
+		idsFamilias = append(idsFamilias, p.IDFamilia)
+	}
+	familias := make(map[uint]*models.Familia)
+	if len(idsFamilias) > 0 {
+		var lista []models.Familia
+		if err := s.db.Where("id_familia IN ?", idsFamilias).Find(&lista).Error; err != nil {
+			return nil, err
+		}
+		for i := range lista {
+			familias[lista[i].IDFamilia] = &lista[i]
+		}
+	}
+
+	fichas := make([]EmpresaDirectorio, len(empresas))
+	for i := range empresas {
+		e := &empresas[i]
+		f := EmpresaDirectorio{
+			IDEmpresa:          e.IDEmpresa,
+			NombreEmpresa:      e.NombreEmpresa,
+			GiroComercial:      e.GiroComercial,
+			Sector:             e.Sector,
+			Descripcion:        e.Descripcion,
+			ServiciosProductos: e.ServiciosProductos,
+			Telefono:           e.Telefono,
+			Email:              e.Email,
+			SitioWeb:           e.SitioWeb,
+			Direccion:          e.Direccion,
+			Ciudad:             e.Ciudad,
+			Estado:             e.Estado,
+			Latitud:            e.Latitud,
+			Longitud:           e.Longitud,
+			LogoEmpresa:        e.LogoEmpresa,
+			FotosEmpresa:       jsonOrNull(e.FotosEmpresa),
+			RedesSociales:      jsonOrNull(e.RedesSociales),
+		}
+
+		if e.HorariosAtencion != nil {
+			if horario, err := ParseHorarios(*e.HorariosAtencion); err == nil {
+				abierto := horario.AbiertoEn(ahora)
+				f.Horario = horario.Texto()
+				f.AbiertoAhora = &abierto
+			}
+		}
+
+		if p := propietarios[e.IDPropietario]; p != nil {
+			if p.AceptaDirectorioPublico {
+				nombre := p.GetNombreCompleto()
+				f.Propietario = &nombre
+			}
+			if familia := familias[p.IDFamilia]; familia != nil {
+				f.Familia = &FamiliaEnlace{
+					IDFamilia:     familia.IDFamilia,
+					ApellidoJP:    familia.ApellidoJP,
+					ApellidoKanji: familia.ApellidoKanji,
+				}
+			}
+		}
+		fichas[i] = f
+	}
+	return fichas, nil
+}
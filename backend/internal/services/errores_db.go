@@ -0,0 +1,32 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+	pgCheckViolation      = "23514"
+)
+
+func pgError(err error) *pgconn.PgError {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr
+	}
+	return nil
+}
+
+func esViolacionFK(err error) bool {
+	pgErr := pgError(err)
+	return pgErr != nil && pgErr.Code == pgForeignKeyViolation
+}
+
+func esViolacionUnica(err error, constraint string) bool {
+	pgErr := pgError(err)
+	return pgErr != nil && pgErr.Code == pgUniqueViolation &&
+		(constraint == "" || pgErr.ConstraintName == constraint)
+}
@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/geo"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/mailer"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var (
+	ErrEventoNoEncontrado = errors.New("el evento no existe")
+	ErrEventoNoEditable   = errors.New("el evento ya no puede modificarse en su estado actual")
+	ErrEventoNoBorrable   = errors.New("solo los eventos en borrador pueden eliminarse; cancela el evento en su lugar")
+)
+
+// ErrTransicionInvalida indica un cambio de estado no permitido por el ciclo
+// de vida del evento.
+type ErrTransicionInvalida struct {
+	Desde string
+	Hacia string
+}
+
+func (e *ErrTransicionInvalida) Error() string {
+	return fmt.Sprintf("no se puede pasar un evento de %s a %s", e.Desde, e.Hacia)
+}
+
+// cierreEventoSQL es GetFechaCierre en SQL: sin fecha de fin el evento dura
+// todo el día de inicio.
+const cierreEventoSQL = "COALESCE(fecha_fin, date_trunc('day', fecha_inicio) + interval '1 day')"
+
+type EventoInput struct {
+	Titulo              string          `json:"titulo" binding:"required,max=200"`
+	Descripcion         *string         `json:"descripcion"`
+	TipoEvento          string          `json:"tipo_evento" binding:"required,oneof=matsuri reunion cultural deportivo educativo empresarial ceremonia"`
+	FechaInicio         time.Time       `json:"fecha_inicio" binding:"required"`
+	FechaFin            *time.Time      `json:"fecha_fin"`
+	Ubicacion           *string         `json:"ubicacion" binding:"omitempty,max=300"`
+	Direccion           *string         `json:"direccion"`
+	Ciudad              *string         `json:"ciudad" binding:"omitempty,max=100"`
+	Latitud             *float64        `json:"latitud" binding:"required_with=Longitud,omitempty,gte=-90,lte=90"`
+	Longitud            *float64        `json:"longitud" binding:"required_with=Latitud,omitempty,gte=-180,lte=180"`
+	CapacidadMaxima     *int            `json:"capacidad_maxima" binding:"omitempty,gte=1"`
+	RequiereRegistro    *bool           `json:"requiere_registro"`
+	EsPublico           *bool           `json:"es_publico"`
+	ImagenEvento        *string         `json:"imagen_evento" binding:"omitempty,max=500"`
+	GaleriaFotos        json.RawMessage `json:"galeria_fotos"`
+	LinkTransmision     *string         `json:"link_transmision" binding:"omitempty,max=300"`
+	Requisitos          *string         `json:"requisitos"`
+	ProgramaActividades json.RawMessage `json:"programa_actividades"`
+	ContactoOrganizador *string         `json:"contacto_organizador" binding:"omitempty,max=100"`
+}
+
+type EventoFiltros struct {
+	Status     *string    `form:"status" binding:"omitempty,oneof=borrador publicado en_curso finalizado cancelado"`
+	TipoEvento *string    `form:"tipo_evento" binding:"omitempty,oneof=matsuri reunion cultural deportivo educativo empresarial ceremonia"`
+	Ciudad     *string    `form:"ciudad"`
+	Desde      *time.Time `form:"desde" time_format:"2006-01-02"`
+	Hasta      *time.Time `form:"hasta" time_format:"2006-01-02"`
+	Sort       string     `form:"sort"`
+	Page       int        `form:"page"`
+	PageSize   int        `form:"page_size"`
+}
+
+var eventoSortCampos = map[string]string{
+	"fecha_inicio": "fecha_inicio",
+	"titulo":       "titulo",
+	"tipo_evento":  "tipo_evento",
+	"created_at":   "created_at",
+}
+
+type EventoService struct {
+	db             *gorm.DB
+	geocodificador geo.Geocodificador
+	mailer         mailer.Mailer
+}
+
+func NewEventoService(db *gorm.DB, geocodificador geo.Geocodificador, m mailer.Mailer) *EventoService {
+	return &EventoService{db: db, geocodificador: geocodificador, mailer: m}
+}
+
+// List oculta los borradores a quien no es administrador.
+func (s *EventoService) List(filtros EventoFiltros, esAdmin bool) (*ListaPaginada[models.Evento], error) {
+	query := s.db.Model(&models.Evento{})
+
+	if !esAdmin {
+		query = query.Where("status <> ?", "borrador")
+	}
+	if filtros.Status != nil {
+		query = query.Where("status = ?", *filtros.Status)
+	}
+	if filtros.TipoEvento != nil {
+		query = query.Where("tipo_evento = ?", *filtros.TipoEvento)
+	}
+	if filtros.Ciudad != nil && *filtros.Ciudad != "" {
+		query = query.Where("LOWER(ciudad) = LOWER(?)", strings.TrimSpace(*filtros.Ciudad))
+	}
+	if filtros.Desde != nil {
+		query = query.Where("fecha_inicio >= ?", *filtros.Desde)
+	}
+	if filtros.Hasta != nil {
+		query = query.Where("fecha_inicio < ?", filtros.Hasta.AddDate(0, 0, 1))
+	}
+
+	pag := nuevaPaginacion(filtros.Page, filtros.PageSize)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	pag.setTotal(total)
+
+	eventos := []models.Evento{}
+	orden := ordenSQL(filtros.Sort, eventoSortCampos, "fecha_inicio ASC") + ", id_evento ASC"
+	if err := pag.aplicar(query.Order(orden)).Find(&eventos).Error; err != nil {
+		return nil, err
+	}
+
+	return &ListaPaginada[models.Evento]{Items: eventos, Pagination: pag}, nil
+}
+
+func (s *EventoService) Get(id uint, esAdmin bool) (*models.Evento, error) {
+	var evento models.Evento
+	if err := s.db.First(&evento, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEventoNoEncontrado
+		}
+		return nil, err
+	}
+	if !esAdmin && evento.Status == "borrador" {
+		return nil, ErrEventoNoEncontrado
+	}
+	return &evento, nil
+}
+
+func (s *EventoService) Create(idOrganizador uint, input EventoInput) (*models.Evento, error) {
+	evento := models.Evento{
+		IDOrganizador: idOrganizador,
+		Status:        "borrador",
+	}
+	if err := s.aplicarInput(&evento, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&evento).Error; err != nil {
+		return nil, err
+	}
+	return &evento, nil
+}
+
+// Update bloquea el evento como Registrar, para que un cambio de cupo no se
+// cruce con una inscripción. El cupo no puede bajar de los lugares ya
+// ocupados y, si cambia, la lista de espera avanza hasta llenarlo.
+func (s *EventoService) Update(id uint, input EventoInput) (*models.Evento, error) {
+	var evento *models.Evento
+	var promovidos []models.ParticipacionEvento
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if evento, err = bloquearEvento(tx, id); err != nil {
+			return err
+		}
+		if !evento.EsEditable() {
+			return ErrEventoNoEditable
+		}
+
+		capacidadAnterior := evento.CapacidadMaxima
+		if err := s.aplicarInput(evento, input); err != nil {
+			return err
+		}
+		cambioCapacidad := !mismoCupo(capacidadAnterior, evento.CapacidadMaxima)
+
+		if cambioCapacidad && evento.CapacidadMaxima != nil {
+			ocupados, err := lugaresOcupados(tx, id)
+			if err != nil {
+				return err
+			}
+			if ocupados > *evento.CapacidadMaxima {
+				return utils.FieldErrors{"capacidad_maxima": fmt.Sprintf("no puede ser menor a los %d lugares ya ocupados", ocupados)}
+			}
+		}
+
+		if err := tx.Model(evento).Select("*").Omit("id_evento", "created_at").Updates(evento).Error; err != nil {
+			return err
+		}
+
+		if cambioCapacidad && evento.AceptaRegistros() {
+			promovidos, err = promoverListaEspera(tx, evento)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notificarPromociones(s.db, s.mailer, promovidos)
+	return evento, nil
+}
+
+func mismoCupo(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s *EventoService) Delete(id uint) error {
+	result := s.db.Where("id_evento = ? AND status = ?", id, "borrador").Delete(&models.Evento{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(id, true); err != nil {
+			return err
+		}
+		return ErrEventoNoBorrable
+	}
+	return nil
+}
+
+func (s *EventoService) Publicar(id uint) (*models.Evento, error) {
+	return s.transicionar(id, "publicado", func(e *models.Evento) error {
+		if !e.GetFechaCierre().After(time.Now()) {
+			return utils.FieldErrors{"fecha_inicio": "no se puede publicar un evento que ya terminó"}
+		}
+		return nil
+	})
+}
+
+func (s *EventoService) Cancelar(id uint) (*models.Evento, error) {
+	return s.transicionar(id, "cancelado", nil)
+}
+
+// Finalizar no acepta eventos que aún no empiezan: cerrarlos marcaría como
+// inasistencia a todos los confirmados.
+func (s *EventoService) Finalizar(id uint) (*models.Evento, error) {
+	evento, err := s.transicionar(id, "finalizado", func(e *models.Evento) error {
+		if e.FechaInicio.After(time.Now()) {
+			return utils.FieldErrors{"fecha_inicio": "no se puede finalizar un evento que aún no empieza"}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.MarcarInasistencias(); err != nil {
+		log.Printf("Error marcando inasistencias del evento %d: %v", id, err)
+	}
+	return evento, nil
+}
+
+// transicionar aplica el cambio solo si el estado no cambió desde que se
+// leyó, para que el ticker y una petición manual no se pisen.
+func (s *EventoService) transicionar(id uint, nuevoStatus string, validar func(*models.Evento) error) (*models.Evento, error) {
+	evento, err := s.Get(id, true)
+	if err != nil {
+		return nil, err
+	}
+	if !evento.PuedeTransicionarA(nuevoStatus) {
+		return nil, &ErrTransicionInvalida{Desde: evento.Status, Hacia: nuevoStatus}
+	}
+	if validar != nil {
+		if err := validar(evento); err != nil {
+			return nil, err
+		}
+	}
+
+	anterior := evento.Status
+	result := s.db.Model(&models.Evento{}).
+		Where("id_evento = ? AND status = ?", id, anterior).
+		Update("status", nuevoStatus)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		actual, err := s.Get(id, true)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &ErrTransicionInvalida{Desde: actual.Status, Hacia: nuevoStatus}
+	}
+
+	evento.Status = nuevoStatus
+	return evento, nil
+}
+
+// ActualizarEstados mueve los eventos publicados a en_curso cuando llega su
+// fecha de inicio y a finalizado cuando pasa su fecha de cierre.
+func (s *EventoService) ActualizarEstados(now time.Time) (iniciados, finalizados int64, err error) {
+	result := s.db.Model(&models.Evento{}).
+		Where("status IN ? AND "+cierreEventoSQL+" <= ?", []string{"publicado", "en_curso"}, now).
+		Update("status", "finalizado")
+	if result.Error != nil {
+		return 0, 0, result.Error
+	}
+	finalizados = result.RowsAffected
+
+	result = s.db.Model(&models.Evento{}).
+		Where("status = ? AND fecha_inicio <= ?", "publicado", now).
+		Update("status", "en_curso")
+	if result.Error != nil {
+		return 0, finalizados, result.Error
+	}
+	iniciados = result.RowsAffected
+
+	return iniciados, finalizados, nil
+}
+
+// MarcarInasistencias pasa a no_asistio a quienes confirmaron pero no
+// hicieron check-in en eventos ya finalizados. Un evento finalizado antes de
+// su cierre espera a que pase, porque aún pueden llegar a registrarse.
+func (s *EventoService) MarcarInasistencias() (int64, error) {
+	result := s.db.Model(&models.ParticipacionEvento{}).
+		Where("status_participacion = ? AND id_evento IN (?)", "confirmado",
+			s.db.Model(&models.Evento{}).Select("id_evento").
+				Where("status = ? AND "+cierreEventoSQL+" <= ?", "finalizado", time.Now())).
+		Update("status_participacion", "no_asistio")
+	return result.RowsAffected, result.Error
+}
+
+func (s *EventoService) IniciarActualizadorEstados(ctx context.Context, intervalo time.Duration) {
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+
+		for {
+			iniciados, finalizados, err := s.ActualizarEstados(time.Now())
+			if err != nil {
+				log.Printf("Error actualizando estados de eventos: %v", err)
+			} else if iniciados > 0 || finalizados > 0 {
+				log.Printf("Eventos actualizados: %d en curso, %d finalizados", iniciados, finalizados)
+			}
+
+			if inasistencias, err := s.MarcarInasistencias(); err != nil {
+				log.Printf("Error marcando inasistencias: %v", err)
+			} else if inasistencias > 0 {
+				log.Printf("Participaciones marcadas como no_asistio: %d", inasistencias)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// aplicarInput usa las coordenadas recibidas o, si no vienen, las de la
+// ciudad del evento.
+func (s *EventoService) aplicarInput(e *models.Evento, in EventoInput) error {
+	fields := utils.FieldErrors{}
+
+	if in.FechaFin != nil && !in.FechaFin.After(in.FechaInicio) {
+		fields["fecha_fin"] = "debe ser posterior a fecha_inicio"
+	}
+	galeria, err := jsonbInput(in.GaleriaFotos)
+	if err != nil {
+		fields["galeria_fotos"] = err.Error()
+	}
+	programa, err := jsonbInput(in.ProgramaActividades)
+	if err != nil {
+		fields["programa_actividades"] = err.Error()
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+
+	e.Titulo = strings.TrimSpace(in.Titulo)
+	e.Descripcion = in.Descripcion
+	e.TipoEvento = in.TipoEvento
+	e.FechaInicio = in.FechaInicio
+	e.FechaFin = in.FechaFin
+	e.Ubicacion = in.Ubicacion
+	e.Direccion = in.Direccion
+	e.Ciudad = in.Ciudad
+	e.Latitud, e.Longitud = in.Latitud, in.Longitud
+	if e.Latitud == nil {
+		e.Latitud, e.Longitud = ubicarMunicipio(s.geocodificador, e.Ciudad, "")
+	}
+	e.CapacidadMaxima = in.CapacidadMaxima
+	e.RequiereRegistro = in.RequiereRegistro == nil || *in.RequiereRegistro
+	e.EsPublico = in.EsPublico == nil || *in.EsPublico
+	e.ImagenEvento = in.ImagenEvento
+	e.GaleriaFotos = galeria
+	e.LinkTransmision = in.LinkTransmision
+	e.Requisitos = in.Requisitos
+	e.ProgramaActividades = programa
+	e.ContactoOrganizador = in.ContactoOrganizador
+	return nil
+}
+
+// jsonbInput valida un campo jsonb recibido como JSON crudo.
+func jsonbInput(raw json.RawMessage) (*string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if !json.Valid(raw) {
+		return nil, errors.New("debe ser JSON válido")
+	}
+	value := string(raw)
+	return &value, nil
+}
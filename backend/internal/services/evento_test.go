@@ -0,0 +1,76 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/mailer"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+func TestActualizarCapacidadEvento(t *testing.T) {
+	inicio := time.Now().Add(7 * 24 * time.Hour)
+	cupo := func(n int) *int { return &n }
+
+	casos := []struct {
+		nombre    string
+		nueva     *int
+		ocupados  int64
+		err       bool
+		promovido bool
+	}{
+		{"bajar por debajo de lo ocupado", cupo(5), 8, true, false},
+		{"bajar hasta lo ocupado", cupo(8), 8, false, false},
+		{"subir promueve la lista de espera", cupo(12), 10, false, true},
+		{"quitar el cupo promueve la lista de espera", nil, 10, false, true},
+		// Un evento que ya estaba sobrevendido se puede seguir editando.
+		{"sin cambio de cupo", cupo(10), 12, false, false},
+	}
+	for _, c := range casos {
+		db, base := dbtest.Nueva(t)
+		base.Responder(`FROM "eventos"`, dbtest.Filas([]string{"id_evento", "status", "titulo", "fecha_inicio", "capacidad_maxima"},
+			[]driver.Value{int64(3), "publicado", "Undokai", inicio, int64(10)}))
+		base.Responder(`COALESCE\(SUM`, dbtest.Filas([]string{"total"}, []driver.Value{c.ocupados}))
+		base.Responder(`ORDER BY fecha_registro`, dbtest.Filas(
+			[]string{"id_participacion", "id_evento", "id_persona", "status_participacion", "acompaniantes"},
+			[]driver.Value{int64(21), int64(3), int64(6), "en_espera", int64(1)}))
+		base.Responder(`FROM "personas"`, dbtest.Filas([]string{"id_persona", "nombres", "email_personal"},
+			[]driver.Value{int64(6), "Yuki", "yuki@example.com"}))
+
+		correo := mailer.NewMemoryMailer()
+		_, err := NewEventoService(db, nil, correo).Update(3, EventoInput{
+			Titulo:          "Undokai",
+			TipoEvento:      "deportivo",
+			FechaInicio:     inicio,
+			CapacidadMaxima: c.nueva,
+		})
+
+		var fields utils.FieldErrors
+		if c.err {
+			if !errors.As(err, &fields) || fields["capacidad_maxima"] == "" {
+				t.Errorf("%s: error = %v; se esperaba un error en capacidad_maxima", c.nombre, err)
+			}
+			if base.Ejecutada(`UPDATE "eventos"`) {
+				t.Errorf("%s: no debe guardarse el evento", c.nombre)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.nombre, err)
+			continue
+		}
+		if !base.Ejecutada(`FOR UPDATE`) {
+			t.Errorf("%s: el evento debe bloquearse", c.nombre)
+		}
+		promovido := base.Ejecutada(`UPDATE "participacion_eventos" SET "status_participacion"=registrado`)
+		if promovido != c.promovido {
+			t.Errorf("%s: promovido = %v; se esperaba %v", c.nombre, promovido, c.promovido)
+		}
+		if enviado := len(correo.Messages()) > 0; enviado != c.promovido {
+			t.Errorf("%s: correo enviado = %v; se esperaba %v", c.nombre, enviado, c.promovido)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var ErrFormatoNoSoportado = errors.New("formato de exportación no soportado")
+
+const nombreAsociacion = "Asociación Nikkei de Sinaloa"
+
+// Colores de la marca en los PDF: rojo hinomaru y gris carbón.
+var (
+	colorMarca = [3]int{188, 0, 45}
+	colorTexto = [3]int{51, 51, 51}
+	colorFondo = [3]int{245, 240, 240}
+)
+
+// GraficaSeccion indica qué columnas de la tabla se dibujan como barras en
+// el PDF: una con la etiqueta y otra con un valor numérico.
+type GraficaSeccion struct {
+	Etiqueta int
+	Valor    int
+}
+
+// SeccionReporte es una tabla. En XLSX cada sección es una hoja; en CSV y
+// PDF van una después de otra.
+type SeccionReporte struct {
+	Titulo   string
+	Hoja     string
+	Columnas []string
+	Filas    [][]any
+	Grafica  *GraficaSeccion
+}
+
+// DocumentoReporte es un reporte independiente del formato de salida.
+type DocumentoReporte struct {
+	Nombre     string
+	Titulo     string
+	GeneradoEn time.Time
+	Secciones  []SeccionReporte
+}
+
+// ExportacionParams elige el formato de salida de un reporte; sin format se
+// responde JSON.
+type ExportacionParams struct {
+	Format string `form:"format" binding:"omitempty,oneof=json csv xlsx pdf"`
+}
+
+type ArchivoReporte struct {
+	Nombre        string
+	TipoContenido string
+	Contenido     []byte
+}
+
+// Exportar genera el archivo en csv, xlsx o pdf.
+func (d *DocumentoReporte) Exportar(formato string) (*ArchivoReporte, error) {
+	var (
+		contenido []byte
+		tipo      string
+		err       error
+	)
+	switch formato {
+	case "csv":
+		contenido, err = d.csv()
+		tipo = "text/csv; charset=utf-8"
+	case "xlsx":
+		contenido, err = d.xlsx()
+		tipo = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case "pdf":
+		contenido, err = d.pdf()
+		tipo = "application/pdf"
+	default:
+		return nil, ErrFormatoNoSoportado
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nombre := fmt.Sprintf("%s-%s.%s", d.Nombre, d.GeneradoEn.Format(utils.FechaLayout), formato)
+	return &ArchivoReporte{Nombre: nombre, TipoContenido: tipo, Contenido: contenido}, nil
+}
+
+// csv separa las secciones con una línea en blanco y las encabeza con su
+// título. Lleva BOM para que Excel lo abra como UTF-8.
+func (d *DocumentoReporte) csv() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+
+	for i, seccion := range d.Secciones {
+		if i > 0 {
+			w.Write(nil)
+		}
+		w.Write([]string{seccion.Titulo})
+		w.Write(seccion.Columnas)
+		for _, fila := range seccion.Filas {
+			registro := make([]string, len(fila))
+			for j, v := range fila {
+				registro[j] = textoCelda(v)
+			}
+			w.Write(registro)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *DocumentoReporte) xlsx() ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	titulo, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return nil, err
+	}
+	encabezado, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"BC002D"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usadas := make(map[string]bool)
+	for i, seccion := range d.Secciones {
+		hoja := nombreHoja(seccion, usadas)
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), hoja); err != nil {
+				return nil, err
+			}
+		} else if _, err := f.NewSheet(hoja); err != nil {
+			return nil, err
+		}
+
+		f.SetCellValue(hoja, "A1", seccion.Titulo)
+		f.SetCellStyle(hoja, "A1", "A1", titulo)
+		f.SetCellValue(hoja, "A2", fmt.Sprintf("%s · %s", nombreAsociacion, d.GeneradoEn.Format("02/01/2006 15:04")))
+
+		columnas := make([]any, len(seccion.Columnas))
+		for j, c := range seccion.Columnas {
+			columnas[j] = c
+		}
+		if err := f.SetSheetRow(hoja, "A4", &columnas); err != nil {
+			return nil, err
+		}
+		ultima, _ := excelize.CoordinatesToCellName(len(columnas), 4)
+		f.SetCellStyle(hoja, "A4", ultima, encabezado)
+
+		anchos := make([]int, len(seccion.Columnas))
+		for j, c := range seccion.Columnas {
+			anchos[j] = len([]rune(c))
+		}
+		for j, fila := range seccion.Filas {
+			valores := make([]any, len(fila))
+			for k, v := range fila {
+				valores[k] = valorCelda(v)
+				if k < len(anchos) {
+					anchos[k] = max(anchos[k], len([]rune(textoCelda(v))))
+				}
+			}
+			celda, _ := excelize.CoordinatesToCellName(1, 5+j)
+			if err := f.SetSheetRow(hoja, celda, &valores); err != nil {
+				return nil, err
+			}
+		}
+		for j, ancho := range anchos {
+			col, _ := excelize.ColumnNumberToName(j + 1)
+			f.SetColWidth(hoja, col, col, float64(min(ancho, 60)+2))
+		}
+		f.SetPanes(hoja, &excelize.Panes{Freeze: true, YSplit: 4, TopLeftCell: "A5", ActivePane: "bottomLeft"})
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// nombreHoja respeta el límite de Excel de 31 caracteres sin []:*?/\ y
+// evita nombres repetidos.
+func nombreHoja(seccion SeccionReporte, usadas map[string]bool) string {
+	nombre := seccion.Hoja
+	if nombre == "" {
+		nombre = seccion.Titulo
+	}
+	nombre = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, nombre)
+	if r := []rune(nombre); len(r) > 31 {
+		nombre = string(r[:31])
+	}
+
+	base := nombre
+	for n := 2; usadas[strings.ToLower(nombre)]; n++ {
+		sufijo := fmt.Sprintf(" (%d)", n)
+		r := []rune(base)
+		nombre = string(r[:min(len(r), 31-len(sufijo))]) + sufijo
+	}
+	usadas[strings.ToLower(nombre)] = true
+	return nombre
+}
+
+// pdf arma un documento carta con encabezado de la asociación, número de
+// página y, por sección, la gráfica de barras seguida de la tabla. Las
+// fuentes base de PDF solo cubren Latin-1, así que el kanji no se imprime.
+func (d *DocumentoReporte) pdf() ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "Letter", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(d.Titulo, true)
+	pdf.SetAuthor(nombreAsociacion, true)
+	pdf.SetCreator("Sistema Nikkei Sinaloa", true)
+	pdf.AliasNbPages("")
+	pdf.SetMargins(15, 30, 15)
+	pdf.SetAutoPageBreak(true, 20)
+
+	ancho, _ := pdf.GetPageSize()
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFillColor(colorMarca[0], colorMarca[1], colorMarca[2])
+		pdf.Rect(0, 0, ancho, 18, "F")
+		pdf.SetTextColor(255, 255, 255)
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.SetXY(15, 5)
+		pdf.CellFormat(0, 8, tr(nombreAsociacion), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.SetXY(15, 5)
+		pdf.CellFormat(0, 8, tr(d.Titulo), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(colorTexto[0], colorTexto[1], colorTexto[2])
+		pdf.SetY(26)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-14)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 6, tr("Generado el "+d.GeneradoEn.Format("02/01/2006 15:04")), "", 0, "L", false, 0, "")
+		pdf.SetX(15)
+		pdf.CellFormat(0, 6, tr(fmt.Sprintf("Página %d de {nb}", pdf.PageNo())), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	for i, seccion := range d.Secciones {
+		if i > 0 {
+			pdf.Ln(6)
+		}
+		// Que el título no quede solo al pie de la página.
+		if _, alto := pdf.GetPageSize(); pdf.GetY() > alto-60 {
+			pdf.AddPage()
+		}
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.SetTextColor(colorMarca[0], colorMarca[1], colorMarca[2])
+		pdf.CellFormat(0, 8, tr(seccion.Titulo), "", 1, "L", false, 0, "")
+		pdf.SetTextColor(colorTexto[0], colorTexto[1], colorTexto[2])
+
+		if seccion.Grafica != nil {
+			graficaPDF(pdf, tr, seccion)
+		}
+		tablaPDF(pdf, tr, seccion)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+const maxBarrasGrafica = 12
+
+// graficaPDF dibuja barras horizontales con las primeras filas de la sección.
+func graficaPDF(pdf *fpdf.Fpdf, tr func(string) string, seccion SeccionReporte) {
+	g := seccion.Grafica
+	filas := seccion.Filas[:min(len(seccion.Filas), maxBarrasGrafica)]
+	valores := make([]float64, len(filas))
+	maximo := 0.0
+	for i, fila := range filas {
+		valores[i] = numeroCelda(fila[g.Valor])
+		maximo = max(maximo, valores[i])
+	}
+	if maximo == 0 {
+		return
+	}
+
+	const (
+		altoBarra   = 5.0
+		anchoRotulo = 45.0
+	)
+	izquierda, _, derecha, _ := pdf.GetMargins()
+	ancho, alto := pdf.GetPageSize()
+	anchoBarras := ancho - izquierda - derecha - anchoRotulo - 20
+	if pdf.GetY()+float64(len(filas))*(altoBarra+1.5) > alto-25 {
+		pdf.AddPage()
+	}
+
+	pdf.SetFont("Helvetica", "", 8)
+	for i, fila := range filas {
+		y := pdf.GetY()
+		pdf.SetX(izquierda)
+		pdf.CellFormat(anchoRotulo, altoBarra, ajustarTexto(pdf, tr(textoCelda(fila[g.Etiqueta])), anchoRotulo-2), "", 0, "R", false, 0, "")
+		largo := anchoBarras * valores[i] / maximo
+		pdf.SetFillColor(colorMarca[0], colorMarca[1], colorMarca[2])
+		pdf.Rect(izquierda+anchoRotulo+1, y+0.8, largo, altoBarra-1.6, "F")
+		pdf.SetXY(izquierda+anchoRotulo+2+largo, y)
+		pdf.CellFormat(18, altoBarra, textoCelda(fila[g.Valor]), "", 1, "L", false, 0, "")
+		pdf.SetY(y + altoBarra + 1.5)
+	}
+	pdf.Ln(3)
+}
+
+// tablaPDF reparte el ancho según el contenido y repite los encabezados al
+// cambiar de página.
+func tablaPDF(pdf *fpdf.Fpdf, tr func(string) string, seccion SeccionReporte) {
+	const altoFila = 6.0
+	izquierda, _, derecha, _ := pdf.GetMargins()
+	ancho, alto := pdf.GetPageSize()
+	disponible := ancho - izquierda - derecha
+
+	pdf.SetFont("Helvetica", "", 8)
+	pesos := make([]float64, len(seccion.Columnas))
+	total := 0.0
+	for j, c := range seccion.Columnas {
+		pesos[j] = pdf.GetStringWidth(tr(c)) + 4
+		for _, fila := range seccion.Filas {
+			pesos[j] = max(pesos[j], min(pdf.GetStringWidth(tr(textoCelda(fila[j])))+4, disponible/2))
+		}
+		total += pesos[j]
+	}
+	anchos := make([]float64, len(pesos))
+	for j := range pesos {
+		anchos[j] = pesos[j] * disponible / total
+	}
+
+	encabezados := func() {
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.SetFillColor(colorMarca[0], colorMarca[1], colorMarca[2])
+		pdf.SetTextColor(255, 255, 255)
+		for j, c := range seccion.Columnas {
+			pdf.CellFormat(anchos[j], altoFila+1, ajustarTexto(pdf, tr(c), anchos[j]-2), "", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(colorTexto[0], colorTexto[1], colorTexto[2])
+	}
+
+	encabezados()
+	if len(seccion.Filas) == 0 {
+		pdf.CellFormat(disponible, altoFila, "Sin datos", "B", 1, "C", false, 0, "")
+		return
+	}
+	pdf.SetFillColor(colorFondo[0], colorFondo[1], colorFondo[2])
+	for i, fila := range seccion.Filas {
+		if pdf.GetY()+altoFila > alto-20 {
+			pdf.AddPage()
+			encabezados()
+			pdf.SetFillColor(colorFondo[0], colorFondo[1], colorFondo[2])
+		}
+		for j, v := range fila {
+			alineacion := "L"
+			if _, ok := valorCelda(v).(string); !ok {
+				alineacion = "R"
+			}
+			pdf.CellFormat(anchos[j], altoFila, ajustarTexto(pdf, tr(textoCelda(v)), anchos[j]-2), "", 0, alineacion, i%2 == 1, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+// ajustarTexto acorta el texto con puntos suspensivos para que quepa en la
+// celda.
+func ajustarTexto(pdf *fpdf.Fpdf, texto string, ancho float64) string {
+	if pdf.GetStringWidth(texto) <= ancho {
+		return texto
+	}
+	r := []rune(texto)
+	for len(r) > 0 && pdf.GetStringWidth(string(r)+"...") > ancho {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
+
+// valorCelda quita los punteros para que Excel reciba números y fechas
+// como tales.
+func valorCelda(v any) any {
+	switch x := v.(type) {
+	case *string:
+		if x == nil {
+			return ""
+		}
+		return *x
+	case *int:
+		if x == nil {
+			return ""
+		}
+		return *x
+	case *time.Time:
+		if x == nil {
+			return ""
+		}
+		return *x
+	case nil:
+		return ""
+	}
+	return v
+}
+
+func textoCelda(v any) string {
+	switch x := valorCelda(v).(type) {
+	case string:
+		return x
+	case bool:
+		if x {
+			return "Sí"
+		}
+		return "No"
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		if x.Hour() == 0 && x.Minute() == 0 {
+			return x.Format("02/01/2006")
+		}
+		return x.Format("02/01/2006 15:04")
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func numeroCelda(v any) float64 {
+	switch x := valorCelda(v).(type) {
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case float64:
+		return x
+	}
+	return 0
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var (
+	ErrFamiliaConPersonas = errors.New("la familia tiene personas registradas y no puede eliminarse")
+)
+
+// ordenGeneraciones define el orden en que se agrupan los miembros.
+var ordenGeneraciones = []string{"issei", "nisei", "sansei", "yonsei", "gosei", "roksei"}
+
+type FamiliaInput struct {
+	ApellidoJP           string          `json:"apellido_jp" binding:"required,max=100"`
+	ApellidoRomanji      *string         `json:"apellido_romanji" binding:"omitempty,max=100"`
+	ApellidoKanji        *string         `json:"apellido_kanji" binding:"omitempty,max=100"`
+	ApellidoSignificado  *string         `json:"apellido_significado"`
+	PrefecturaOrigen     *string         `json:"prefectura_origen" binding:"omitempty,max=100"`
+	CiudadOrigen         *string         `json:"ciudad_origen" binding:"omitempty,max=100"`
+	AnioLlegadaMexico    *int            `json:"anio_llegada_mexico" binding:"omitempty,gte=1800,lte=2100"`
+	LugarLlegada         *string         `json:"lugar_llegada" binding:"omitempty,max=100"`
+	HistoriaFamiliar     *string         `json:"historia_familiar"`
+	FotoFamiliar         *string         `json:"foto_familiar" binding:"omitempty,max=500"`
+	DocumentosHistoricos json.RawMessage `json:"documentos_historicos"`
+}
+
+type FamiliaFiltros struct {
+	Q                string  `form:"q"`
+	PrefecturaOrigen *string `form:"prefectura_origen"`
+	LugarLlegada     *string `form:"lugar_llegada"`
+	Sort             string  `form:"sort"`
+	Page             int     `form:"page"`
+	PageSize         int     `form:"page_size"`
+}
+
+var familiaSortCampos = map[string]string{
+	"id_familia":          "id_familia",
+	"apellido_jp":         "apellido_jp",
+	"anio_llegada_mexico": "anio_llegada_mexico",
+	"prefectura_origen":   "prefectura_origen",
+	"created_at":          "created_at",
+}
+
+type MiembroFamilia struct {
+	PersonaVista
+	Empresa             *models.Empresa `json:"empresa"`
+	VinculosConfirmados int64           `json:"vinculos_confirmados"`
+}
+
+type GrupoGeneracion struct {
+	Generacion string           `json:"generacion"`
+	Total      int              `json:"total"`
+	Personas   []MiembroFamilia `json:"personas"`
+}
+
+type FamiliaDetalle struct {
+	models.Familia
+	DocumentosHistoricos json.RawMessage   `json:"documentos_historicos"`
+	TotalPersonas        int               `json:"total_personas"`
+	VinculosConfirmados  int64             `json:"vinculos_confirmados"`
+	Generaciones         []GrupoGeneracion `json:"generaciones"`
+}
+
+type FamiliaService struct {
+	db *gorm.DB
+}
+
+func NewFamiliaService(db *gorm.DB) *FamiliaService {
+	return &FamiliaService{db: db}
+}
+
+func (s *FamiliaService) List(filtros FamiliaFiltros) (*ListaPaginada[models.Familia], error) {
+	query := s.db.Model(&models.Familia{})
+
+	if q := strings.TrimSpace(filtros.Q); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		clave := "%" + japones.Normalizar(q) + "%"
+		query = query.Where("LOWER(apellido_jp) LIKE ? OR LOWER(COALESCE(apellido_romanji, '')) LIKE ? OR COALESCE(apellido_kanji, '') LIKE ? OR clave_busqueda LIKE ?",
+			like, like, "%"+q+"%", clave)
+	}
+	if filtros.PrefecturaOrigen != nil && *filtros.PrefecturaOrigen != "" {
+		query = query.Where("LOWER(prefectura_origen) = LOWER(?)", strings.TrimSpace(*filtros.PrefecturaOrigen))
+	}
+	if filtros.LugarLlegada != nil && *filtros.LugarLlegada != "" {
+		query = query.Where("LOWER(lugar_llegada) = LOWER(?)", strings.TrimSpace(*filtros.LugarLlegada))
+	}
+
+	pag := nuevaPaginacion(filtros.Page, filtros.PageSize)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	pag.setTotal(total)
+
+	familias := []models.Familia{}
+	orden := ordenSQL(filtros.Sort, familiaSortCampos, "apellido_jp ASC") + ", id_familia ASC"
+	if err := pag.aplicar(query.Order(orden)).Find(&familias).Error; err != nil {
+		return nil, err
+	}
+
+	return &ListaPaginada[models.Familia]{Items: familias, Pagination: pag}, nil
+}
+
+func (s *FamiliaService) Get(id uint) (*models.Familia, error) {
+	var familia models.Familia
+	if err := s.db.First(&familia, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFamiliaNoEncontrada
+		}
+		return nil, err
+	}
+	return &familia, nil
+}
+
+// GetDetalle arma la vista completa de una familia: miembros agrupados por
+// generación, la empresa de cada uno y sus vínculos genealógicos confirmados.
+// Cada miembro se proyecta según lo que el visor puede ver de él.
+func (s *FamiliaService) GetDetalle(id uint, visor *models.User) (*FamiliaDetalle, error) {
+	familia, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ordenar por fecha de nacimiento delataría las edades relativas, así que
+	// solo los admins ven a los miembros en ese orden.
+	orden := "nombres ASC, apellido_paterno ASC, id_persona ASC"
+	if visor != nil && visor.EsAdmin() {
+		orden = "fecha_nacimiento ASC NULLS LAST, nombres ASC"
+	}
+	var personas []models.Persona
+	err = s.db.Where("id_familia = ?", id).Order(orden).Find(&personas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(personas))
+	for i, p := range personas {
+		ids[i] = p.IDPersona
+	}
+
+	empresas := make(map[uint]*models.Empresa)
+	vinculos := make(map[uint]int64)
+	if len(ids) > 0 {
+		var listaEmpresas []models.Empresa
+		if err := s.db.Where("id_propietario IN ?", ids).Find(&listaEmpresas).Error; err != nil {
+			return nil, err
+		}
+		for i := range listaEmpresas {
+			empresas[listaEmpresas[i].IDPropietario] = &listaEmpresas[i]
+		}
+
+		var conteos []struct {
+			IDPersona uint
+			Total     int64
+		}
+		err := s.db.Model(&models.Genealogia{}).
+			Select("id_persona, COUNT(*) AS total").
+			Where("id_persona IN ? AND confirmado_ambas_partes = ?", ids, true).
+			Group("id_persona").
+			Scan(&conteos).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range conteos {
+			vinculos[c.IDPersona] = c.Total
+		}
+	}
+
+	grupos := make(map[string][]MiembroFamilia)
+	detalle := &FamiliaDetalle{
+		Familia:              *familia,
+		DocumentosHistoricos: jsonOrNull(familia.DocumentosHistoricos),
+		TotalPersonas:        len(personas),
+		Generaciones:         []GrupoGeneracion{},
+	}
+	niveles, err := nivelesAcceso(s.db, visor, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range personas {
+		p := &personas[i]
+		vista := ProyectarPersona(p, niveles[p.IDPersona])
+		if vista == nil {
+			continue
+		}
+		miembro := MiembroFamilia{
+			PersonaVista:        *vista,
+			VinculosConfirmados: vinculos[p.IDPersona],
+		}
+		if e := empresas[p.IDPersona]; e != nil && (e.AceptaPromocionDirectorio || niveles[p.IDPersona] >= AccesoFamiliar) {
+			miembro.Empresa = e
+		}
+		grupos[p.Generacion] = append(grupos[p.Generacion], miembro)
+		detalle.VinculosConfirmados += miembro.VinculosConfirmados
+	}
+	for _, gen := range ordenGeneraciones {
+		if miembros, ok := grupos[gen]; ok {
+			detalle.Generaciones = append(detalle.Generaciones, GrupoGeneracion{
+				Generacion: gen,
+				Total:      len(miembros),
+				Personas:   miembros,
+			})
+		}
+	}
+
+	return detalle, nil
+}
+
+func (s *FamiliaService) Create(input FamiliaInput) (*models.Familia, error) {
+	var familia models.Familia
+	if err := aplicarFamiliaInput(&familia, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&familia).Error; err != nil {
+		return nil, err
+	}
+	return &familia, nil
+}
+
+func (s *FamiliaService) Update(id uint, input FamiliaInput) (*models.Familia, error) {
+	familia, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := aplicarFamiliaInput(familia, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(familia).Error; err != nil {
+		return nil, err
+	}
+	return familia, nil
+}
+
+// Delete revisa personas.id_familia antes de borrar, igual que
+// PersonaService.Delete con sus referencias.
+func (s *FamiliaService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var familia models.Familia
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&familia, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFamiliaNoEncontrada
+			}
+			return err
+		}
+
+		var personas int64
+		if err := tx.Model(&models.Persona{}).Where("id_familia = ?", id).Count(&personas).Error; err != nil {
+			return err
+		}
+		if personas > 0 {
+			return ErrFamiliaConPersonas
+		}
+
+		if err := tx.Delete(&familia).Error; err != nil {
+			if esViolacionFK(err) {
+				return ErrFamiliaConPersonas
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func aplicarFamiliaInput(f *models.Familia, in FamiliaInput) error {
+	var documentos *string
+	if len(in.DocumentosHistoricos) > 0 && string(in.DocumentosHistoricos) != "null" {
+		var parsed interface{}
+		if err := json.Unmarshal(in.DocumentosHistoricos, &parsed); err != nil {
+			return utils.FieldErrors{"documentos_historicos": "debe ser JSON válido"}
+		}
+		switch parsed.(type) {
+		case []interface{}, map[string]interface{}:
+		default:
+			return utils.FieldErrors{"documentos_historicos": "debe ser un arreglo u objeto JSON"}
+		}
+		raw := string(in.DocumentosHistoricos)
+		documentos = &raw
+	}
+
+	f.ApellidoJP = strings.TrimSpace(in.ApellidoJP)
+	f.ApellidoRomanji = in.ApellidoRomanji
+	f.ApellidoKanji = in.ApellidoKanji
+	f.ApellidoSignificado = in.ApellidoSignificado
+	f.PrefecturaOrigen = in.PrefecturaOrigen
+	f.CiudadOrigen = in.CiudadOrigen
+	f.AnioLlegadaMexico = in.AnioLlegadaMexico
+	f.LugarLlegada = in.LugarLlegada
+	f.HistoriaFamiliar = in.HistoriaFamiliar
+	f.FotoFamiliar = in.FotoFamiliar
+	f.DocumentosHistoricos = documentos
+	return nil
+}
+
+// jsonOrNull devuelve el contenido de una columna jsonb listo para incrustarse
+// en la respuesta; si está vacío o dañado se devuelve null.
+func jsonOrNull(value *string) json.RawMessage {
+	if value == nil || !json.Valid([]byte(*value)) {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(*value)
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+func TestDetalleFamiliaOrdenMiembros(t *testing.T) {
+	casos := []struct {
+		nombre string
+		visor  *models.User
+		orden  string
+	}{
+		{"admin", &models.User{Role: "admin"}, "ORDER BY fecha_nacimiento"},
+		{"miembro", &models.User{Role: "miembro"}, "ORDER BY nombres"},
+		{"sin sesión", nil, "ORDER BY nombres"},
+	}
+	for _, c := range casos {
+		db, base := dbtest.Nueva(t)
+		base.Responder(`FROM "familias"`, dbtest.Filas([]string{"id_familia", "apellido_jp"},
+			[]driver.Value{int64(4), "Tanaka"}))
+
+		if _, err := NewFamiliaService(db).GetDetalle(4, c.visor); err != nil {
+			t.Errorf("%s: %v", c.nombre, err)
+			continue
+		}
+		consultas := base.Buscar(`FROM "personas" WHERE id_familia`)
+		if len(consultas) != 1 || !base.Ejecutada(c.orden) {
+			t.Errorf("%s: se esperaba %q en %q", c.nombre, c.orden, consultas)
+		}
+	}
+}
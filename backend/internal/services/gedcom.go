@@ -0,0 +1,562 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/gedcom"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var ErrGedcomInvalido = errors.New("el archivo GEDCOM no se pudo interpretar")
+
+// errSimulacion revierte la transacción de una vista previa.
+var errSimulacion = errors.New("simulación de importación")
+
+// particulasApellido se quedan pegadas al apellido siguiente al separar
+// paterno y materno ("de la Cruz").
+var particulasApellido = map[string]bool{"de": true, "del": true, "la": true, "las": true, "los": true, "y": true, "san": true}
+
+var generosGedcom = map[string]string{"M": "masculino", "F": "femenino", "X": "otro"}
+
+type ImportarGedcomOpciones struct {
+	Confirmar         bool   `form:"confirmar"`
+	IDFamilia         *uint  `form:"id_familia"`
+	GeneracionDefault string `form:"generacion" binding:"omitempty,oneof=issei nisei sansei yonsei gosei roksei"`
+}
+
+type IndividuoImportado struct {
+	XRef      string `json:"xref"`
+	Nombre    string `json:"nombre"`
+	IDPersona *uint  `json:"id_persona,omitempty"`
+	Motivo    string `json:"motivo,omitempty"`
+}
+
+type ReporteImportacion struct {
+	Version          string               `json:"version"`
+	Confirmado       bool                 `json:"confirmado"`
+	Coincidentes     []IndividuoImportado `json:"coincidentes"`
+	Nuevos           []IndividuoImportado `json:"nuevos"`
+	Omitidos         []IndividuoImportado `json:"omitidos"`
+	FamiliasNuevas   []string             `json:"familias_nuevas"`
+	RelacionesNuevas int                  `json:"relaciones_nuevas"`
+	Advertencias     []string             `json:"advertencias"`
+}
+
+type GedcomService struct {
+	db *gorm.DB
+}
+
+func NewGedcomService(db *gorm.DB) *GedcomService {
+	return &GedcomService{db: db}
+}
+
+// individuoGedcom es un INDI ya traducido, antes de decidir si se crea.
+type individuoGedcom struct {
+	xref           string
+	persona        models.Persona
+	apellidoKanji  *string
+	anioLlegada    *int
+	lugarLlegada   *string
+	generacionFija bool
+	omitido        string
+	existente      *models.Persona
+}
+
+type importadorGedcom struct {
+	tx          *gorm.DB
+	doc         *gedcom.Documento
+	opciones    ImportarGedcomOpciones
+	reporte     *ReporteImportacion
+	individuos  map[string]*individuoGedcom
+	orden       []string
+	familias    map[string]*models.Familia
+	familiaFija *models.Familia
+}
+
+// Importar lee un GEDCOM y lo aplica en una sola transacción. Sin Confirmar
+// la transacción se revierte al final, así que la vista previa reporta
+// exactamente lo que haría la importación real.
+func (s *GedcomService) Importar(data []byte, opciones ImportarGedcomOpciones) (*ReporteImportacion, error) {
+	doc, err := gedcom.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGedcomInvalido, err)
+	}
+	if opciones.GeneracionDefault == "" {
+		opciones.GeneracionDefault = "nisei"
+	}
+
+	reporte := &ReporteImportacion{
+		Version:        doc.Version,
+		Coincidentes:   []IndividuoImportado{},
+		Nuevos:         []IndividuoImportado{},
+		Omitidos:       []IndividuoImportado{},
+		FamiliasNuevas: []string{},
+		Advertencias:   append([]string{}, doc.Advertencias...),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		imp := &importadorGedcom{
+			tx:         tx,
+			doc:        doc,
+			opciones:   opciones,
+			reporte:    reporte,
+			individuos: make(map[string]*individuoGedcom),
+			familias:   make(map[string]*models.Familia),
+		}
+		if err := imp.ejecutar(); err != nil {
+			return err
+		}
+		if !opciones.Confirmar {
+			return errSimulacion
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSimulacion) {
+		return nil, err
+	}
+
+	reporte.Confirmado = opciones.Confirmar
+	if !reporte.Confirmado {
+		// Los IDs de la vista previa pertenecen a una transacción revertida.
+		for i := range reporte.Nuevos {
+			reporte.Nuevos[i].IDPersona = nil
+		}
+	}
+	return reporte, nil
+}
+
+func (imp *importadorGedcom) ejecutar() error {
+	if imp.opciones.IDFamilia != nil {
+		var familia models.Familia
+		if err := imp.tx.First(&familia, *imp.opciones.IDFamilia).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFamiliaNoEncontrada
+			}
+			return err
+		}
+		imp.familiaFija = &familia
+	}
+
+	for _, r := range imp.doc.Tipo("INDI") {
+		if r.XRef == "" {
+			imp.advertir("Línea %d: INDI sin identificador, se ignoró", r.Linea)
+			continue
+		}
+		ind := imp.traducir(r)
+		imp.individuos[ind.xref] = ind
+		imp.orden = append(imp.orden, ind.xref)
+	}
+
+	for _, xref := range imp.orden {
+		if err := imp.buscarCoincidencia(imp.individuos[xref]); err != nil {
+			return err
+		}
+	}
+	imp.asignarGeneraciones()
+
+	for _, xref := range imp.orden {
+		if err := imp.guardar(imp.individuos[xref]); err != nil {
+			return err
+		}
+	}
+
+	return imp.crearRelaciones()
+}
+
+// traducir convierte un INDI en una persona sin tocar la base de datos.
+func (imp *importadorGedcom) traducir(r *gedcom.Registro) *individuoGedcom {
+	ind := &individuoGedcom{xref: r.XRef}
+
+	if resn := strings.ToUpper(r.Texto("RESN")); strings.Contains(resn, "PRIVACY") || strings.Contains(resn, "CONFIDENTIAL") {
+		ind.omitido = "marcado como privado en el archivo"
+		return ind
+	}
+
+	var principal, japones, variante *gedcom.Nombre
+	nombres := r.Nombres()
+	for i := range nombres {
+		n := &nombres[i]
+		switch {
+		case n.EsJapones():
+			if japones == nil || (gedcom.TieneKanji(n.Completo()) && !gedcom.TieneKanji(japones.Completo())) {
+				japones = n
+			}
+		case principal == nil && !n.Variante:
+			principal = n
+		case variante == nil && n.Completo() != "":
+			variante = n
+		}
+	}
+	if principal == nil {
+		principal, variante = variante, nil
+	}
+	if principal == nil {
+		principal = japones
+	}
+	if principal == nil || principal.Nombres == "" || principal.Apellido == "" {
+		ind.omitido = "sin nombre y apellido"
+		return ind
+	}
+
+	p := &ind.persona
+	p.Nombres = recortar(principal.Nombres, 150)
+	paterno, materno := separarApellidos(principal.Apellido)
+	p.ApellidoPaterno = recortar(paterno, 100)
+	if materno != "" {
+		m := recortar(materno, 100)
+		p.ApellidoMaterno = &m
+	}
+	if variante != nil && variante.Completo() != principal.Completo() {
+		v := recortar(variante.Completo(), 150)
+		p.NombreJapones = &v
+	}
+	if japones != nil && japones != principal {
+		k := recortar(japones.Completo(), 150)
+		p.NombreKanji = &k
+		if japones.Apellido != "" {
+			a := recortar(japones.Apellido, 100)
+			ind.apellidoKanji = &a
+		}
+	}
+
+	if genero, ok := generosGedcom[strings.ToUpper(r.Texto("SEX"))]; ok {
+		p.Genero = &genero
+	}
+
+	if birt := r.Primero("BIRT"); birt != nil {
+		if valor := birt.Texto("DATE"); valor != "" {
+			fecha := gedcom.ParseFecha(valor)
+			if fecha.Exacta != nil {
+				p.FechaNacimiento = fecha.Exacta
+			} else {
+				imp.advertir("%s: la fecha de nacimiento %q no es exacta y no se importó", r.XRef, valor)
+			}
+		}
+		if lugar := birt.Texto("PLAC"); lugar != "" {
+			l := recortar(lugar, 200)
+			p.LugarNacimiento = &l
+			if esLugarJapon(lugar) {
+				p.Generacion = "issei"
+				ind.generacionFija = true
+			}
+		}
+	}
+
+	if immi := r.Primero("IMMI"); immi != nil {
+		p.Generacion = "issei"
+		ind.generacionFija = true
+		if valor := immi.Texto("DATE"); valor != "" {
+			ind.anioLlegada = gedcom.ParseFecha(valor).Anio
+		}
+		if lugar := immi.Texto("PLAC"); lugar != "" {
+			l := recortar(lugar, 100)
+			ind.lugarLlegada = &l
+		}
+	}
+
+	return ind
+}
+
+// buscarCoincidencia reconoce a una persona ya registrada por nombre
+// normalizado y fecha de nacimiento; si alguna de las dos fechas falta basta
+// el nombre. Varias coincidencias dejan al individuo fuera para revisarlo a mano.
+func (imp *importadorGedcom) buscarCoincidencia(ind *individuoGedcom) error {
+	if ind.omitido != "" {
+		return nil
+	}
+
+	var candidatos []models.Persona
+	err := imp.tx.Where("LOWER(apellido_paterno) = LOWER(?) OR ' ' || clave_busqueda || ' ' LIKE ?",
+		ind.persona.ApellidoPaterno, "% "+japones.Normalizar(ind.persona.ApellidoPaterno)+" %").Find(&candidatos).Error
+	if err != nil {
+		return err
+	}
+
+	apellido := japones.Normalizar(ind.persona.ApellidoPaterno)
+	nombre := japones.Normalizar(ind.persona.Nombres)
+	var coincidencias []models.Persona
+	for _, c := range candidatos {
+		if japones.Normalizar(c.ApellidoPaterno) != apellido || japones.Normalizar(c.Nombres) != nombre {
+			continue
+		}
+		if c.FechaNacimiento != nil && ind.persona.FechaNacimiento != nil &&
+			!c.FechaNacimiento.Equal(*ind.persona.FechaNacimiento) {
+			continue
+		}
+		coincidencias = append(coincidencias, c)
+	}
+
+	switch len(coincidencias) {
+	case 0:
+	case 1:
+		ind.existente = &coincidencias[0]
+	default:
+		ids := make([]string, len(coincidencias))
+		for i, c := range coincidencias {
+			ids[i] = fmt.Sprint(c.IDPersona)
+		}
+		ind.omitido = "coincide con varias personas registradas (" + strings.Join(ids, ", ") + ")"
+	}
+	return nil
+}
+
+// asignarGeneraciones propaga la generación de padres a hijos. Los issei se
+// reconocen por nacer en Japón o tener IMMI; las personas ya registradas
+// conservan la suya. Lo que no se pueda deducir usa la generación por defecto.
+func (imp *importadorGedcom) asignarGeneraciones() {
+	indice := make(map[string]int, len(ordenGeneraciones))
+	for i, g := range ordenGeneraciones {
+		indice[g] = i
+	}
+
+	for _, ind := range imp.individuos {
+		if ind.existente != nil {
+			ind.persona.Generacion = ind.existente.Generacion
+			ind.generacionFija = true
+		}
+	}
+
+	// Cada vuelta baja al menos una generación; el límite evita ciclos.
+	for vuelta := 0; vuelta < len(imp.individuos); vuelta++ {
+		cambios := false
+		for _, fam := range imp.doc.Tipo("FAM") {
+			padres := imp.miembros(fam, "HUSB", "WIFE")
+			for _, hijo := range imp.miembros(fam, "CHIL") {
+				if hijo.generacionFija || hijo.omitido != "" {
+					continue
+				}
+				nueva := -1
+				for _, p := range padres {
+					if i, ok := indice[p.persona.Generacion]; ok && i+1 > nueva {
+						nueva = i + 1
+					}
+				}
+				if nueva < 0 || nueva >= len(ordenGeneraciones) {
+					continue
+				}
+				if hijo.persona.Generacion != ordenGeneraciones[nueva] {
+					hijo.persona.Generacion = ordenGeneraciones[nueva]
+					cambios = true
+				}
+			}
+		}
+		if !cambios {
+			break
+		}
+	}
+
+	for _, ind := range imp.individuos {
+		if ind.persona.Generacion == "" {
+			ind.persona.Generacion = imp.opciones.GeneracionDefault
+		}
+	}
+}
+
+func (imp *importadorGedcom) guardar(ind *individuoGedcom) error {
+	item := IndividuoImportado{XRef: ind.xref, Nombre: ind.persona.GetNombreCompleto(), Motivo: ind.omitido}
+
+	switch {
+	case ind.omitido != "":
+		imp.reporte.Omitidos = append(imp.reporte.Omitidos, item)
+		return nil
+	case ind.existente != nil:
+		item.IDPersona = &ind.existente.IDPersona
+		imp.reporte.Coincidentes = append(imp.reporte.Coincidentes, item)
+		ind.persona = *ind.existente
+		return nil
+	}
+
+	familia, err := imp.familiaPara(ind)
+	if err != nil {
+		return err
+	}
+	ind.persona.IDFamilia = familia.IDFamilia
+	ind.persona.Estado = "Sinaloa"
+	ind.persona.ParticipaEventos = true
+	ind.persona.AceptaComunicaciones = true
+
+	if err := imp.tx.Create(&ind.persona).Error; err != nil {
+		return err
+	}
+	item.IDPersona = &ind.persona.IDPersona
+	imp.reporte.Nuevos = append(imp.reporte.Nuevos, item)
+	return nil
+}
+
+// familiaPara resuelve la familia por apellido paterno. En el sistema una
+// familia es un linaje por apellido, no un FAM de GEDCOM: los FAM se traducen
+// a relaciones de genealogía y el apellido decide el linaje.
+func (imp *importadorGedcom) familiaPara(ind *individuoGedcom) (*models.Familia, error) {
+	if imp.familiaFija != nil {
+		return imp.familiaFija, nil
+	}
+
+	apellido := ind.persona.ApellidoPaterno
+	clave := japones.Normalizar(apellido)
+	if familia, ok := imp.familias[clave]; ok {
+		if err := imp.completarLlegada(familia, ind); err != nil {
+			return nil, err
+		}
+		return familia, nil
+	}
+
+	var familia models.Familia
+	err := imp.tx.Where("LOWER(apellido_jp) = LOWER(?) OR LOWER(COALESCE(apellido_romanji, '')) = LOWER(?) OR ' ' || clave_busqueda || ' ' LIKE ?",
+		apellido, apellido, "% "+clave+" %").
+		Order("id_familia ASC").
+		First(&familia).Error
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		familia = models.Familia{
+			ApellidoJP:    apellido,
+			ApellidoKanji: ind.apellidoKanji,
+		}
+		if err := imp.tx.Create(&familia).Error; err != nil {
+			return nil, err
+		}
+		imp.reporte.FamiliasNuevas = append(imp.reporte.FamiliasNuevas, apellido)
+	default:
+		return nil, err
+	}
+
+	imp.familias[clave] = &familia
+	if err := imp.completarLlegada(&familia, ind); err != nil {
+		return nil, err
+	}
+	return &familia, nil
+}
+
+// completarLlegada toma el IMMI del primer issei como llegada de la familia
+// si todavía no tiene una registrada.
+func (imp *importadorGedcom) completarLlegada(familia *models.Familia, ind *individuoGedcom) error {
+	cambios := map[string]interface{}{}
+	if familia.AnioLlegadaMexico == nil && ind.anioLlegada != nil {
+		familia.AnioLlegadaMexico = ind.anioLlegada
+		cambios["anio_llegada_mexico"] = *ind.anioLlegada
+	}
+	if familia.LugarLlegada == nil && ind.lugarLlegada != nil {
+		familia.LugarLlegada = ind.lugarLlegada
+		cambios["lugar_llegada"] = *ind.lugarLlegada
+	}
+	if len(cambios) == 0 {
+		return nil
+	}
+	return imp.tx.Model(familia).Updates(cambios).Error
+}
+
+// crearRelaciones traduce cada FAM en parejas y en relaciones de padres e
+// hijos. Las relaciones que ya existen se dejan como están.
+func (imp *importadorGedcom) crearRelaciones() error {
+	notas := "Importada de GEDCOM"
+	for _, fam := range imp.doc.Tipo("FAM") {
+		esposo, esposa := imp.miembro(fam, "HUSB"), imp.miembro(fam, "WIFE")
+		hijos := imp.miembros(fam, "CHIL")
+
+		if esposo != nil && esposa != nil {
+			if err := imp.relacionar(esposo, esposa, "esposo", &notas); err != nil {
+				return err
+			}
+		}
+		for _, hijo := range hijos {
+			if esposo != nil {
+				if err := imp.relacionar(esposo, hijo, "padre", &notas); err != nil {
+					return err
+				}
+			}
+			if esposa != nil {
+				if err := imp.relacionar(esposa, hijo, "madre", &notas); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// relacionar crea "a es tipo de b". tipo es la forma que corresponde al rol
+// en el FAM y solo se usa si el género de a no se conoce.
+func (imp *importadorGedcom) relacionar(a, b *individuoGedcom, tipo string, notas *string) error {
+	if a.persona.IDPersona == 0 || b.persona.IDPersona == 0 || a.persona.IDPersona == b.persona.IDPersona {
+		return nil
+	}
+	if a.persona.Genero != nil && (*a.persona.Genero == "masculino" || *a.persona.Genero == "femenino") {
+		tipo = models.RelacionConGenero(tipo, a.persona.Genero)
+	}
+
+	directa := &models.Genealogia{
+		IDPersona:    a.persona.IDPersona,
+		IDPariente:   b.persona.IDPersona,
+		TipoRelacion: tipo,
+		Notas:        notas,
+	}
+	_, err := crearParRelacion(imp.tx, directa, &b.persona)
+	switch {
+	case errors.Is(err, ErrRelacionDuplicada):
+		return nil
+	case err != nil:
+		return err
+	}
+	imp.reporte.RelacionesNuevas++
+	return nil
+}
+
+func (imp *importadorGedcom) miembro(fam *gedcom.Registro, tag string) *individuoGedcom {
+	if m := imp.miembros(fam, tag); len(m) > 0 {
+		return m[0]
+	}
+	return nil
+}
+
+func (imp *importadorGedcom) miembros(fam *gedcom.Registro, tags ...string) []*individuoGedcom {
+	var lista []*individuoGedcom
+	for _, tag := range tags {
+		for _, r := range fam.Todos(tag) {
+			if ind := imp.individuos[r.Puntero()]; ind != nil && ind.omitido == "" {
+				lista = append(lista, ind)
+			}
+		}
+	}
+	return lista
+}
+
+func (imp *importadorGedcom) advertir(formato string, args ...interface{}) {
+	imp.reporte.Advertencias = append(imp.reporte.Advertencias, fmt.Sprintf(formato, args...))
+}
+
+// separarApellidos divide "Tanaka García" en paterno y materno respetando
+// partículas como "de la".
+func separarApellidos(apellido string) (string, string) {
+	palabras := strings.Fields(apellido)
+	if len(palabras) < 2 {
+		return apellido, ""
+	}
+	corte := 0
+	for corte < len(palabras)-1 && particulasApellido[strings.ToLower(palabras[corte])] {
+		corte++
+	}
+	corte++
+	if corte >= len(palabras) {
+		return strings.Join(palabras, " "), ""
+	}
+	return strings.Join(palabras[:corte], " "), strings.Join(palabras[corte:], " ")
+}
+
+func esLugarJapon(lugar string) bool {
+	l := utils.NormalizarTexto(lugar)
+	return strings.Contains(l, "japan") || strings.Contains(l, "japon") || strings.Contains(lugar, "日本")
+}
+
+func recortar(s string, max int) string {
+	runas := []rune(strings.TrimSpace(s))
+	if len(runas) > max {
+		return string(runas[:max])
+	}
+	return string(runas)
+}
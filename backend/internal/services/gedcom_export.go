@@ -0,0 +1,377 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/gedcom"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+type GedcomExportOpciones struct {
+	Version      string `form:"version" binding:"omitempty,oneof=5.5.1 7.0"`
+	Generaciones *int   `form:"generaciones" binding:"omitempty,gte=0,lte=10"`
+}
+
+// ArchivoGedcom es el resultado de una exportación. Personas lista los IDs
+// incluidos para que el handler decida si quien pide puede verlo.
+type ArchivoGedcom struct {
+	Nombre    string
+	Version   string
+	Contenido []byte
+	Personas  []uint
+}
+
+var sexoGedcom = map[string]string{"masculino": "M", "femenino": "F"}
+
+// ExportarFamilia incluye a los miembros de la familia y a sus parejas, para
+// que cada matrimonio quede completo en el archivo.
+func (s *GedcomService) ExportarFamilia(visor *models.User, idFamilia uint, opciones GedcomExportOpciones) (*ArchivoGedcom, error) {
+	var familia models.Familia
+	if err := s.db.First(&familia, idFamilia).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFamiliaNoEncontrada
+		}
+		return nil, err
+	}
+
+	var miembros []uint
+	if err := s.db.Model(&models.Persona{}).Where("id_familia = ?", idFamilia).Pluck("id_persona", &miembros).Error; err != nil {
+		return nil, err
+	}
+
+	grafo := grafoParaVisor(s.db, visor)
+	if err := grafo.expandir(miembros); err != nil {
+		return nil, err
+	}
+	incluidos := make(map[uint]bool, len(miembros))
+	for _, id := range miembros {
+		incluidos[id] = true
+	}
+	if err := agregarConyuges(grafo, incluidos); err != nil {
+		return nil, err
+	}
+
+	nombre := "familia-" + strconv.FormatUint(uint64(idFamilia), 10) + ".ged"
+	return s.exportar(visor, grafo, incluidos, nombre, opciones.Version)
+}
+
+// ExportarPersona incluye ancestros y descendientes hasta el número de
+// generaciones pedido, más las parejas de todos ellos.
+func (s *GedcomService) ExportarPersona(visor *models.User, idPersona uint, opciones GedcomExportOpciones) (*ArchivoGedcom, error) {
+	if _, err := cargarPersona(s.db, idPersona); err != nil {
+		return nil, err
+	}
+	generaciones := generacionesArbolDefault
+	if opciones.Generaciones != nil {
+		generaciones = *opciones.Generaciones
+	}
+
+	grafo := grafoParaVisor(s.db, visor)
+	incluidos := map[uint]bool{idPersona: true}
+	for _, siguientes := range []func(uint) []uint{grafo.padresDe, grafo.hijosDe} {
+		if err := precargarNiveles(grafo, idPersona, generaciones, siguientes); err != nil {
+			return nil, err
+		}
+		frontera := []uint{idPersona}
+		for nivel := 0; nivel < generaciones && len(frontera) > 0; nivel++ {
+			var proxima []uint
+			for _, id := range frontera {
+				for _, sig := range siguientes(id) {
+					if !incluidos[sig] {
+						incluidos[sig] = true
+						proxima = append(proxima, sig)
+					}
+				}
+			}
+			frontera = proxima
+		}
+	}
+	if err := agregarConyuges(grafo, incluidos); err != nil {
+		return nil, err
+	}
+
+	nombre := "persona-" + strconv.FormatUint(uint64(idPersona), 10) + ".ged"
+	return s.exportar(visor, grafo, incluidos, nombre, opciones.Version)
+}
+
+// agregarConyuges suma las parejas y carga sus relaciones, para que los hijos
+// que comparten con alguien incluido queden en el mismo FAM.
+func agregarConyuges(g *grafoGenealogico, incluidos map[uint]bool) error {
+	var nuevos []uint
+	for _, id := range ordenarIDs(incluidos) {
+		for _, c := range g.conyugesDe(id) {
+			if !incluidos[c] {
+				incluidos[c] = true
+				nuevos = append(nuevos, c)
+			}
+		}
+	}
+	return g.expandir(nuevos)
+}
+
+type famGedcom struct {
+	xref   string
+	padres []uint
+	hijos  []uint
+}
+
+// exportar escribe cada INDI a partir de ProyectarPersona con el nivel de
+// acceso del visor, así el archivo no trae más de lo que la API mostraría.
+func (s *GedcomService) exportar(visor *models.User, g *grafoGenealogico, incluidos map[uint]bool, nombre, version string) (*ArchivoGedcom, error) {
+	if version == "" {
+		version = gedcom.Version551
+	}
+	ids := ordenarIDs(incluidos)
+
+	personas, err := vistasPorID(s.db, visor, ids)
+	if err != nil {
+		return nil, err
+	}
+	idsFamilias := make(map[uint]bool)
+	for _, p := range personas {
+		idsFamilias[p.IDFamilia] = true
+	}
+	var listaFamilias []models.Familia
+	if err := s.db.Where("id_familia IN ?", ordenarIDs(idsFamilias)).Find(&listaFamilias).Error; err != nil {
+		return nil, err
+	}
+	familias := make(map[uint]*models.Familia, len(listaFamilias))
+	for i := range listaFamilias {
+		familias[listaFamilias[i].IDFamilia] = &listaFamilias[i]
+	}
+
+	fams := armarFams(g, incluidos, personas)
+	famcPorPersona := make(map[uint][]string)
+	famsPorPersona := make(map[uint][]string)
+	for _, f := range fams {
+		for _, h := range f.hijos {
+			famcPorPersona[h] = append(famcPorPersona[h], f.xref)
+		}
+		for _, p := range f.padres {
+			famsPorPersona[p] = append(famsPorPersona[p], f.xref)
+		}
+	}
+
+	var registros []*gedcom.Registro
+	var exportados []uint
+	for _, id := range ids {
+		p := personas[id]
+		if p == nil {
+			continue
+		}
+		exportados = append(exportados, id)
+		indi := individuoGedcomDe(p, familias[p.IDFamilia], version)
+		for _, x := range famcPorPersona[id] {
+			indi.Agregar(gedcom.Nuevo("FAMC", x))
+		}
+		for _, x := range famsPorPersona[id] {
+			indi.Agregar(gedcom.Nuevo("FAMS", x))
+		}
+		registros = append(registros, indi)
+	}
+
+	for _, f := range fams {
+		fam := &gedcom.Registro{XRef: f.xref, Tag: "FAM"}
+		esposo, esposa := rolesPareja(f.padres, personas)
+		if esposo != 0 {
+			fam.Agregar(gedcom.Nuevo("HUSB", xrefPersona(esposo)))
+		}
+		if esposa != 0 {
+			fam.Agregar(gedcom.Nuevo("WIFE", xrefPersona(esposa)))
+		}
+		for _, h := range f.hijos {
+			fam.Agregar(gedcom.Nuevo("CHIL", xrefPersona(h)))
+		}
+		registros = append(registros, fam)
+	}
+
+	var buf bytes.Buffer
+	if err := gedcom.Escribir(&buf, version, registros); err != nil {
+		return nil, err
+	}
+
+	return &ArchivoGedcom{
+		Nombre:    nombre,
+		Version:   version,
+		Contenido: buf.Bytes(),
+		Personas:  exportados,
+	}, nil
+}
+
+// armarFams agrupa a los hijos por su par de padres; las parejas sin hijos
+// registrados también forman un FAM.
+func armarFams(g *grafoGenealogico, incluidos map[uint]bool, personas map[uint]*PersonaVista) []*famGedcom {
+	porPadres := make(map[string]*famGedcom)
+	var orden []string
+	obtener := func(padres []uint) *famGedcom {
+		clave := fmt.Sprint(padres)
+		f, ok := porPadres[clave]
+		if !ok {
+			f = &famGedcom{padres: padres}
+			porPadres[clave] = f
+			orden = append(orden, clave)
+		}
+		return f
+	}
+
+	ids := ordenarIDs(incluidos)
+	for _, id := range ids {
+		if personas[id] == nil {
+			continue
+		}
+		for _, c := range g.conyugesDe(id) {
+			if c > id && incluidos[c] && personas[c] != nil {
+				obtener([]uint{id, c})
+			}
+		}
+	}
+	for _, id := range ids {
+		if personas[id] == nil {
+			continue
+		}
+		var padres []uint
+		for _, p := range g.padresDe(id) {
+			if incluidos[p] && personas[p] != nil && len(padres) < 2 {
+				padres = append(padres, p)
+			}
+		}
+		if len(padres) > 0 {
+			f := obtener(padres)
+			f.hijos = append(f.hijos, id)
+		}
+	}
+
+	fams := make([]*famGedcom, len(orden))
+	for i, clave := range orden {
+		fams[i] = porPadres[clave]
+		fams[i].xref = "@F" + strconv.Itoa(i+1) + "@"
+	}
+	return fams
+}
+
+// rolesPareja asigna HUSB y WIFE por género; sin género (o si el visor no
+// puede verlo) la primera persona queda como HUSB.
+func rolesPareja(padres []uint, personas map[uint]*PersonaVista) (uint, uint) {
+	var esposo, esposa uint
+	var sinRol []uint
+	for _, id := range padres {
+		switch genero := personas[id].Genero; {
+		case genero != nil && *genero == "femenino" && esposa == 0:
+			esposa = id
+		case genero != nil && *genero == "masculino" && esposo == 0:
+			esposo = id
+		default:
+			sinRol = append(sinRol, id)
+		}
+	}
+	for _, id := range sinRol {
+		if esposo == 0 {
+			esposo = id
+		} else if esposa == 0 {
+			esposa = id
+		}
+	}
+	return esposo, esposa
+}
+
+// individuoGedcomDe solo escribe lo que trae la vista: debajo de
+// AccesoFamiliar no hay fecha ni lugar de nacimiento, a lo más el año.
+func individuoGedcomDe(p *PersonaVista, familia *models.Familia, version string) *gedcom.Registro {
+	indi := &gedcom.Registro{XRef: xrefPersona(p.IDPersona), Tag: "INDI"}
+
+	apellidos := p.ApellidoPaterno
+	if p.ApellidoMaterno != nil && *p.ApellidoMaterno != "" {
+		apellidos += " " + *p.ApellidoMaterno
+	}
+	name := gedcom.Nuevo("NAME", p.Nombres+" /"+apellidos+"/",
+		gedcom.Nuevo("GIVN", p.Nombres),
+		gedcom.Nuevo("SURN", apellidos),
+	)
+	indi.Agregar(name)
+
+	kanji := ""
+	if p.NombreKanji != nil {
+		kanji = nombreKanjiGedcom(*p.NombreKanji, familia)
+	}
+	romaji := ""
+	if p.NombreJapones != nil {
+		romaji = strings.TrimSpace(*p.NombreJapones)
+	}
+
+	// 7.0 usa TRAN con idioma; 5.5.1 solo admite otro NAME con ROMN.
+	if version == gedcom.Version70 {
+		name.Agregar(
+			gedcom.Opcional("TRAN", kanji, gedcom.Nuevo("LANG", "ja")),
+			gedcom.Opcional("TRAN", romaji, gedcom.Nuevo("LANG", "ja-Latn")),
+		)
+	} else if kanji != "" {
+		indi.Agregar(gedcom.Nuevo("NAME", kanji,
+			gedcom.Nuevo("TYPE", "aka"),
+			gedcom.Opcional("ROMN", romaji, gedcom.Nuevo("TYPE", "romaji")),
+		))
+	} else if romaji != "" {
+		indi.Agregar(gedcom.Nuevo("NAME", romaji, gedcom.Nuevo("TYPE", "aka")))
+	}
+
+	sexo := "U"
+	if s, ok := sexoGedcom[valorOVacio(p.Genero)]; ok {
+		sexo = s
+	} else if version == gedcom.Version70 && valorOVacio(p.Genero) == "otro" {
+		sexo = "X"
+	}
+	indi.Agregar(gedcom.Nuevo("SEX", sexo))
+
+	if p.FechaNacimiento != nil || p.AnioNacimiento != nil || p.LugarNacimiento != nil {
+		birt := gedcom.Nuevo("BIRT", "")
+		if p.FechaNacimiento != nil {
+			birt.Agregar(gedcom.Nuevo("DATE", gedcom.FormatFecha(*p.FechaNacimiento)))
+		} else if p.AnioNacimiento != nil {
+			birt.Agregar(gedcom.Nuevo("DATE", strconv.Itoa(*p.AnioNacimiento)))
+		}
+		birt.Agregar(gedcom.Opcional("PLAC", valorOVacio(p.LugarNacimiento)))
+		indi.Agregar(birt)
+	}
+
+	// La llegada a México está en la familia y corresponde a sus issei.
+	if p.Generacion == "issei" && familia != nil && (familia.AnioLlegadaMexico != nil || familia.LugarLlegada != nil) {
+		immi := gedcom.Nuevo("IMMI", "")
+		if familia.AnioLlegadaMexico != nil {
+			immi.Agregar(gedcom.Nuevo("DATE", strconv.Itoa(*familia.AnioLlegadaMexico)))
+		}
+		immi.Agregar(gedcom.Opcional("PLAC", valorOVacio(familia.LugarLlegada)))
+		indi.Agregar(immi)
+	}
+
+	return indi
+}
+
+// nombreKanjiGedcom marca el apellido entre diagonales cuando el nombre en
+// kanji empieza con el apellido en kanji de la familia.
+func nombreKanjiGedcom(nombre string, familia *models.Familia) string {
+	nombre = strings.TrimSpace(nombre)
+	if familia == nil || familia.ApellidoKanji == nil || *familia.ApellidoKanji == "" {
+		return nombre
+	}
+	apellido := strings.TrimSpace(*familia.ApellidoKanji)
+	if resto, ok := strings.CutPrefix(nombre, apellido); ok {
+		return "/" + apellido + "/" + strings.TrimSpace(resto)
+	}
+	return nombre
+}
+
+func xrefPersona(id uint) string {
+	return "@I" + strconv.FormatUint(uint64(id), 10) + "@"
+}
+
+func valorOVacio(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
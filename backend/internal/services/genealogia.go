@@ -0,0 +1,320 @@
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var (
+	ErrRelacionNoEncontrada = errors.New("la relación no existe")
+	ErrRelacionDuplicada    = errors.New("esa relación ya está registrada entre estas personas")
+	ErrConfirmacionPropia   = errors.New("la relación debe confirmarla la otra persona")
+)
+
+type GenealogiaInput struct {
+	IDPersona    uint    `json:"id_persona" binding:"required"`
+	IDPariente   uint    `json:"id_pariente" binding:"required"`
+	TipoRelacion string  `json:"tipo_relacion" binding:"required,oneof=padre madre hijo hija esposo esposa hermano hermana abuelo abuela nieto nieta tio tia sobrino sobrina primo prima cuniado cuniada yerno nuera suegro suegra"`
+	Notas        *string `json:"notas"`
+}
+
+type GenealogiaUpdateInput struct {
+	TipoRelacion string  `json:"tipo_relacion" binding:"required,oneof=padre madre hijo hija esposo esposa hermano hermana abuelo abuela nieto nieta tio tia sobrino sobrina primo prima cuniado cuniada yerno nuera suegro suegra"`
+	Notas        *string `json:"notas"`
+}
+
+// RelacionPar es una relación junto con su arista inversa.
+type RelacionPar struct {
+	Relacion models.Genealogia  `json:"relacion"`
+	Inversa  *models.Genealogia `json:"inversa"`
+}
+
+// GenealogiaService mantiene cada relación como un par de aristas dirigidas:
+// {id_persona: A, id_pariente: B, tipo_relacion: "padre"} se lee "A es padre
+// de B" y siempre se acompaña de B→A como hijo/hija según el género de B.
+type GenealogiaService struct {
+	db *gorm.DB
+}
+
+func NewGenealogiaService(db *gorm.DB) *GenealogiaService {
+	return &GenealogiaService{db: db}
+}
+
+func (s *GenealogiaService) Get(id uint) (*models.Genealogia, error) {
+	var relacion models.Genealogia
+	if err := s.db.First(&relacion, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRelacionNoEncontrada
+		}
+		return nil, err
+	}
+	return &relacion, nil
+}
+
+// ListarPorPersona muestra las relaciones sin confirmar solo al admin y a
+// las dos partes de cada una; los demás ven únicamente las confirmadas.
+func (s *GenealogiaService) ListarPorPersona(visor *models.User, idPersona uint) ([]models.Genealogia, error) {
+	if err := s.db.Select("id_persona").First(&models.Persona{}, idPersona).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonaNoEncontrada
+		}
+		return nil, err
+	}
+
+	query := s.db.Where("id_persona = ?", idPersona)
+	if visor == nil || !visor.EsAdmin() {
+		var propia uint
+		if visor != nil && visor.IDPersona != nil {
+			propia = *visor.IDPersona
+		}
+		if propia != idPersona {
+			query = query.Where("confirmado_ambas_partes = ? OR id_pariente = ?", true, propia)
+		}
+	}
+
+	relaciones := []models.Genealogia{}
+	err := query.Order("tipo_relacion ASC, id_genealogia ASC").Find(&relaciones).Error
+	return relaciones, err
+}
+
+// Crear registra la relación y su inversa en una sola transacción. Queda sin
+// confirmar hasta que la otra parte la apruebe.
+func (s *GenealogiaService) Crear(input GenealogiaInput, idSolicitante *uint) (*RelacionPar, error) {
+	if input.IDPersona == input.IDPariente {
+		return nil, utils.FieldErrors{"id_pariente": "no puede ser la misma persona"}
+	}
+
+	var par RelacionPar
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := cargarPersona(tx, input.IDPersona); err != nil {
+			return err
+		}
+		pariente, err := cargarPersona(tx, input.IDPariente)
+		if err != nil {
+			return err
+		}
+
+		directa := models.Genealogia{
+			IDPersona:            input.IDPersona,
+			IDPariente:           input.IDPariente,
+			TipoRelacion:         input.TipoRelacion,
+			IDPersonaSolicitante: idSolicitante,
+			Notas:                input.Notas,
+		}
+		inversa, err := crearParRelacion(tx, &directa, pariente)
+		if err != nil {
+			return err
+		}
+
+		par = RelacionPar{Relacion: directa, Inversa: inversa}
+		return nil
+	})
+	if err != nil {
+		return nil, traducirErrorGenealogia(err)
+	}
+
+	return &par, nil
+}
+
+// Actualizar cambia el tipo de ambas aristas. Como el vínculo cambió, la
+// confirmación previa deja de valer.
+func (s *GenealogiaService) Actualizar(id uint, input GenealogiaUpdateInput, idSolicitante *uint) (*RelacionPar, error) {
+	var par RelacionPar
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		directa, inversa, err := bloquearPar(tx, id)
+		if err != nil {
+			return err
+		}
+		pariente, err := cargarPersona(tx, directa.IDPariente)
+		if err != nil {
+			return err
+		}
+
+		directa.TipoRelacion = input.TipoRelacion
+		directa.Notas = input.Notas
+		tipoInversa := directa.GetRelacionInversaPorGenero(pariente.Genero)
+		if inversa == nil {
+			inversa = &models.Genealogia{IDPersona: directa.IDPariente, IDPariente: directa.IDPersona}
+		}
+		inversa.TipoRelacion = tipoInversa
+		inversa.Notas = input.Notas
+
+		for _, arista := range []*models.Genealogia{directa, inversa} {
+			existe, err := existeArista(tx, arista, arista.IDGenealogia)
+			if err != nil {
+				return err
+			}
+			if existe {
+				return ErrRelacionDuplicada
+			}
+			arista.ConfirmadoAmbasPartes = false
+			arista.FechaConfirmacion = nil
+			arista.IDPersonaSolicitante = idSolicitante
+			if err := tx.Save(arista).Error; err != nil {
+				return err
+			}
+		}
+
+		par = RelacionPar{Relacion: *directa, Inversa: inversa}
+		return nil
+	})
+	if err != nil {
+		return nil, traducirErrorGenealogia(err)
+	}
+
+	return &par, nil
+}
+
+func (s *GenealogiaService) Eliminar(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		directa, inversa, err := bloquearPar(tx, id)
+		if err != nil {
+			return err
+		}
+		ids := []uint{directa.IDGenealogia}
+		if inversa != nil {
+			ids = append(ids, inversa.IDGenealogia)
+		}
+		return tx.Delete(&models.Genealogia{}, ids).Error
+	})
+}
+
+// Confirmar marca el par como confirmado. Quien solicitó la relación no puede
+// confirmarla; idPersona nil indica que confirma un administrador.
+func (s *GenealogiaService) Confirmar(id uint, idPersona *uint) (*RelacionPar, error) {
+	var par RelacionPar
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		directa, inversa, err := bloquearPar(tx, id)
+		if err != nil {
+			return err
+		}
+		if idPersona != nil && directa.IDPersonaSolicitante != nil && *directa.IDPersonaSolicitante == *idPersona {
+			return ErrConfirmacionPropia
+		}
+
+		if inversa == nil {
+			pariente, err := cargarPersona(tx, directa.IDPariente)
+			if err != nil {
+				return err
+			}
+			inversa = &models.Genealogia{
+				IDPersona:            directa.IDPariente,
+				IDPariente:           directa.IDPersona,
+				TipoRelacion:         directa.GetRelacionInversaPorGenero(pariente.Genero),
+				IDPersonaSolicitante: directa.IDPersonaSolicitante,
+				Notas:                directa.Notas,
+			}
+		}
+
+		for _, arista := range []*models.Genealogia{directa, inversa} {
+			if !arista.EstaConfirmado() {
+				arista.Confirmar()
+			}
+			if err := tx.Save(arista).Error; err != nil {
+				return err
+			}
+		}
+
+		par = RelacionPar{Relacion: *directa, Inversa: inversa}
+		return nil
+	})
+	if err != nil {
+		return nil, traducirErrorGenealogia(err)
+	}
+
+	return &par, nil
+}
+
+// crearParRelacion guarda la arista y su inversa, con el tipo de la inversa
+// ajustado al género del pariente. Devuelve ErrRelacionDuplicada si alguna de
+// las dos ya existe en cualquiera de sus formas.
+func crearParRelacion(tx *gorm.DB, directa *models.Genealogia, pariente *models.Persona) (*models.Genealogia, error) {
+	inversa := &models.Genealogia{
+		IDPersona:            directa.IDPariente,
+		IDPariente:           directa.IDPersona,
+		TipoRelacion:         directa.GetRelacionInversaPorGenero(pariente.Genero),
+		IDPersonaSolicitante: directa.IDPersonaSolicitante,
+		Notas:                directa.Notas,
+	}
+
+	for _, arista := range []*models.Genealogia{directa, inversa} {
+		existe, err := existeArista(tx, arista, 0)
+		if err != nil {
+			return nil, err
+		}
+		if existe {
+			return nil, ErrRelacionDuplicada
+		}
+	}
+
+	if err := tx.Create(directa).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(inversa).Error; err != nil {
+		return nil, err
+	}
+	return inversa, nil
+}
+
+// bloquearPar obtiene la arista pedida y su inversa con FOR UPDATE. Datos
+// anteriores a este servicio pueden no tener inversa, en cuyo caso es nil.
+func bloquearPar(tx *gorm.DB, id uint) (*models.Genealogia, *models.Genealogia, error) {
+	var directa models.Genealogia
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&directa, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrRelacionNoEncontrada
+		}
+		return nil, nil, err
+	}
+
+	var inversa models.Genealogia
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id_persona = ? AND id_pariente = ? AND tipo_relacion IN ?",
+			directa.IDPariente, directa.IDPersona, models.VariantesRelacion(directa.GetRelacionInversa())).
+		Order("id_genealogia ASC").
+		First(&inversa).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &directa, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &directa, &inversa, nil
+}
+
+// existeArista busca la misma relación en cualquiera de sus formas de género
+// para no guardar "padre" y "madre" de la misma persona hacia el mismo hijo.
+func existeArista(tx *gorm.DB, arista *models.Genealogia, excluir uint) (bool, error) {
+	var total int64
+	err := tx.Model(&models.Genealogia{}).
+		Where("id_persona = ? AND id_pariente = ? AND tipo_relacion IN ? AND id_genealogia <> ?",
+			arista.IDPersona, arista.IDPariente, models.VariantesRelacion(arista.TipoRelacion), excluir).
+		Count(&total).Error
+	return total > 0, err
+}
+
+func cargarPersona(tx *gorm.DB, id uint) (*models.Persona, error) {
+	var persona models.Persona
+	if err := tx.First(&persona, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonaNoEncontrada
+		}
+		return nil, err
+	}
+	return &persona, nil
+}
+
+func traducirErrorGenealogia(err error) error {
+	switch {
+	case esViolacionUnica(err, "unique_relacion_genealogia"):
+		return ErrRelacionDuplicada
+	case esViolacionFK(err):
+		return ErrPersonaNoEncontrada
+	}
+	return err
+}
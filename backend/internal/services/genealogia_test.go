@@ -0,0 +1,40 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+func TestListarPorPersonaFiltraPendientes(t *testing.T) {
+	id := func(n uint) *uint { return &n }
+	casos := []struct {
+		nombre string
+		visor  *models.User
+		filtro string
+	}{
+		{"admin", &models.User{Role: "admin"}, ""},
+		{"la propia persona", &models.User{Role: "miembro", IDPersona: id(1)}, ""},
+		{"otro miembro", &models.User{Role: "miembro", IDPersona: id(8)}, "(confirmado_ambas_partes = true OR id_pariente = 8)"},
+		{"miembro sin persona", &models.User{Role: "miembro"}, "(confirmado_ambas_partes = true OR id_pariente = 0)"},
+	}
+	for _, c := range casos {
+		s, base := baseConPersonas(t)
+		if _, err := s.ListarPorPersona(c.visor, 1); err != nil {
+			t.Errorf("%s: %v", c.nombre, err)
+			continue
+		}
+		consultas := base.Buscar(`FROM "genealogia"`)
+		if len(consultas) != 1 {
+			t.Fatalf("%s: consultas = %q", c.nombre, consultas)
+		}
+		filtrada := base.Ejecutada(`confirmado_ambas_partes`)
+		if filtrada != (c.filtro != "") {
+			t.Errorf("%s: filtrada = %v en %q", c.nombre, filtrada, consultas[0])
+		}
+		if c.filtro != "" && !base.Ejecutada(regexp.QuoteMeta(c.filtro)) {
+			t.Errorf("%s: se esperaba %q en %q", c.nombre, c.filtro, consultas[0])
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/geo"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+// MapaFiltros son los parámetros que manda Leaflet al mover el mapa. Sin
+// zoom no se agrupa; sin bbox se devuelve todo.
+type MapaFiltros struct {
+	Zoom *int   `form:"zoom" binding:"omitempty,min=0,max=20"`
+	BBox string `form:"bbox"`
+}
+
+// ResultadoGeocodificacion cuenta los registros que recibieron coordenadas y
+// los lugares que la tabla no reconoció, para corregirlos a mano.
+type ResultadoGeocodificacion struct {
+	Personas  int64    `json:"personas"`
+	Empresas  int64    `json:"empresas"`
+	Eventos   int64    `json:"eventos"`
+	SinUbicar []string `json:"sin_ubicar"`
+}
+
+type GeoService struct {
+	db             *gorm.DB
+	geocodificador geo.Geocodificador
+}
+
+func NewGeoService(db *gorm.DB, geocodificador geo.Geocodificador) *GeoService {
+	return &GeoService{db: db, geocodificador: geocodificador}
+}
+
+// ubicarMunicipio devuelve las coordenadas de la ciudad o nil si no hay
+// ciudad o no se reconoce. Sin ciudad no se usa la capital del estado para
+// no amontonar a todos en Culiacán.
+func ubicarMunicipio(g geo.Geocodificador, ciudad *string, estado string) (*float64, *float64) {
+	if g == nil || ciudad == nil || strings.TrimSpace(*ciudad) == "" {
+		return nil, nil
+	}
+	c, err := g.Municipio(*ciudad, estado)
+	if err != nil {
+		return nil, nil
+	}
+	return &c.Latitud, &c.Longitud
+}
+
+// CompletarCoordenadas ubica los registros que aún no tienen coordenadas,
+// como los creados antes de que existieran o por el registro público. Se
+// geocodifica cada par ciudad/estado una sola vez.
+func (s *GeoService) CompletarCoordenadas() (*ResultadoGeocodificacion, error) {
+	resultado := &ResultadoGeocodificacion{SinUbicar: []string{}}
+	sinUbicar := map[string]bool{}
+
+	var err error
+	if resultado.Personas, err = s.completar(&models.Persona{}, true, sinUbicar); err != nil {
+		return nil, err
+	}
+	if resultado.Empresas, err = s.completar(&models.Empresa{}, true, sinUbicar); err != nil {
+		return nil, err
+	}
+	if resultado.Eventos, err = s.completar(&models.Evento{}, false, sinUbicar); err != nil {
+		return nil, err
+	}
+	for nombre := range sinUbicar {
+		resultado.SinUbicar = append(resultado.SinUbicar, nombre)
+	}
+	sort.Strings(resultado.SinUbicar)
+	return resultado, nil
+}
+
+func (s *GeoService) completar(modelo any, conEstado bool, sinUbicar map[string]bool) (int64, error) {
+	type lugar struct {
+		Ciudad string
+		Estado string
+	}
+	columnas := []string{"ciudad"}
+	if conEstado {
+		columnas = append(columnas, "estado")
+	}
+
+	var lugares []lugar
+	err := s.db.Model(modelo).Distinct(columnas).
+		Where("latitud IS NULL AND ciudad IS NOT NULL AND ciudad <> ''").
+		Scan(&lugares).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, l := range lugares {
+		c, err := s.geocodificador.Municipio(l.Ciudad, l.Estado)
+		if err != nil {
+			sinUbicar[strings.TrimSuffix(l.Ciudad+", "+l.Estado, ", ")] = true
+			continue
+		}
+
+		query := s.db.Model(modelo).Where("latitud IS NULL AND ciudad = ?", l.Ciudad)
+		if conEstado {
+			query = query.Where("estado = ?", l.Estado)
+		}
+		result := query.UpdateColumns(map[string]any{"latitud": c.Latitud, "longitud": c.Longitud})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		total += result.RowsAffected
+	}
+	return total, nil
+}
+
+// Miembros cuenta personas por ciudad; nunca manda puntos individuales. Solo
+// cuenta a quienes aceptaron el directorio, porque una ciudad con un único
+// miembro lo ubicaría igual que un punto. Las ciudades escritas de forma
+// distinta que caen en el mismo lugar se juntan.
+func (s *GeoService) Miembros(filtros MapaFiltros) (*geo.FeatureCollection, error) {
+	caja, err := cajaMapa(filtros)
+	if err != nil {
+		return nil, err
+	}
+
+	var filas []struct {
+		Ciudad   string
+		Estado   string
+		Latitud  float64
+		Longitud float64
+		Total    int64
+		Activos  int64
+	}
+	err = s.db.Model(&models.Persona{}).
+		Select("MIN(ciudad) AS ciudad, MIN(estado) AS estado, latitud, longitud, "+
+			"COUNT(*) AS total, COUNT(*) FILTER (WHERE es_miembro_activo) AS activos").
+		Where("latitud IS NOT NULL AND longitud IS NOT NULL AND acepta_directorio_publico = ?", true).
+		Group("latitud, longitud").
+		Scan(&filas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	puntos := make([]geo.Punto, len(filas))
+	for i, f := range filas {
+		puntos[i] = geo.Punto{
+			Coordenadas: geo.Coordenadas{Latitud: f.Latitud, Longitud: f.Longitud},
+			Peso:        f.Total,
+			Propiedades: map[string]any{"ciudad": f.Ciudad, "estado": f.Estado, "activos": f.Activos},
+		}
+	}
+	capa := geo.Capa(puntos, filtros.Zoom, caja)
+	return &capa, nil
+}
+
+// Prefecturas cuenta familias por prefectura de origen, ubicadas en su
+// capital. Como la prefectura es texto libre se reconoce en Go.
+func (s *GeoService) Prefecturas(filtros MapaFiltros) (*geo.FeatureCollection, error) {
+	caja, err := cajaMapa(filtros)
+	if err != nil {
+		return nil, err
+	}
+
+	var origenes []string
+	err = s.db.Model(&models.Familia{}).
+		Where("prefectura_origen IS NOT NULL AND prefectura_origen <> ''").
+		Pluck("prefectura_origen", &origenes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	familias := map[*japones.Prefectura]int64{}
+	var orden []*japones.Prefectura
+	for _, origen := range origenes {
+		p, ok := japones.BuscarPrefectura(origen)
+		if !ok {
+			continue
+		}
+		if familias[p] == 0 {
+			orden = append(orden, p)
+		}
+		familias[p]++
+	}
+
+	puntos := make([]geo.Punto, 0, len(orden))
+	for _, p := range orden {
+		c, err := s.geocodificador.Prefectura(p.Nombre)
+		if err != nil {
+			log.Printf("Sin coordenadas para la prefectura %s: %v", p.Nombre, err)
+			continue
+		}
+		puntos = append(puntos, geo.Punto{
+			Coordenadas: c,
+			Peso:        familias[p],
+			Propiedades: map[string]any{"prefectura": p.Nombre, "kanji": p.Kanji, "region": p.Region},
+		})
+	}
+	capa := geo.Capa(puntos, filtros.Zoom, caja)
+	return &capa, nil
+}
+
+// Empresas es la capa pública del directorio: solo los negocios que
+// aceptaron la promoción.
+func (s *GeoService) Empresas(filtros MapaFiltros) (*geo.FeatureCollection, error) {
+	caja, err := cajaMapa(filtros)
+	if err != nil {
+		return nil, err
+	}
+
+	var empresas []models.Empresa
+	err = s.db.Select("id_empresa", "nombre_empresa", "sector", "ciudad", "latitud", "longitud").
+		Where("acepta_promocion_directorio = ? AND latitud IS NOT NULL AND longitud IS NOT NULL", true).
+		Order("nombre_empresa ASC, id_empresa ASC").
+		Find(&empresas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	puntos := make([]geo.Punto, len(empresas))
+	for i, e := range empresas {
+		puntos[i] = geo.Punto{
+			Coordenadas: geo.Coordenadas{Latitud: *e.Latitud, Longitud: *e.Longitud},
+			Peso:        1,
+			Propiedades: map[string]any{
+				"id_empresa":     e.IDEmpresa,
+				"nombre_empresa": e.NombreEmpresa,
+				"sector":         e.Sector,
+				"ciudad":         e.Ciudad,
+				"enlace":         fmt.Sprintf("/api/v1/directorio/empresas/%d", e.IDEmpresa),
+			},
+		}
+	}
+	capa := geo.Capa(puntos, filtros.Zoom, caja)
+	return &capa, nil
+}
+
+func cajaMapa(filtros MapaFiltros) (*geo.Caja, error) {
+	if strings.TrimSpace(filtros.BBox) == "" {
+		return nil, nil
+	}
+	caja, err := geo.ParseCaja(filtros.BBox)
+	if err != nil {
+		return nil, utils.FieldErrors{"bbox": err.Error()}
+	}
+	return caja, nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+)
+
+func TestMapaMiembrosSoloConsentidos(t *testing.T) {
+	db, base := dbtest.Nueva(t)
+	base.Responder(`FROM "personas"`, dbtest.Filas([]string{"ciudad", "estado", "latitud", "longitud", "total", "activos"},
+		[]driver.Value{"Culiacán", "Sinaloa", 24.8, -107.4, int64(3), int64(2)}))
+
+	capa, err := NewGeoService(db, nil).Miembros(MapaFiltros{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !base.Ejecutada(`FROM "personas" WHERE .*acepta_directorio_publico = true`) {
+		t.Errorf("se esperaba filtrar por consentimiento: %q", base.Buscar(`FROM "personas"`))
+	}
+	if len(capa.Features) != 1 {
+		t.Errorf("features = %d; se esperaba 1", len(capa.Features))
+	}
+}
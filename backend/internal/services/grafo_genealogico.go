@@ -0,0 +1,185 @@
+package services
+
+import (
+	"slices"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+// tiposEstructurales son las relaciones de las que se deriva todo lo demás;
+// abuelos, tíos o primos se calculan a partir de ellas.
+var tiposEstructurales = []string{"padre", "madre", "hijo", "hija", "esposo", "esposa"}
+
+// grafoGenealogico es una vista en memoria de las relaciones de padres e
+// hijos y de pareja; cada vecino guarda el id_genealogia que lo respalda. Se
+// carga por partes con expandir para no leer la tabla completa cuando solo se
+// recorren unas cuantas generaciones.
+type grafoGenealogico struct {
+	db          *gorm.DB
+	confirmadas bool
+	padres      map[uint]map[uint]uint
+	hijos       map[uint]map[uint]uint
+	conyuges    map[uint]map[uint]uint
+	cargados    map[uint]bool
+	aristas     map[uint]bool
+	invalidas   []models.Genealogia
+}
+
+func nuevoGrafoGenealogico(db *gorm.DB) *grafoGenealogico {
+	return &grafoGenealogico{
+		db:       db,
+		padres:   make(map[uint]map[uint]uint),
+		hijos:    make(map[uint]map[uint]uint),
+		conyuges: make(map[uint]map[uint]uint),
+		cargados: make(map[uint]bool),
+		aristas:  make(map[uint]bool),
+	}
+}
+
+// nuevoGrafoConfirmado solo carga relaciones que ambas partes confirmaron;
+// es el que se usa cuando el resultado sale hacia alguien que no es admin.
+func nuevoGrafoConfirmado(db *gorm.DB) *grafoGenealogico {
+	g := nuevoGrafoGenealogico(db)
+	g.confirmadas = true
+	return g
+}
+
+// grafoParaVisor usa todas las relaciones solo para un admin; cualquier otro
+// visor recibe únicamente las confirmadas por ambas partes, para que una
+// solicitud pendiente no sirva para sacar el árbol de otra persona.
+func grafoParaVisor(db *gorm.DB, visor *models.User) *grafoGenealogico {
+	if visor != nil && visor.EsAdmin() {
+		return nuevoGrafoGenealogico(db)
+	}
+	return nuevoGrafoConfirmado(db)
+}
+
+func (g *grafoGenealogico) relaciones() *gorm.DB {
+	query := g.db.Where("tipo_relacion IN ?", tiposEstructurales)
+	if g.confirmadas {
+		query = query.Where("confirmado_ambas_partes = ?", true)
+	}
+	return query
+}
+
+// expandir carga las relaciones estructurales de las personas indicadas que
+// aún no se hayan leído. Se consultan ambas direcciones porque los registros
+// previos al servicio de genealogía pueden no tener su inversa.
+func (g *grafoGenealogico) expandir(ids []uint) error {
+	pendientes := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !g.cargados[id] {
+			pendientes = append(pendientes, id)
+			g.cargados[id] = true
+		}
+	}
+	if len(pendientes) == 0 {
+		return nil
+	}
+
+	var relaciones []models.Genealogia
+	err := g.relaciones().Where("id_persona IN ? OR id_pariente IN ?", pendientes, pendientes).
+		Find(&relaciones).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range relaciones {
+		g.agregar(&relaciones[i])
+	}
+	return nil
+}
+
+// cargarTodo lee todas las relaciones estructurales de una vez.
+func (g *grafoGenealogico) cargarTodo() error {
+	var relaciones []models.Genealogia
+	if err := g.relaciones().Find(&relaciones).Error; err != nil {
+		return err
+	}
+	for i := range relaciones {
+		g.agregar(&relaciones[i])
+		g.cargados[relaciones[i].IDPersona] = true
+		g.cargados[relaciones[i].IDPariente] = true
+	}
+	return nil
+}
+
+// agregar normaliza una arista usando su nivel generacional: -1 significa que
+// id_persona está una generación arriba de id_pariente.
+func (g *grafoGenealogico) agregar(r *models.Genealogia) {
+	if g.aristas[r.IDGenealogia] {
+		return
+	}
+	g.aristas[r.IDGenealogia] = true
+
+	if r.IDPersona == r.IDPariente {
+		g.invalidas = append(g.invalidas, *r)
+		return
+	}
+
+	id := r.IDGenealogia
+	switch {
+	case r.EsRelacionMatrimonial():
+		enlazar(g.conyuges, r.IDPersona, r.IDPariente, id)
+		enlazar(g.conyuges, r.IDPariente, r.IDPersona, id)
+	case r.EsRelacionPadreHijo() && r.GetNivelGeneracional() < 0:
+		enlazar(g.hijos, r.IDPersona, r.IDPariente, id)
+		enlazar(g.padres, r.IDPariente, r.IDPersona, id)
+	case r.EsRelacionPadreHijo():
+		enlazar(g.padres, r.IDPersona, r.IDPariente, id)
+		enlazar(g.hijos, r.IDPariente, r.IDPersona, id)
+	}
+}
+
+// enlazar conserva la primera arista vista entre dos personas; la inversa
+// aporta la misma información.
+func enlazar(m map[uint]map[uint]uint, desde, hacia, idArista uint) {
+	if m[desde] == nil {
+		m[desde] = make(map[uint]uint)
+	}
+	if _, existe := m[desde][hacia]; !existe {
+		m[desde][hacia] = idArista
+	}
+}
+
+func (g *grafoGenealogico) padresDe(id uint) []uint   { return ordenarIDs(g.padres[id]) }
+func (g *grafoGenealogico) hijosDe(id uint) []uint    { return ordenarIDs(g.hijos[id]) }
+func (g *grafoGenealogico) conyugesDe(id uint) []uint { return ordenarIDs(g.conyuges[id]) }
+
+// personas devuelve todos los IDs que aparecen en el grafo cargado.
+func (g *grafoGenealogico) personas() []uint {
+	todos := make(map[uint]bool)
+	for _, m := range []map[uint]map[uint]uint{g.padres, g.hijos, g.conyuges} {
+		for id, vecinos := range m {
+			todos[id] = true
+			for v := range vecinos {
+				todos[v] = true
+			}
+		}
+	}
+	return ordenarIDs(todos)
+}
+
+func ordenarIDs[V any](set map[uint]V) []uint {
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// hermanosDe devuelve a quienes comparten al menos un padre con id.
+func (g *grafoGenealogico) hermanosDe(id uint) []uint {
+	hermanos := make(map[uint]bool)
+	for padre := range g.padres[id] {
+		for hijo := range g.hijos[padre] {
+			if hijo != id {
+				hermanos[hijo] = true
+			}
+		}
+	}
+	return ordenarIDs(hermanos)
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var errHorarioInvalido = errors.New("horario no reconocido")
+
+const minutosPorDia = 24 * 60
+
+// zonaNegocios es la hora de Sinaloa, que no cambia en verano. Si el sistema
+// no trae la base de zonas se usa el desfase fijo.
+var zonaNegocios = func() *time.Location {
+	if loc, err := time.LoadLocation("America/Mazatlan"); err == nil {
+		return loc
+	}
+	return time.FixedZone("MST", -7*60*60)
+}()
+
+var diasSemana = []string{"domingo", "lunes", "martes", "miercoles", "jueves", "viernes", "sabado"}
+
+// nombresDia acepta nombres completos y abreviados en español e inglés, ya
+// normalizados (sin acentos y en minúsculas).
+var nombresDia = map[string]time.Weekday{
+	"domingo": time.Sunday, "dom": time.Sunday, "sunday": time.Sunday, "sun": time.Sunday,
+	"lunes": time.Monday, "lun": time.Monday, "monday": time.Monday, "mon": time.Monday,
+	"martes": time.Tuesday, "mar": time.Tuesday, "tuesday": time.Tuesday, "tue": time.Tuesday,
+	"miercoles": time.Wednesday, "mie": time.Wednesday, "wednesday": time.Wednesday, "wed": time.Wednesday,
+	"jueves": time.Thursday, "jue": time.Thursday, "thursday": time.Thursday, "thu": time.Thursday,
+	"viernes": time.Friday, "vie": time.Friday, "friday": time.Friday, "fri": time.Friday,
+	"sabado": time.Saturday, "sab": time.Saturday, "saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// intervaloHorario va en minutos desde la medianoche. Si hasta <= desde el
+// negocio cierra después de la medianoche.
+type intervaloHorario struct {
+	desde, hasta int
+}
+
+func (i intervaloHorario) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", i.desde/60, i.desde%60, (i.hasta/60)%24, i.hasta%60)
+}
+
+// HorarioSemanal es HorariosAtencion ya interpretado. Los días sin entrada
+// se consideran cerrados.
+type HorarioSemanal map[time.Weekday][]intervaloHorario
+
+// ParseHorarios interpreta el JSON libre de horarios_atencion. Acepta un
+// objeto por día ({"lunes": "09:00-18:00", "lun-vie": ["9-14", "16-20"],
+// "domingo": "cerrado"}), objetos con abre/cierra y una lista de objetos con
+// "dia".
+func ParseHorarios(raw string) (HorarioSemanal, error) {
+	var valor any
+	if err := json.Unmarshal([]byte(raw), &valor); err != nil {
+		return nil, errHorarioInvalido
+	}
+
+	horario := HorarioSemanal{}
+	switch v := valor.(type) {
+	case map[string]any:
+		for clave, rangos := range v {
+			if err := horario.agregar(clave, rangos); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for _, elemento := range v {
+			obj, ok := elemento.(map[string]any)
+			if !ok {
+				return nil, errHorarioInvalido
+			}
+			dia, _ := primerTexto(obj, "dia", "dias", "day")
+			if err := horario.agregar(dia, obj); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errHorarioInvalido
+	}
+	return horario, nil
+}
+
+func (h HorarioSemanal) agregar(claveDias string, rangos any) error {
+	dias, err := parseDias(claveDias)
+	if err != nil {
+		return err
+	}
+	intervalos, err := parseIntervalos(rangos)
+	if err != nil {
+		return err
+	}
+	for _, d := range dias {
+		h[d] = append(h[d], intervalos...)
+	}
+	return nil
+}
+
+// parseDias entiende un día, varios ("lunes, miércoles y viernes") o un rango
+// ("lunes-viernes", "lun a vie"). "diario" y "todos los días" son toda la
+// semana.
+func parseDias(clave string) ([]time.Weekday, error) {
+	texto := utils.NormalizarTexto(strings.ReplaceAll(clave, "-", " a "))
+	switch texto {
+	case "diario", "todos", "todos los dias":
+		return []time.Weekday{0, 1, 2, 3, 4, 5, 6}, nil
+	}
+
+	var dias []time.Weekday
+	palabras := strings.Fields(texto)
+	for i := 0; i < len(palabras); i++ {
+		if palabras[i] == "y" {
+			continue
+		}
+		inicio, ok := nombresDia[palabras[i]]
+		if !ok {
+			return nil, errHorarioInvalido
+		}
+		fin := inicio
+		if i+2 < len(palabras) && palabras[i+1] == "a" {
+			if fin, ok = nombresDia[palabras[i+2]]; !ok {
+				return nil, errHorarioInvalido
+			}
+			i += 2
+		}
+		for d := inicio; ; d = (d + 1) % 7 {
+			dias = append(dias, d)
+			if d == fin {
+				break
+			}
+		}
+	}
+	if len(dias) == 0 {
+		return nil, errHorarioInvalido
+	}
+	return dias, nil
+}
+
+func parseIntervalos(valor any) ([]intervaloHorario, error) {
+	switch v := valor.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if v {
+			return []intervaloHorario{{0, minutosPorDia}}, nil
+		}
+		return nil, nil
+	case string:
+		var intervalos []intervaloHorario
+		for _, parte := range strings.Split(v, ",") {
+			i, abierto, err := parseRango(parte)
+			if err != nil {
+				return nil, err
+			}
+			if abierto {
+				intervalos = append(intervalos, i)
+			}
+		}
+		return intervalos, nil
+	case []any:
+		var intervalos []intervaloHorario
+		for _, elemento := range v {
+			parciales, err := parseIntervalos(elemento)
+			if err != nil {
+				return nil, err
+			}
+			intervalos = append(intervalos, parciales...)
+		}
+		return intervalos, nil
+	case map[string]any:
+		if cerrado, ok := v["cerrado"].(bool); ok && cerrado {
+			return nil, nil
+		}
+		abre, okAbre := primerTexto(v, "abre", "apertura", "desde", "open")
+		cierra, okCierra := primerTexto(v, "cierra", "cierre", "hasta", "close")
+		if !okAbre || !okCierra {
+			return nil, errHorarioInvalido
+		}
+		return parseIntervalos(abre + "-" + cierra)
+	}
+	return nil, errHorarioInvalido
+}
+
+// parseRango lee "09:00-18:00", "9-14", "9:30 a 20:00", "cerrado" o "24h".
+func parseRango(texto string) (intervaloHorario, bool, error) {
+	texto = utils.NormalizarTexto(texto)
+	switch texto {
+	case "", "cerrado", "closed":
+		return intervaloHorario{}, false, nil
+	case "24h", "24 h", "24 horas", "24 hrs":
+		return intervaloHorario{0, minutosPorDia}, true, nil
+	}
+
+	// NormalizarTexto convierte ":" y "-" en espacios: "09 00 18 00".
+	texto = strings.ReplaceAll(texto, " a ", " ")
+	campos := strings.Fields(texto)
+	var horas []int
+	switch len(campos) {
+	case 2, 4:
+		for _, c := range campos {
+			n, err := strconv.Atoi(c)
+			if err != nil {
+				return intervaloHorario{}, false, errHorarioInvalido
+			}
+			horas = append(horas, n)
+		}
+	default:
+		return intervaloHorario{}, false, errHorarioInvalido
+	}
+
+	var desde, hasta int
+	if len(horas) == 2 {
+		desde, hasta = horas[0]*60, horas[1]*60
+	} else {
+		if horas[1] > 59 || horas[3] > 59 {
+			return intervaloHorario{}, false, errHorarioInvalido
+		}
+		desde, hasta = horas[0]*60+horas[1], horas[2]*60+horas[3]
+	}
+	if desde >= minutosPorDia || hasta > minutosPorDia {
+		return intervaloHorario{}, false, errHorarioInvalido
+	}
+	return intervaloHorario{desde, hasta}, true, nil
+}
+
+func primerTexto(obj map[string]any, claves ...string) (string, bool) {
+	for _, c := range claves {
+		if s, ok := obj[c].(string); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// AbiertoEn revisa el día de t y los turnos del día anterior que pasan de la
+// medianoche.
+func (h HorarioSemanal) AbiertoEn(t time.Time) bool {
+	t = t.In(zonaNegocios)
+	minuto := t.Hour()*60 + t.Minute()
+	hoy := t.Weekday()
+	ayer := (hoy + 6) % 7
+
+	for _, i := range h[hoy] {
+		if i.hasta > i.desde {
+			if minuto >= i.desde && minuto < i.hasta {
+				return true
+			}
+		} else if minuto >= i.desde {
+			return true
+		}
+	}
+	for _, i := range h[ayer] {
+		if i.hasta <= i.desde && minuto < i.hasta {
+			return true
+		}
+	}
+	return false
+}
+
+// Texto devuelve el horario por día en formato "HH:MM-HH:MM"; los días
+// cerrados quedan con lista vacía.
+func (h HorarioSemanal) Texto() map[string][]string {
+	texto := make(map[string][]string, len(diasSemana))
+	for d, nombre := range diasSemana {
+		rangos := []string{}
+		for _, i := range h[time.Weekday(d)] {
+			rangos = append(rangos, i.String())
+		}
+		texto[nombre] = rangos
+	}
+	return texto
+}
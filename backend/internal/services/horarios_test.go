@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseHorarios(t *testing.T) {
+	semana := func(lunVie, sab, dom []string) map[string][]string {
+		return map[string][]string{
+			"lunes": lunVie, "martes": lunVie, "miercoles": lunVie, "jueves": lunVie, "viernes": lunVie,
+			"sabado": sab, "domingo": dom,
+		}
+	}
+	nada := []string{}
+
+	casos := []struct {
+		nombre string
+		raw    string
+		texto  map[string][]string
+	}{
+		{
+			"objeto por día con rango de días",
+			`{"lun-vie": "09:00-18:00", "sábado": "9-14", "domingo": "cerrado"}`,
+			semana([]string{"09:00-18:00"}, []string{"09:00-14:00"}, nada),
+		},
+		{
+			"lista de rangos por día",
+			`{"lunes a viernes": ["9-14", "16:30-20:00"], "sab": "10:00 a 13:00"}`,
+			semana([]string{"09:00-14:00", "16:30-20:00"}, []string{"10:00-13:00"}, nada),
+		},
+		{
+			"rangos separados por coma",
+			`{"lunes-viernes": "9-14, 16-20"}`,
+			semana([]string{"09:00-14:00", "16:00-20:00"}, nada, nada),
+		},
+		{
+			"objetos con abre y cierra",
+			`{"Mon-Fri": {"abre": "08:00", "cierra": "17:00"}, "sat": {"open": "9", "close": "13"}, "sun": {"cerrado": true}}`,
+			semana([]string{"08:00-17:00"}, []string{"09:00-13:00"}, nada),
+		},
+		{
+			"lista de objetos con dia",
+			`[{"dia": "lunes, miércoles y viernes", "desde": "10:00", "hasta": "15:00"}, {"dias": "sab-dom", "apertura": "12", "cierre": "18"}]`,
+			map[string][]string{
+				"lunes": {"10:00-15:00"}, "martes": nada, "miercoles": {"10:00-15:00"}, "jueves": nada,
+				"viernes": {"10:00-15:00"}, "sabado": {"12:00-18:00"}, "domingo": {"12:00-18:00"},
+			},
+		},
+		{
+			"diario y 24 horas",
+			`{"diario": "24h"}`,
+			semana([]string{"00:00-00:00"}, []string{"00:00-00:00"}, []string{"00:00-00:00"}),
+		},
+		{
+			"rango que da la vuelta a la semana",
+			`{"vie-lun": "18-02"}`,
+			map[string][]string{
+				"lunes": {"18:00-02:00"}, "martes": nada, "miercoles": nada, "jueves": nada,
+				"viernes": {"18:00-02:00"}, "sabado": {"18:00-02:00"}, "domingo": {"18:00-02:00"},
+			},
+		},
+	}
+	for _, c := range casos {
+		h, err := ParseHorarios(c.raw)
+		if err != nil {
+			t.Errorf("%s: %v", c.nombre, err)
+			continue
+		}
+		if got := h.Texto(); !reflect.DeepEqual(got, c.texto) {
+			t.Errorf("%s: Texto() = %v; se esperaba %v", c.nombre, got, c.texto)
+		}
+	}
+}
+
+func TestParseHorariosInvalido(t *testing.T) {
+	casos := []string{
+		`no es json`,
+		`"lunes 9-18"`,
+		`{"lunex": "9-18"}`,
+		`{"lunes": "9-25"}`,
+		`{"lunes": "9:75-18:00"}`,
+		`{"lunes": "temprano"}`,
+		`{"lunes": {"abre": "9"}}`,
+		`["lunes"]`,
+	}
+	for _, raw := range casos {
+		if _, err := ParseHorarios(raw); !errors.Is(err, errHorarioInvalido) {
+			t.Errorf("ParseHorarios(%s): error = %v; se esperaba errHorarioInvalido", raw, err)
+		}
+	}
+}
+
+func TestAbiertoEn(t *testing.T) {
+	h, err := ParseHorarios(`{"lun-jue": "09:00-18:00", "vie": ["9-14", "20:00-02:00"], "sab-dom": "cerrado"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// El 1 de enero de 2024 fue lunes.
+	lunes := time.Date(2024, time.January, 1, 0, 0, 0, 0, zonaNegocios)
+	en := func(dias, hora, minuto int) time.Time {
+		return lunes.AddDate(0, 0, dias).Add(time.Duration(hora)*time.Hour + time.Duration(minuto)*time.Minute)
+	}
+
+	casos := []struct {
+		nombre  string
+		momento time.Time
+		abierto bool
+	}{
+		{"lunes antes de abrir", en(0, 8, 59), false},
+		{"lunes al abrir", en(0, 9, 0), true},
+		{"jueves por la tarde", en(3, 17, 59), true},
+		{"jueves al cerrar", en(3, 18, 0), false},
+		{"viernes entre turnos", en(4, 15, 0), false},
+		{"viernes en la noche", en(4, 23, 30), true},
+		{"sábado de madrugada, turno del viernes", en(5, 1, 59), true},
+		{"sábado al terminar el turno", en(5, 2, 0), false},
+		{"sábado a mediodía", en(5, 12, 0), false},
+		{"viernes de madrugada, el jueves no pasa la medianoche", en(4, 1, 0), false},
+		// La hora se interpreta en la zona de los negocios aunque llegue en UTC.
+		{"lunes 16:00 UTC son las 9:00 en Sinaloa", time.Date(2024, time.January, 1, 16, 0, 0, 0, time.UTC), true},
+		{"lunes 15:59 UTC son las 8:59 en Sinaloa", time.Date(2024, time.January, 1, 15, 59, 0, 0, time.UTC), false},
+	}
+	for _, c := range casos {
+		if got := h.AbiertoEn(c.momento); got != c.abierto {
+			t.Errorf("%s: AbiertoEn = %v; se esperaba %v", c.nombre, got, c.abierto)
+		}
+	}
+
+	siempre, err := ParseHorarios(`{"todos los días": "24 horas"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for d := 0; d < 7; d++ {
+		if !siempre.AbiertoEn(en(d, 0, 0)) || !siempre.AbiertoEn(en(d, 23, 59)) {
+			t.Errorf("24 horas debe estar abierto todo el día %d", d)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+type InferenciaFiltros struct {
+	IDPersona *uint `form:"id_persona" json:"id_persona"`
+}
+
+// RelacionInferida se lee igual que una fila de genealogia: id_persona es
+// tipo_relacion de id_pariente.
+type RelacionInferida struct {
+	IDPersona    uint   `json:"id_persona"`
+	IDPariente   uint   `json:"id_pariente"`
+	TipoRelacion string `json:"tipo_relacion"`
+	Motivo       string `json:"motivo"`
+}
+
+type ConflictoGenealogia struct {
+	Registrada models.Genealogia `json:"registrada"`
+	Inferida   RelacionInferida  `json:"inferida"`
+}
+
+type ResultadoInferencia struct {
+	Faltantes      []RelacionInferida    `json:"faltantes"`
+	Conflictos     []ConflictoGenealogia `json:"conflictos"`
+	Materializadas int                   `json:"materializadas"`
+}
+
+type parPersonas struct {
+	persona, pariente uint
+}
+
+// motorInferencia deduce las relaciones derivadas a partir de las aristas de
+// padres e hijos y de pareja. Cada par conserva la primera relación inferida,
+// y las reglas se aplican de la más cercana a la más lejana, así que si los
+// datos dicen que dos personas son hermanos y primos gana "hermano".
+type motorInferencia struct {
+	grafo     *grafoGenealogico
+	generos   map[uint]*string
+	inferidas map[parPersonas]RelacionInferida
+	orden     []parPersonas
+}
+
+// Inferir compara las relaciones que implican los padres y las parejas
+// registradas con las que están guardadas. Devuelve las que faltan y las
+// guardadas que contradicen a las inferidas.
+func (s *GenealogiaService) Inferir(filtros InferenciaFiltros) (*ResultadoInferencia, error) {
+	motor, err := s.nuevoMotorInferencia()
+	if err != nil {
+		return nil, err
+	}
+	motor.inferir()
+
+	var registradas []models.Genealogia
+	query := s.db.Order("id_genealogia ASC")
+	if filtros.IDPersona != nil {
+		query = query.Where("id_persona = ? OR id_pariente = ?", *filtros.IDPersona, *filtros.IDPersona)
+	}
+	if err := query.Find(&registradas).Error; err != nil {
+		return nil, err
+	}
+
+	porPar := make(map[parPersonas][]models.Genealogia)
+	for _, r := range registradas {
+		par := parPersonas{r.IDPersona, r.IDPariente}
+		porPar[par] = append(porPar[par], r)
+	}
+
+	resultado := &ResultadoInferencia{
+		Faltantes:  []RelacionInferida{},
+		Conflictos: []ConflictoGenealogia{},
+	}
+	for _, par := range motor.orden {
+		if filtros.IDPersona != nil && par.persona != *filtros.IDPersona && par.pariente != *filtros.IDPersona {
+			continue
+		}
+		inferida := motor.inferidas[par]
+		guardadas := porPar[par]
+		if len(guardadas) == 0 {
+			resultado.Faltantes = append(resultado.Faltantes, inferida)
+			continue
+		}
+
+		coincide := false
+		for _, g := range guardadas {
+			if models.RelacionBase(g.TipoRelacion) == models.RelacionBase(inferida.TipoRelacion) {
+				coincide = true
+				break
+			}
+		}
+		if !coincide {
+			for _, g := range guardadas {
+				resultado.Conflictos = append(resultado.Conflictos, ConflictoGenealogia{Registrada: g, Inferida: inferida})
+			}
+		}
+	}
+
+	return resultado, nil
+}
+
+// MaterializarInferencias guarda las relaciones faltantes como sugerencias
+// sin confirmar; las personas involucradas las confirman como cualquier otra.
+func (s *GenealogiaService) MaterializarInferencias(filtros InferenciaFiltros) (*ResultadoInferencia, error) {
+	resultado, err := s.Inferir(filtros)
+	if err != nil {
+		return nil, err
+	}
+	if len(resultado.Faltantes) == 0 {
+		return resultado, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, f := range resultado.Faltantes {
+			nota := "Sugerida automáticamente: " + f.Motivo
+			fila := models.Genealogia{
+				IDPersona:    f.IDPersona,
+				IDPariente:   f.IDPariente,
+				TipoRelacion: f.TipoRelacion,
+				Notas:        &nota,
+			}
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fila)
+			if res.Error != nil {
+				return res.Error
+			}
+			resultado.Materializadas += int(res.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, traducirErrorGenealogia(err)
+	}
+
+	return resultado, nil
+}
+
+func (s *GenealogiaService) nuevoMotorInferencia() (*motorInferencia, error) {
+	grafo := nuevoGrafoGenealogico(s.db)
+	if err := grafo.cargarTodo(); err != nil {
+		return nil, err
+	}
+
+	var personas []models.Persona
+	if err := s.db.Select("id_persona", "genero").Find(&personas).Error; err != nil {
+		return nil, err
+	}
+	generos := make(map[uint]*string, len(personas))
+	for _, p := range personas {
+		generos[p.IDPersona] = p.Genero
+	}
+
+	return &motorInferencia{
+		grafo:     grafo,
+		generos:   generos,
+		inferidas: make(map[parPersonas]RelacionInferida),
+	}, nil
+}
+
+func (m *motorInferencia) inferir() {
+	g := m.grafo
+	ids := g.personas()
+
+	// Inversas de padres y parejas que no se guardaron.
+	for _, x := range ids {
+		for _, p := range g.padresDe(x) {
+			m.agregarPar(p, x, "padre", "hijo", "registrado como padre o madre")
+		}
+		for _, c := range g.conyugesDe(x) {
+			m.agregar(c, x, "esposo", "registrado como pareja")
+		}
+	}
+
+	for _, x := range ids {
+		for _, h := range g.hermanosDe(x) {
+			m.agregar(h, x, "hermano", "comparten padre o madre")
+		}
+	}
+
+	for _, x := range ids {
+		for _, p := range g.padresDe(x) {
+			for _, a := range g.padresDe(p) {
+				m.agregarPar(a, x, "abuelo", "nieto", fmt.Sprintf("padre o madre de %d, quien es padre o madre de %d", p, x))
+			}
+			for _, t := range g.hermanosDe(p) {
+				m.agregarPar(t, x, "tio", "sobrino", fmt.Sprintf("hermano o hermana de %d, padre o madre de %d", p, x))
+			}
+		}
+	}
+
+	for _, x := range ids {
+		for _, c := range g.conyugesDe(x) {
+			for _, s := range g.padresDe(c) {
+				m.agregarPar(s, x, "suegro", "yerno", fmt.Sprintf("padre o madre de %d, pareja de %d", c, x))
+			}
+			for _, h := range g.hermanosDe(c) {
+				m.agregar(h, x, "cuniado", fmt.Sprintf("hermano o hermana de %d, pareja de %d", c, x))
+			}
+		}
+		for _, h := range g.hermanosDe(x) {
+			for _, c := range g.conyugesDe(h) {
+				m.agregar(c, x, "cuniado", fmt.Sprintf("pareja de %d, hermano o hermana de %d", h, x))
+			}
+		}
+	}
+
+	for _, x := range ids {
+		for _, p := range g.padresDe(x) {
+			for _, t := range g.hermanosDe(p) {
+				for _, primo := range g.hijosDe(t) {
+					m.agregar(primo, x, "primo", fmt.Sprintf("hijo o hija de %d, hermano o hermana de %d", t, p))
+				}
+			}
+		}
+	}
+}
+
+// agregar registra una relación simétrica en ambos sentidos.
+func (m *motorInferencia) agregar(a, b uint, base, motivo string) {
+	m.agregarPar(a, b, base, base, motivo)
+}
+
+// agregarPar registra "a es base de b" y "b es inversa de a", cada una en la
+// forma que corresponde al género de quien la lleva.
+func (m *motorInferencia) agregarPar(a, b uint, base, inversa, motivo string) {
+	if a == b {
+		return
+	}
+	m.registrar(parPersonas{a, b}, base, motivo)
+	m.registrar(parPersonas{b, a}, inversa, motivo)
+}
+
+func (m *motorInferencia) registrar(par parPersonas, base, motivo string) {
+	if _, existe := m.inferidas[par]; existe {
+		return
+	}
+	m.inferidas[par] = RelacionInferida{
+		IDPersona:    par.persona,
+		IDPariente:   par.pariente,
+		TipoRelacion: models.RelacionConGenero(base, m.generos[par.persona]),
+		Motivo:       motivo,
+	}
+	m.orden = append(m.orden, par)
+}
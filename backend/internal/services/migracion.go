@@ -0,0 +1,326 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+const largoExtractoHistoria = 280
+
+// puertosLlegada reconoce los puntos de entrada que aparecen en los
+// registros, escritos de varias formas ("Puerto de Mazatlán, Sin.").
+var puertosLlegada = []struct {
+	nombre string
+	claves []string
+}{
+	{"Mazatlán", []string{"mazatlan"}},
+	{"Manzanillo", []string{"manzanillo"}},
+	{"Salina Cruz", []string{"salina cruz"}},
+	{"Acapulco", []string{"acapulco"}},
+	{"Ensenada", []string{"ensenada"}},
+	{"Guaymas", []string{"guaymas"}},
+	{"Topolobampo", []string{"topolobampo"}},
+	{"Veracruz", []string{"veracruz"}},
+	{"Tampico", []string{"tampico"}},
+	{"Ciudad de México", []string{"ciudad de mexico", "cdmx", "mexico df", "distrito federal"}},
+	{"Tijuana", []string{"tijuana"}},
+	{"Mexicali", []string{"mexicali"}},
+	{"Ciudad Juárez", []string{"ciudad juarez", "juarez"}},
+	{"Nogales", []string{"nogales"}},
+}
+
+type MigracionFiltros struct {
+	Prefectura *string `form:"prefectura"`
+	Puerto     *string `form:"puerto"`
+	Desde      *int    `form:"desde" binding:"omitempty,gte=1800,lte=2100"`
+	Hasta      *int    `form:"hasta" binding:"omitempty,gte=1800,lte=2100"`
+}
+
+type ConteoMigracion struct {
+	Categoria  string  `json:"categoria"`
+	Familias   int64   `json:"familias"`
+	Porcentaje float64 `json:"porcentaje"`
+}
+
+type DecadaMigracion struct {
+	Decada    int    `json:"decada"`
+	Etiqueta  string `json:"etiqueta"`
+	Familias  int64  `json:"familias"`
+	Acumulado int64  `json:"acumulado"`
+}
+
+// FamiliaLlegada es una familia en la línea de tiempo. Prefectura y Puerto
+// son los nombres ya reconocidos; si no se reconocieron se deja lo
+// capturado.
+type FamiliaLlegada struct {
+	IDFamilia     uint    `json:"id_familia"`
+	ApellidoJP    string  `json:"apellido_jp"`
+	ApellidoKanji *string `json:"apellido_kanji"`
+	Prefectura    *string `json:"prefectura"`
+	CiudadOrigen  *string `json:"ciudad_origen"`
+	Puerto        *string `json:"puerto"`
+	LugarLlegada  *string `json:"lugar_llegada"`
+	Extracto      *string `json:"extracto_historia"`
+	TieneHistoria bool    `json:"tiene_historia"`
+	Enlace        string  `json:"enlace"`
+}
+
+type AnioLlegada struct {
+	Anio     int              `json:"anio"`
+	Familias []FamiliaLlegada `json:"familias"`
+}
+
+// HistoriaMigracion resume la llegada de las familias a México. Las
+// familias sin año de llegada cuentan en los desgloses por puerto y
+// prefectura pero no en la línea de tiempo ni en las décadas.
+type HistoriaMigracion struct {
+	GeneradoEn    time.Time         `json:"generado_en"`
+	TotalFamilias int64             `json:"total_familias"`
+	SinAnio       int64             `json:"sin_anio"`
+	PrimerAnio    *int              `json:"primer_anio"`
+	UltimoAnio    *int              `json:"ultimo_anio"`
+	PorDecada     []DecadaMigracion `json:"por_decada"`
+	PorPuerto     []ConteoMigracion `json:"por_puerto"`
+	PorPrefectura []ConteoMigracion `json:"por_prefectura"`
+	PorRegion     []ConteoMigracion `json:"por_region"`
+	LineaTiempo   []AnioLlegada     `json:"linea_tiempo"`
+}
+
+type MigracionService struct {
+	db *gorm.DB
+}
+
+func NewMigracionService(db *gorm.DB) *MigracionService {
+	return &MigracionService{db: db}
+}
+
+// Historia agrega las llegadas en Go porque el puerto y la prefectura se
+// capturan como texto libre y hay que reconocerlos antes de agrupar.
+func (s *MigracionService) Historia(filtros MigracionFiltros, ahora time.Time) (*HistoriaMigracion, error) {
+	var familias []models.Familia
+	err := s.db.Select("id_familia", "apellido_jp", "apellido_kanji", "prefectura_origen", "ciudad_origen",
+		"anio_llegada_mexico", "lugar_llegada", "historia_familiar").
+		Order("anio_llegada_mexico ASC NULLS LAST, apellido_jp ASC, id_familia ASC").
+		Find(&familias).Error
+	if err != nil {
+		return nil, err
+	}
+
+	historia := &HistoriaMigracion{GeneradoEn: ahora, LineaTiempo: []AnioLlegada{}}
+	porPuerto := map[string]int64{}
+	porPrefectura := map[string]int64{}
+	porRegion := map[string]int64{}
+	porDecada := map[int]int64{}
+
+	for i := range familias {
+		f := &familias[i]
+		llegada := nuevaFamiliaLlegada(f)
+		region := sinDato
+		if f.PrefecturaOrigen != nil {
+			if p, ok := japones.BuscarPrefectura(*f.PrefecturaOrigen); ok {
+				region = p.Region
+			}
+		}
+
+		if !coincideTexto(filtros.Prefectura, llegada.Prefectura) || !coincideTexto(filtros.Puerto, llegada.Puerto) {
+			continue
+		}
+		anio := f.AnioLlegadaMexico
+		if (filtros.Desde != nil || filtros.Hasta != nil) && anio == nil {
+			continue
+		}
+		if (filtros.Desde != nil && *anio < *filtros.Desde) || (filtros.Hasta != nil && *anio > *filtros.Hasta) {
+			continue
+		}
+
+		historia.TotalFamilias++
+		porPuerto[valorOSinDato(llegada.Puerto)]++
+		porPrefectura[valorOSinDato(llegada.Prefectura)]++
+		porRegion[region]++
+
+		if anio == nil {
+			historia.SinAnio++
+			continue
+		}
+		porDecada[*anio/10*10]++
+		if historia.PrimerAnio == nil {
+			historia.PrimerAnio = anio
+		}
+		historia.UltimoAnio = anio
+
+		ultimo := len(historia.LineaTiempo) - 1
+		if ultimo < 0 || historia.LineaTiempo[ultimo].Anio != *anio {
+			historia.LineaTiempo = append(historia.LineaTiempo, AnioLlegada{Anio: *anio})
+			ultimo++
+		}
+		historia.LineaTiempo[ultimo].Familias = append(historia.LineaTiempo[ultimo].Familias, llegada)
+	}
+
+	historia.PorPuerto = conteosMigracion(porPuerto, historia.TotalFamilias)
+	historia.PorPrefectura = conteosMigracion(porPrefectura, historia.TotalFamilias)
+	historia.PorRegion = conteosMigracion(porRegion, historia.TotalFamilias)
+	historia.PorDecada = []DecadaMigracion{}
+	if historia.PrimerAnio != nil {
+		var acumulado int64
+		for d := *historia.PrimerAnio / 10 * 10; d <= *historia.UltimoAnio; d += 10 {
+			acumulado += porDecada[d]
+			historia.PorDecada = append(historia.PorDecada, DecadaMigracion{
+				Decada:    d,
+				Etiqueta:  fmt.Sprintf("%d-%d", d, d+9),
+				Familias:  porDecada[d],
+				Acumulado: acumulado,
+			})
+		}
+	}
+	return historia, nil
+}
+
+func nuevaFamiliaLlegada(f *models.Familia) FamiliaLlegada {
+	llegada := FamiliaLlegada{
+		IDFamilia:     f.IDFamilia,
+		ApellidoJP:    f.ApellidoJP,
+		ApellidoKanji: f.ApellidoKanji,
+		CiudadOrigen:  f.CiudadOrigen,
+		LugarLlegada:  f.LugarLlegada,
+		Enlace:        fmt.Sprintf("/api/v1/familias/%d", f.IDFamilia),
+	}
+	if f.PrefecturaOrigen != nil && strings.TrimSpace(*f.PrefecturaOrigen) != "" {
+		nombre := strings.TrimSpace(*f.PrefecturaOrigen)
+		if p, ok := japones.BuscarPrefectura(nombre); ok {
+			nombre = p.Nombre
+		}
+		llegada.Prefectura = &nombre
+	}
+	if f.LugarLlegada != nil && strings.TrimSpace(*f.LugarLlegada) != "" {
+		puerto := reconocerPuerto(*f.LugarLlegada)
+		llegada.Puerto = &puerto
+	}
+	if f.HistoriaFamiliar != nil && strings.TrimSpace(*f.HistoriaFamiliar) != "" {
+		extracto := extraer(*f.HistoriaFamiliar, largoExtractoHistoria)
+		llegada.Extracto = &extracto
+		llegada.TieneHistoria = true
+	}
+	return llegada
+}
+
+// reconocerPuerto devuelve el nombre canónico del puerto o, si no es uno
+// conocido, el lugar tal como se capturó.
+func reconocerPuerto(lugar string) string {
+	texto := " " + utils.NormalizarTexto(lugar) + " "
+	for _, p := range puertosLlegada {
+		for _, clave := range p.claves {
+			if strings.Contains(texto, " "+clave+" ") {
+				return p.nombre
+			}
+		}
+	}
+	return strings.TrimSpace(lugar)
+}
+
+// extraer corta el texto en la última palabra completa antes del límite.
+func extraer(texto string, largo int) string {
+	texto = strings.Join(strings.Fields(texto), " ")
+	r := []rune(texto)
+	if len(r) <= largo {
+		return texto
+	}
+	corte := largo
+	for corte > largo/2 && !unicode.IsSpace(r[corte]) {
+		corte--
+	}
+	return strings.TrimRightFunc(string(r[:corte]), unicode.IsPunct) + "…"
+}
+
+func coincideTexto(filtro, valor *string) bool {
+	if filtro == nil || strings.TrimSpace(*filtro) == "" {
+		return true
+	}
+	return valor != nil && japones.Normalizar(*filtro) == japones.Normalizar(*valor)
+}
+
+func valorOSinDato(v *string) string {
+	if v == nil {
+		return sinDato
+	}
+	return *v
+}
+
+// conteosMigracion ordena de mayor a menor y deja sin_dato al final.
+func conteosMigracion(conteos map[string]int64, total int64) []ConteoMigracion {
+	lista := make([]ConteoMigracion, 0, len(conteos))
+	for categoria, n := range conteos {
+		lista = append(lista, ConteoMigracion{Categoria: categoria, Familias: n, Porcentaje: porcentaje(n, total)})
+	}
+	sort.Slice(lista, func(i, j int) bool {
+		if (lista[i].Categoria == sinDato) != (lista[j].Categoria == sinDato) {
+			return lista[j].Categoria == sinDato
+		}
+		if lista[i].Familias != lista[j].Familias {
+			return lista[i].Familias > lista[j].Familias
+		}
+		return lista[i].Categoria < lista[j].Categoria
+	})
+	return lista
+}
+
+// Documento arma el reporte para la exposición: los desgloses con gráfica y
+// la línea de tiempo completa.
+func (h *HistoriaMigracion) Documento() *DocumentoReporte {
+	decadas := make([][]any, len(h.PorDecada))
+	for i, d := range h.PorDecada {
+		decadas[i] = []any{d.Etiqueta, d.Familias, d.Acumulado}
+	}
+	var linea [][]any
+	for _, anio := range h.LineaTiempo {
+		for _, f := range anio.Familias {
+			linea = append(linea, []any{anio.Anio, f.ApellidoJP, f.ApellidoKanji, f.Prefectura, f.CiudadOrigen, f.Puerto, f.Extracto})
+		}
+	}
+
+	return &DocumentoReporte{
+		Nombre:     "historia-migracion",
+		Titulo:     "Llegada de las familias a México",
+		GeneradoEn: h.GeneradoEn,
+		Secciones: []SeccionReporte{
+			{
+				Titulo:   "Llegadas por década",
+				Hoja:     "Décadas",
+				Columnas: []string{"Década", "Familias", "Acumulado"},
+				Filas:    decadas,
+				Grafica:  &GraficaSeccion{Etiqueta: 0, Valor: 1},
+			},
+			seccionMigracion("Puerto de llegada", "Puertos", "Puerto", h.PorPuerto),
+			seccionMigracion("Prefectura de origen", "Prefecturas", "Prefectura", h.PorPrefectura),
+			seccionMigracion("Región de origen", "Regiones", "Región", h.PorRegion),
+			{
+				Titulo:   "Línea de tiempo",
+				Hoja:     "Línea de tiempo",
+				Columnas: []string{"Año", "Familia", "Kanji", "Prefectura", "Ciudad de origen", "Puerto", "Historia"},
+				Filas:    linea,
+			},
+		},
+	}
+}
+
+func seccionMigracion(titulo, hoja, columna string, conteos []ConteoMigracion) SeccionReporte {
+	filas := make([][]any, len(conteos))
+	for i, c := range conteos {
+		filas[i] = []any{c.Categoria, c.Familias, c.Porcentaje}
+	}
+	return SeccionReporte{
+		Titulo:   titulo,
+		Hoja:     hoja,
+		Columnas: []string{columna, "Familias", "%"},
+		Filas:    filas,
+		Grafica:  &GraficaSeccion{Etiqueta: 0, Valor: 1},
+	}
+}
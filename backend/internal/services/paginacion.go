@@ -0,0 +1,67 @@
+package services
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type Paginacion struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+type ListaPaginada[T any] struct {
+	Items      []T        `json:"items"`
+	Pagination Paginacion `json:"pagination"`
+}
+
+func nuevaPaginacion(page, pageSize int) Paginacion {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return Paginacion{Page: page, PageSize: pageSize}
+}
+
+func (p *Paginacion) aplicar(db *gorm.DB) *gorm.DB {
+	return db.Offset((p.Page - 1) * p.PageSize).Limit(p.PageSize)
+}
+
+func (p *Paginacion) setTotal(total int64) {
+	p.Total = total
+	p.TotalPages = int((total + int64(p.PageSize) - 1) / int64(p.PageSize))
+}
+
+// ordenSQL convierte "campo,-otro" en una cláusula ORDER BY usando solo las
+// columnas permitidas. Los campos desconocidos se ignoran.
+func ordenSQL(sort string, permitidos map[string]string, defecto string) string {
+	var partes []string
+	for _, campo := range strings.Split(sort, ",") {
+		campo = strings.TrimSpace(campo)
+		dir := "ASC"
+		if strings.HasPrefix(campo, "-") {
+			dir = "DESC"
+			campo = campo[1:]
+		}
+		if columna, ok := permitidos[campo]; ok {
+			partes = append(partes, columna+" "+dir)
+		}
+	}
+	if len(partes) == 0 {
+		return defecto
+	}
+	return strings.Join(partes, ", ")
+}
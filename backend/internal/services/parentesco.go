@@ -0,0 +1,332 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+var ErrSinParentesco = errors.New("no se encontró un parentesco entre estas personas")
+
+// maxPasosParentesco limita la búsqueda; más allá de esto el parentesco no
+// tiene nombre útil y la consulta recorrería media comunidad.
+const maxPasosParentesco = 12
+
+// Pasos del camino: subir a un padre, bajar a un hijo o cruzar a la pareja.
+const (
+	pasoPadre   = 'U'
+	pasoHijo    = 'D'
+	pasoConyuge = 'S'
+)
+
+type PasoParentesco struct {
+	Persona      PersonaArbol `json:"persona"`
+	Relacion     string       `json:"relacion"`
+	IDGenealogia uint         `json:"id_genealogia"`
+}
+
+// Parentesco describe qué es Hasta para Desde, con el camino que lo respalda.
+type Parentesco struct {
+	Desde         PersonaArbol     `json:"desde"`
+	Hasta         PersonaArbol     `json:"hasta"`
+	Nombre        string           `json:"parentesco"`
+	Consanguineo  bool             `json:"consanguineo"`
+	Grado         *int             `json:"grado"`
+	AncestroComun *PersonaArbol    `json:"ancestro_comun"`
+	Camino        []PasoParentesco `json:"camino"`
+}
+
+type previoParentesco struct {
+	desde     uint
+	paso      byte
+	arista    uint
+	conyuges  int
+	distancia int
+}
+
+// Parentesco busca el camino más corto entre dos personas a través de padres,
+// hijos y parejas. Entre caminos del mismo largo prefiere el que cruza menos
+// matrimonios, para nombrar primero el vínculo de sangre. Si el visor no
+// puede ver el género de alguien se usa la forma masculina, la genérica.
+func (s *GenealogiaService) Parentesco(visor *models.User, idDesde, idHasta uint) (*Parentesco, error) {
+	if _, err := cargarPersona(s.db, idDesde); err != nil {
+		return nil, err
+	}
+	if _, err := cargarPersona(s.db, idHasta); err != nil {
+		return nil, err
+	}
+
+	grafo := grafoParaVisor(s.db, visor)
+	previos := map[uint]previoParentesco{idDesde: {}}
+	frontera := []uint{idDesde}
+
+	for distancia := 1; distancia <= maxPasosParentesco && len(frontera) > 0; distancia++ {
+		if _, encontrado := previos[idHasta]; encontrado {
+			break
+		}
+		if err := grafo.expandir(frontera); err != nil {
+			return nil, err
+		}
+
+		candidatos := make(map[uint]previoParentesco)
+		for _, id := range frontera {
+			base := previos[id]
+			vecinos := []struct {
+				paso byte
+				m    map[uint]uint
+			}{
+				{pasoPadre, grafo.padres[id]},
+				{pasoHijo, grafo.hijos[id]},
+				{pasoConyuge, grafo.conyuges[id]},
+			}
+			for _, v := range vecinos {
+				for _, sig := range ordenarIDs(v.m) {
+					if _, visto := previos[sig]; visto {
+						continue
+					}
+					cand := previoParentesco{desde: id, paso: v.paso, arista: v.m[sig], conyuges: base.conyuges, distancia: distancia}
+					if v.paso == pasoConyuge {
+						cand.conyuges++
+					}
+					if actual, ok := candidatos[sig]; !ok || cand.conyuges < actual.conyuges {
+						candidatos[sig] = cand
+					}
+				}
+			}
+		}
+
+		frontera = frontera[:0]
+		for _, id := range ordenarIDs(candidatos) {
+			previos[id] = candidatos[id]
+			frontera = append(frontera, id)
+		}
+	}
+
+	if _, encontrado := previos[idHasta]; !encontrado {
+		return nil, ErrSinParentesco
+	}
+
+	// Se reconstruye el camino desde el destino hacia el origen.
+	var ids []uint
+	var pasos []byte
+	var aristas []uint
+	for id := idHasta; id != idDesde; id = previos[id].desde {
+		p := previos[id]
+		ids = append([]uint{id}, ids...)
+		pasos = append([]byte{p.paso}, pasos...)
+		aristas = append([]uint{p.arista}, aristas...)
+	}
+
+	personas, err := vistasPorID(s.db, visor, append(ids, idDesde))
+	if err != nil {
+		return nil, err
+	}
+	if personas[idDesde] == nil {
+		return nil, ErrPersonaNoEncontrada
+	}
+	if personas[idHasta] == nil {
+		return nil, ErrSinParentesco
+	}
+
+	hasta := personas[idHasta]
+	femenino := hasta.Genero != nil && *hasta.Genero == "femenino"
+	resultado := &Parentesco{
+		Desde:  personaArbol(personas[idDesde]),
+		Hasta:  personaArbol(hasta),
+		Camino: []PasoParentesco{},
+	}
+
+	for i, id := range ids {
+		p := personas[id]
+		if p == nil {
+			return nil, ErrSinParentesco
+		}
+		fem := p.Genero != nil && *p.Genero == "femenino"
+		resultado.Camino = append(resultado.Camino, PasoParentesco{
+			Persona:      personaArbol(p),
+			Relacion:     nombrePaso(pasos[i], fem),
+			IDGenealogia: aristas[i],
+		})
+	}
+
+	patron := string(pasos)
+	resultado.Nombre, resultado.Consanguineo = nombrarParentesco(patron, femenino)
+	if patron == "UD" && !comparteAmbosPadres(grafo, idDesde, idHasta) {
+		resultado.Nombre = genero(femenino, "medio hermano", "media hermana")
+	}
+	if resultado.Consanguineo {
+		grado := len(pasos)
+		resultado.Grado = &grado
+		subidas := strings.Count(patron, string(pasoPadre))
+		if subidas > 0 && subidas < len(pasos) {
+			comun := resultado.Camino[subidas-1].Persona
+			resultado.AncestroComun = &comun
+		}
+	}
+
+	return resultado, nil
+}
+
+// comparteAmbosPadres solo descarta a los hermanos completos cuando los dos
+// tienen padre y madre registrados y difieren en alguno.
+func comparteAmbosPadres(g *grafoGenealogico, a, b uint) bool {
+	padresA, padresB := g.padresDe(a), g.padresDe(b)
+	if len(padresA) < 2 || len(padresB) < 2 {
+		return true
+	}
+	for _, p := range padresA {
+		if _, ok := g.padres[b][p]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func nombrePaso(paso byte, femenino bool) string {
+	switch paso {
+	case pasoPadre:
+		return genero(femenino, "padre", "madre")
+	case pasoHijo:
+		return genero(femenino, "hijo", "hija")
+	default:
+		return genero(femenino, "esposo", "esposa")
+	}
+}
+
+// nombrarParentesco traduce un patrón de pasos a su nombre en español. Los
+// casos políticos con nombre propio van primero; el resto es un parentesco
+// de sangre con "político" cuando el camino empieza o termina en una pareja.
+func nombrarParentesco(patron string, femenino bool) (string, bool) {
+	switch patron {
+	case "":
+		return "misma persona", false
+	case "S":
+		return genero(femenino, "esposo", "esposa"), false
+	case "SU":
+		return genero(femenino, "suegro", "suegra"), false
+	case "DS":
+		return genero(femenino, "yerno", "nuera"), false
+	case "SUD", "UDS":
+		return genero(femenino, "cuñado", "cuñada"), false
+	case "SUDS":
+		return genero(femenino, "concuño", "concuña"), false
+	case "US":
+		return genero(femenino, "padrastro", "madrastra"), false
+	case "SD":
+		return genero(femenino, "hijastro", "hijastra"), false
+	case "DSU":
+		return genero(femenino, "consuegro", "consuegra"), false
+	}
+
+	sangre := strings.TrimSuffix(strings.TrimPrefix(patron, string(pasoConyuge)), string(pasoConyuge))
+	politico := sangre != patron
+	subidas := len(sangre) - len(strings.TrimLeft(sangre, string(pasoPadre)))
+	bajadas := len(sangre) - subidas
+	if strings.ContainsRune(sangre, pasoConyuge) || strings.Count(sangre, string(pasoHijo)) != bajadas {
+		return genero(femenino, "pariente político", "pariente política"), false
+	}
+
+	nombre := nombreConsanguineo(subidas, bajadas, femenino)
+	if politico {
+		return nombre + " " + genero(femenino, "político", "política"), false
+	}
+	return nombre, true
+}
+
+// nombreConsanguineo nombra el parentesco de quien está a "subidas"
+// generaciones del ancestro común desde el origen y a "bajadas" desde él.
+func nombreConsanguineo(subidas, bajadas int, femenino bool) string {
+	switch {
+	case bajadas == 0:
+		return lineaDirecta(subidas, femenino, ancestros)
+	case subidas == 0:
+		return lineaDirecta(bajadas, femenino, descendientes)
+	case subidas == 1 && bajadas == 1:
+		return genero(femenino, "hermano", "hermana")
+	}
+
+	grado := min(subidas, bajadas)
+	diferencia := subidas - bajadas
+
+	var partes []string
+	switch {
+	case diferencia == 0:
+		partes = append(partes, genero(femenino, "primo", "prima"))
+		if grado == 2 {
+			partes = append(partes, genero(femenino, "hermano", "hermana"))
+		}
+	case diferencia > 0:
+		partes = append(partes, genero(femenino, "tío", "tía"))
+		if diferencia > 1 {
+			partes = append(partes, lineaDirecta(diferencia, femenino, ancestros))
+		}
+	default:
+		partes = append(partes, genero(femenino, "sobrino", "sobrina"))
+		if -diferencia > 1 {
+			partes = append(partes, lineaDirecta(-diferencia, femenino, descendientes))
+		}
+	}
+
+	// En los primos el grado se cuenta desde los primos hermanos; en tíos y
+	// sobrinos, desde el tío carnal.
+	ordinal := grado
+	if diferencia == 0 {
+		ordinal = grado - 1
+	}
+	if ordinal >= 2 {
+		partes = append(partes, ordinalParentesco(ordinal, femenino))
+	}
+	return strings.Join(partes, " ")
+}
+
+var ancestros = [][2]string{
+	{"padre", "madre"},
+	{"abuelo", "abuela"},
+	{"bisabuelo", "bisabuela"},
+	{"tatarabuelo", "tatarabuela"},
+	{"trastatarabuelo", "trastatarabuela"},
+}
+
+var descendientes = [][2]string{
+	{"hijo", "hija"},
+	{"nieto", "nieta"},
+	{"bisnieto", "bisnieta"},
+	{"tataranieto", "tataranieta"},
+	{"trastataranieto", "trastataranieta"},
+}
+
+func lineaDirecta(generaciones int, femenino bool, nombres [][2]string) string {
+	if generaciones <= len(nombres) {
+		n := nombres[generaciones-1]
+		return genero(femenino, n[0], n[1])
+	}
+	base := nombres[len(nombres)-1]
+	return fmt.Sprintf("%s (%d generaciones)", genero(femenino, base[0], base[1]), generaciones)
+}
+
+var ordinales = [][2]string{
+	{"segundo", "segunda"},
+	{"tercero", "tercera"},
+	{"cuarto", "cuarta"},
+	{"quinto", "quinta"},
+	{"sexto", "sexta"},
+	{"séptimo", "séptima"},
+	{"octavo", "octava"},
+}
+
+func ordinalParentesco(n int, femenino bool) string {
+	if n-2 < len(ordinales) {
+		o := ordinales[n-2]
+		return genero(femenino, o[0], o[1])
+	}
+	return fmt.Sprintf("de grado %d", n)
+}
+
+func genero(femenino bool, masculino, femenina string) string {
+	if femenino {
+		return femenina
+	}
+	return masculino
+}
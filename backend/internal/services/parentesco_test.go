@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNombrarParentesco(t *testing.T) {
+	casos := []struct {
+		patron       string
+		femenino     bool
+		nombre       string
+		consanguineo bool
+	}{
+		{"", false, "misma persona", false},
+		{"U", false, "padre", true},
+		{"U", true, "madre", true},
+		{"UUU", true, "bisabuela", true},
+		{"UUUUUU", false, "trastatarabuelo (6 generaciones)", true},
+		{"DD", true, "nieta", true},
+		{"UD", false, "hermano", true},
+		{"UD", true, "hermana", true},
+		{"UUDD", false, "primo hermano", true},
+		{"UUDD", true, "prima hermana", true},
+		{"UUUDDD", false, "primo segundo", true},
+		{"UUUDDD", true, "prima segunda", true},
+		{"UUUUDDDD", false, "primo tercero", true},
+		{"UUD", false, "tío", true},
+		{"UUD", true, "tía", true},
+		{"UUUD", false, "tío abuelo", true},
+		{"UUUD", true, "tía abuela", true},
+		{"UUUDD", false, "tío segundo", true},
+		{"UUUUDD", true, "tía abuela segunda", true},
+		{"UDD", false, "sobrino", true},
+		{"UDDD", true, "sobrina nieta", true},
+		{"UUDDD", false, "sobrino segundo", true},
+
+		{"S", true, "esposa", false},
+		{"SU", false, "suegro", false},
+		{"SU", true, "suegra", false},
+		{"DS", false, "yerno", false},
+		{"DS", true, "nuera", false},
+		{"SUD", false, "cuñado", false},
+		{"UDS", true, "cuñada", false},
+		{"SUDS", false, "concuño", false},
+		{"SUDS", true, "concuña", false},
+		{"US", true, "madrastra", false},
+		{"SD", false, "hijastro", false},
+		{"DSU", true, "consuegra", false},
+
+		{"SUU", false, "abuelo político", false},
+		{"SUU", true, "abuela política", false},
+		{"UUDS", true, "tía política", false},
+		{"SUUD", false, "tío político", false},
+		{"UDDS", false, "sobrino político", false},
+		{"SUUDD", true, "prima hermana política", false},
+		{"USUD", false, "pariente político", false},
+		{"UDU", true, "pariente política", false},
+	}
+
+	for _, c := range casos {
+		nombre, consanguineo := nombrarParentesco(c.patron, c.femenino)
+		if nombre != c.nombre || consanguineo != c.consanguineo {
+			t.Errorf("nombrarParentesco(%q, %v) = %q, %v; se esperaba %q, %v",
+				c.patron, c.femenino, nombre, consanguineo, c.nombre, c.consanguineo)
+		}
+	}
+}
+
+func TestComparteAmbosPadres(t *testing.T) {
+	const (
+		padre = iota + 1
+		madre
+		otraMadre
+		hijo
+		hermano
+		medioHermano
+		soloPadre
+	)
+	g := nuevoGrafoGenealogico(nil)
+	padresDe := map[uint][]uint{
+		hijo:         {padre, madre},
+		hermano:      {padre, madre},
+		medioHermano: {padre, otraMadre},
+		soloPadre:    {padre},
+	}
+	for h, padres := range padresDe {
+		for _, p := range padres {
+			enlazar(g.padres, h, p, 0)
+			enlazar(g.hijos, p, h, 0)
+		}
+	}
+
+	casos := []struct {
+		a, b uint
+		ok   bool
+	}{
+		{hijo, hermano, true},
+		{hijo, medioHermano, false},
+		{medioHermano, hermano, false},
+		// Con un solo padre registrado no se puede descartar que sean hermanos.
+		{hijo, soloPadre, true},
+	}
+	for _, c := range casos {
+		if got := comparteAmbosPadres(g, c.a, c.b); got != c.ok {
+			t.Errorf("comparteAmbosPadres(%d, %d) = %v; se esperaba %v", c.a, c.b, got, c.ok)
+		}
+	}
+}
+
+func TestParentescoSoloConfirmadasParaNoAdmins(t *testing.T) {
+	for _, v := range visoresGrafo {
+		s, base := baseConPersonas(t)
+		if _, err := s.Parentesco(v.visor, 1, 2); err != nil && !errors.Is(err, ErrSinParentesco) {
+			t.Errorf("%s: %v", v.nombre, err)
+		}
+		revisarConsultasGrafo(t, base, v.nombre, v.confirmadas)
+	}
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/mailer"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+var (
+	ErrEventoSinRegistro          = errors.New("el evento no acepta registros en su estado actual")
+	ErrYaRegistrado               = errors.New("la persona ya está registrada en este evento")
+	ErrParticipacionNoEncontrada  = errors.New("la participación no existe")
+	ErrParticipacionNoConfirmable = errors.New("solo un registro activo que no esté en lista de espera puede confirmarse")
+	ErrParticipacionNoCancelable  = errors.New("la participación ya no puede cancelarse")
+	ErrPersonaNoParticipa         = errors.New("la persona pidió no participar en eventos")
+	ErrEventoIniciado             = errors.New("el evento ya comenzó; la participación no puede confirmarse ni cancelarse")
+)
+
+// statusQueOcupanLugar son los estados que cuentan contra CapacidadMaxima;
+// debe coincidir con ParticipacionEvento.OcupaLugar.
+var statusQueOcupanLugar = []string{"registrado", "confirmado", "asistio"}
+
+type RegistroEventoInput struct {
+	IDPersona             uint
+	Acompaniantes         int
+	NotasParticipante     *string
+	NecesidadesEspeciales *string
+}
+
+type ParticipacionService struct {
+	db     *gorm.DB
+	mailer mailer.Mailer
+}
+
+func NewParticipacionService(db *gorm.DB, m mailer.Mailer) *ParticipacionService {
+	return &ParticipacionService{db: db, mailer: m}
+}
+
+// Registrar inscribe a una persona. La fila del evento se bloquea durante la
+// transacción para que dos registros simultáneos no sobrevendan el cupo; si
+// no hay lugar la participación queda en lista de espera.
+func (s *ParticipacionService) Registrar(idEvento uint, input RegistroEventoInput) (*models.ParticipacionEvento, error) {
+	var participacion models.ParticipacionEvento
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		evento, err := bloquearEvento(tx, idEvento)
+		if err != nil {
+			return err
+		}
+		if !evento.AceptaRegistros() || !evento.RequiereRegistro {
+			return ErrEventoSinRegistro
+		}
+
+		var persona models.Persona
+		if err := tx.First(&persona, input.IDPersona).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPersonaNoEncontrada
+			}
+			return err
+		}
+		if !persona.ParticipaEventos {
+			return ErrPersonaNoParticipa
+		}
+
+		err = tx.Where("id_evento = ? AND id_persona = ?", idEvento, input.IDPersona).First(&participacion).Error
+		switch {
+		case err == nil && !participacion.EstaCancelado():
+			return ErrYaRegistrado
+		case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		ocupados, err := lugaresOcupados(tx, idEvento)
+		if err != nil {
+			return err
+		}
+
+		// Una participación cancelada se reutiliza para respetar la
+		// restricción unique_persona_evento.
+		participacion.IDEvento = idEvento
+		participacion.IDPersona = input.IDPersona
+		participacion.Acompaniantes = input.Acompaniantes
+		participacion.NotasParticipante = input.NotasParticipante
+		participacion.NecesidadesEspeciales = input.NecesidadesEspeciales
+		participacion.FechaConfirmacion = nil
+		participacion.StatusParticipacion = "registrado"
+
+		enEspera, err := hayListaEspera(tx, idEvento)
+		if err != nil {
+			return err
+		}
+		if enEspera || !evento.TieneCapacidadPara(ocupados, participacion.GetTotalPersonas()) {
+			participacion.PonerEnEspera()
+		}
+
+		if participacion.IDParticipacion == 0 {
+			if err := tx.Create(&participacion).Error; err != nil {
+				if esViolacionUnica(err, "unique_persona_evento") {
+					return ErrYaRegistrado
+				}
+				return err
+			}
+			return nil
+		}
+		// Al reinscribirse va al final de la fila, no conserva su lugar previo.
+		participacion.FechaRegistro = time.Now()
+		return tx.Save(&participacion).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &participacion, nil
+}
+
+func (s *ParticipacionService) Get(id uint) (*models.ParticipacionEvento, error) {
+	var participacion models.ParticipacionEvento
+	if err := s.db.First(&participacion, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParticipacionNoEncontrada
+		}
+		return nil, err
+	}
+	return &participacion, nil
+}
+
+func (s *ParticipacionService) ListByEvento(idEvento uint) ([]models.ParticipacionEvento, error) {
+	participaciones := []models.ParticipacionEvento{}
+	err := s.db.Where("id_evento = ?", idEvento).
+		Order("fecha_registro ASC, id_participacion ASC").
+		Find(&participaciones).Error
+	return participaciones, err
+}
+
+func (s *ParticipacionService) Confirmar(id uint) (*models.ParticipacionEvento, error) {
+	var participacion models.ParticipacionEvento
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&participacion, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrParticipacionNoEncontrada
+			}
+			return err
+		}
+		if participacion.EstaConfirmado() {
+			return nil
+		}
+		if participacion.StatusParticipacion != "registrado" {
+			return ErrParticipacionNoConfirmable
+		}
+
+		var evento models.Evento
+		if err := tx.First(&evento, participacion.IDEvento).Error; err != nil {
+			return err
+		}
+		if evento.YaInicio() {
+			return ErrEventoIniciado
+		}
+
+		participacion.Confirmar()
+		return tx.Model(&participacion).Updates(map[string]interface{}{
+			"status_participacion": participacion.StatusParticipacion,
+			"fecha_confirmacion":   participacion.FechaConfirmacion,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &participacion, nil
+}
+
+// Cancelar libera el lugar y promueve a la lista de espera dentro de la misma
+// transacción. Una vez que el evento empezó ya no se puede cancelar.
+func (s *ParticipacionService) Cancelar(id uint) (*models.ParticipacionEvento, []models.ParticipacionEvento, error) {
+	var participacion models.ParticipacionEvento
+	var promovidos []models.ParticipacionEvento
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&participacion, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrParticipacionNoEncontrada
+			}
+			return err
+		}
+
+		evento, err := bloquearEvento(tx, participacion.IDEvento)
+		if err != nil {
+			return err
+		}
+
+		// Se relee con el evento ya bloqueado por si otra petición la cambió.
+		if err := tx.First(&participacion, id).Error; err != nil {
+			return err
+		}
+		cancelable := (participacion.OcupaLugar() || participacion.EstaEnEspera()) && !participacion.Asistio()
+		if !cancelable {
+			return ErrParticipacionNoCancelable
+		}
+		// Cancelar después de empezar convertiría una inasistencia en
+		// cancelación.
+		if evento.YaInicio() {
+			return ErrEventoIniciado
+		}
+
+		liberaLugar := participacion.OcupaLugar()
+		participacion.Cancelar()
+		if err := tx.Model(&participacion).Update("status_participacion", participacion.StatusParticipacion).Error; err != nil {
+			return err
+		}
+
+		if liberaLugar && evento.AceptaRegistros() {
+			promovidos, err = promoverListaEspera(tx, evento)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notificarPromociones(s.db, s.mailer, promovidos)
+
+	return &participacion, promovidos, nil
+}
+
+// promoverListaEspera avanza la lista en orden de llegada y se detiene en el
+// primer registro que no cabe, para no saltarse a quien llegó antes.
+func promoverListaEspera(tx *gorm.DB, evento *models.Evento) ([]models.ParticipacionEvento, error) {
+	var espera []models.ParticipacionEvento
+	err := tx.Where("id_evento = ? AND status_participacion = ?", evento.IDEvento, "en_espera").
+		Order("fecha_registro ASC, id_participacion ASC").
+		Find(&espera).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ocupados, err := lugaresOcupados(tx, evento.IDEvento)
+	if err != nil {
+		return nil, err
+	}
+
+	var promovidos []models.ParticipacionEvento
+	for _, p := range espera {
+		if !evento.TieneCapacidadPara(ocupados, p.GetTotalPersonas()) {
+			break
+		}
+		p.StatusParticipacion = "registrado"
+		if err := tx.Model(&p).Update("status_participacion", p.StatusParticipacion).Error; err != nil {
+			return nil, err
+		}
+		ocupados += p.GetTotalPersonas()
+		promovidos = append(promovidos, p)
+	}
+
+	return promovidos, nil
+}
+
+// notificarPromociones avisa por correo a quienes salieron de la lista de
+// espera. Se llama después de la transacción; un error solo se registra.
+func notificarPromociones(db *gorm.DB, m mailer.Mailer, promovidos []models.ParticipacionEvento) {
+	for i := range promovidos {
+		notificarPromocion(db, m, &promovidos[i])
+	}
+}
+
+func notificarPromocion(db *gorm.DB, m mailer.Mailer, p *models.ParticipacionEvento) {
+	var persona models.Persona
+	var evento models.Evento
+	if err := db.First(&persona, p.IDPersona).Error; err != nil {
+		return
+	}
+	if err := db.First(&evento, p.IDEvento).Error; err != nil {
+		return
+	}
+	if persona.EmailPersonal == nil || *persona.EmailPersonal == "" {
+		return
+	}
+
+	err := m.Send(mailer.Message{
+		To:      *persona.EmailPersonal,
+		Subject: "Ya tienes lugar en " + evento.Titulo,
+		Body: fmt.Sprintf("Hola %s,\n\n"+
+			"Se liberó un lugar y tu registro a \"%s\" (%s) salió de la lista de espera.\n"+
+			"Confirma tu asistencia desde el sistema para conservar tu lugar.\n",
+			persona.Nombres, evento.Titulo, evento.FechaInicio.Format("02/01/2006 15:04")),
+	})
+	if err != nil {
+		log.Printf("Error notificando promoción de lista de espera a %s: %v", *persona.EmailPersonal, err)
+	}
+}
+
+func bloquearEvento(tx *gorm.DB, idEvento uint) (*models.Evento, error) {
+	var evento models.Evento
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&evento, idEvento).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEventoNoEncontrado
+		}
+		return nil, err
+	}
+	return &evento, nil
+}
+
+func lugaresOcupados(tx *gorm.DB, idEvento uint) (int, error) {
+	var total int64
+	err := tx.Model(&models.ParticipacionEvento{}).
+		Select("COALESCE(SUM(1 + acompaniantes), 0)").
+		Where("id_evento = ? AND status_participacion IN ?", idEvento, statusQueOcupanLugar).
+		Scan(&total).Error
+	return int(total), err
+}
+
+func hayListaEspera(tx *gorm.DB, idEvento uint) (bool, error) {
+	var total int64
+	err := tx.Model(&models.ParticipacionEvento{}).
+		Where("id_evento = ? AND status_participacion = ?", idEvento, "en_espera").
+		Count(&total).Error
+	return total > 0, err
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+)
+
+func TestCambiarParticipacionSegunEvento(t *testing.T) {
+	ahora := time.Now()
+	casos := []struct {
+		nombre string
+		status string
+		inicio time.Time
+		err    error
+	}{
+		{"evento próximo", "publicado", ahora.Add(48 * time.Hour), nil},
+		{"ya llegó la hora aunque siga publicado", "publicado", ahora.Add(-time.Hour), ErrEventoIniciado},
+		{"en curso", "en_curso", ahora.Add(-time.Hour), ErrEventoIniciado},
+		{"finalizado", "finalizado", ahora.Add(-72 * time.Hour), ErrEventoIniciado},
+	}
+
+	for _, c := range casos {
+		for _, operacion := range []string{"confirmar", "cancelar"} {
+			db, base := dbtest.Nueva(t)
+			base.Responder(`FROM "eventos"`, dbtest.Filas([]string{"id_evento", "status", "fecha_inicio", "capacidad_maxima"},
+				[]driver.Value{int64(3), c.status, c.inicio, int64(10)}))
+			base.Responder(`ORDER BY fecha_registro`, dbtest.Respuesta{})
+			base.Responder(`COALESCE\(SUM`, dbtest.Filas([]string{"total"}, []driver.Value{int64(1)}))
+			base.Responder(`FROM "participacion_eventos"`, dbtest.Filas(
+				[]string{"id_participacion", "id_evento", "id_persona", "status_participacion", "acompaniantes"},
+				[]driver.Value{int64(9), int64(3), int64(5), "registrado", int64(0)}))
+
+			s := NewParticipacionService(db, nil)
+			var err error
+			if operacion == "confirmar" {
+				_, err = s.Confirmar(9)
+			} else {
+				_, _, err = s.Cancelar(9)
+			}
+
+			if !errors.Is(err, c.err) {
+				t.Errorf("%s, %s: error = %v; se esperaba %v", c.nombre, operacion, err, c.err)
+			}
+			if cambio := base.Ejecutada(`UPDATE "participacion_eventos"`); cambio != (c.err == nil) {
+				t.Errorf("%s, %s: UPDATE ejecutado = %v", c.nombre, operacion, cambio)
+			}
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/geo"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/japones"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var (
+	ErrPersonaNoEncontrada   = errors.New("la persona no existe")
+	ErrPersonaConReferencias = errors.New("la persona tiene registros asociados (por ejemplo una empresa) y no puede eliminarse")
+)
+
+// PersonaInput contiene los campos editables de una persona. Las reglas de
+// validación reflejan los CHECK de la tabla personas.
+type PersonaInput struct {
+	IDFamilia               uint    `json:"id_familia" binding:"required"`
+	Nombres                 string  `json:"nombres" binding:"required,max=150"`
+	ApellidoPaterno         string  `json:"apellido_paterno" binding:"required,max=100"`
+	ApellidoMaterno         *string `json:"apellido_materno" binding:"omitempty,max=100"`
+	NombreJapones           *string `json:"nombre_japones" binding:"omitempty,max=150"`
+	NombreKanji             *string `json:"nombre_kanji" binding:"omitempty,max=150"`
+	Genero                  *string `json:"genero" binding:"omitempty,oneof=masculino femenino otro prefiero_no_decir"`
+	FechaNacimiento         *string `json:"fecha_nacimiento" binding:"omitempty,fecha"`
+	LugarNacimiento         *string `json:"lugar_nacimiento" binding:"omitempty,max=200"`
+	Generacion              string  `json:"generacion" binding:"required,oneof=issei nisei sansei yonsei gosei roksei"`
+	EstadoCivil             *string `json:"estado_civil" binding:"omitempty,oneof=soltero casado divorciado viudo union_libre"`
+	TelefonoPrincipal       *string `json:"telefono_principal" binding:"omitempty,max=20"`
+	TelefonoAlternativo     *string `json:"telefono_alternativo" binding:"omitempty,max=20"`
+	EmailPersonal           *string `json:"email_personal" binding:"omitempty,email,max=255"`
+	DireccionCompleta       *string `json:"direccion_completa"`
+	Ciudad                  *string `json:"ciudad" binding:"omitempty,max=100"`
+	Estado                  string  `json:"estado" binding:"max=100"`
+	CodigoPostal            *string `json:"codigo_postal" binding:"omitempty,max=10"`
+	FotoPerfil              *string `json:"foto_perfil" binding:"omitempty,max=500"`
+	EsMiembroActivo         bool    `json:"es_miembro_activo"`
+	FechaIngresoAsociacion  *string `json:"fecha_ingreso_asociacion" binding:"omitempty,fecha"`
+	NivelJapones            *string `json:"nivel_japones" binding:"omitempty,oneof=ninguno basico intermedio avanzado nativo"`
+	ParticipaEventos        *bool   `json:"participa_eventos"`
+	AceptaDirectorioPublico bool    `json:"acepta_directorio_publico"`
+	AceptaComunicaciones    *bool   `json:"acepta_comunicaciones"`
+	NotasAdministrativas    *string `json:"notas_administrativas"`
+	IDEmpresaEmpleadora     *uint   `json:"id_empresa_empleadora"`
+	Puesto                  *string `json:"puesto" binding:"omitempty,max=150"`
+}
+
+// CamposSoloAdmin no pueden cambiarse cuando una persona edita su propio
+// registro.
+var CamposSoloAdmin = []string{
+	"id_familia", "es_miembro_activo", "fecha_ingreso_asociacion", "notas_administrativas",
+}
+
+type PersonaFiltros struct {
+	IDFamilia       *uint   `form:"id_familia"`
+	Generacion      *string `form:"generacion" binding:"omitempty,oneof=issei nisei sansei yonsei gosei roksei"`
+	Ciudad          *string `form:"ciudad"`
+	EsMiembroActivo *bool   `form:"es_miembro_activo"`
+	NivelJapones    *string `form:"nivel_japones" binding:"omitempty,oneof=ninguno basico intermedio avanzado nativo"`
+	Q               string  `form:"q"`
+	Sort            string  `form:"sort"`
+	Page            int     `form:"page"`
+	PageSize        int     `form:"page_size"`
+
+	// RestringirPrivados lo activa el handler para quien no es admin: los
+	// filtros y el orden por datos de directorio solo consideran a quienes
+	// aceptaron aparecer, para no revelar por descarte la ciudad o la edad.
+	RestringirPrivados bool `form:"-"`
+}
+
+var personaSortCampos = map[string]string{
+	"id_persona":       "id_persona",
+	"nombres":          "nombres",
+	"apellido_paterno": "apellido_paterno",
+	"fecha_nacimiento": "fecha_nacimiento",
+	"generacion":       "generacion",
+	"ciudad":           "ciudad",
+	"created_at":       "created_at",
+}
+
+var personaSortCamposPublicos = map[string]string{
+	"id_persona":       "id_persona",
+	"nombres":          "nombres",
+	"apellido_paterno": "apellido_paterno",
+	"generacion":       "generacion",
+	"created_at":       "created_at",
+}
+
+type PersonaService struct {
+	db             *gorm.DB
+	geocodificador geo.Geocodificador
+}
+
+func NewPersonaService(db *gorm.DB, geocodificador geo.Geocodificador) *PersonaService {
+	return &PersonaService{db: db, geocodificador: geocodificador}
+}
+
+func (s *PersonaService) List(filtros PersonaFiltros) (*ListaPaginada[models.Persona], error) {
+	query := s.db.Model(&models.Persona{})
+
+	if filtros.IDFamilia != nil {
+		query = query.Where("id_familia = ?", *filtros.IDFamilia)
+	}
+	if filtros.Generacion != nil {
+		query = query.Where("generacion = ?", *filtros.Generacion)
+	}
+	if filtros.RestringirPrivados && ((filtros.Ciudad != nil && *filtros.Ciudad != "") || filtros.NivelJapones != nil) {
+		query = query.Where("acepta_directorio_publico = ?", true)
+	}
+	if filtros.Ciudad != nil && *filtros.Ciudad != "" {
+		query = query.Where("LOWER(ciudad) = LOWER(?)", strings.TrimSpace(*filtros.Ciudad))
+	}
+	if filtros.EsMiembroActivo != nil {
+		query = query.Where("es_miembro_activo = ?", *filtros.EsMiembroActivo)
+	}
+	if filtros.NivelJapones != nil {
+		query = query.Where("nivel_japones = ?", *filtros.NivelJapones)
+	}
+	if q := strings.TrimSpace(filtros.Q); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		clave := "%" + japones.Normalizar(q) + "%"
+		query = query.Where("LOWER(nombres || ' ' || apellido_paterno || ' ' || COALESCE(apellido_materno, '')) LIKE ? OR clave_busqueda LIKE ?", like, clave)
+	}
+
+	pag := nuevaPaginacion(filtros.Page, filtros.PageSize)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	pag.setTotal(total)
+
+	personas := []models.Persona{}
+	campos := personaSortCampos
+	if filtros.RestringirPrivados {
+		campos = personaSortCamposPublicos
+	}
+	orden := ordenSQL(filtros.Sort, campos, "apellido_paterno ASC, nombres ASC") + ", id_persona ASC"
+	if err := pag.aplicar(query.Order(orden)).Find(&personas).Error; err != nil {
+		return nil, err
+	}
+
+	return &ListaPaginada[models.Persona]{Items: personas, Pagination: pag}, nil
+}
+
+func (s *PersonaService) Get(id uint) (*models.Persona, error) {
+	var persona models.Persona
+	if err := s.db.First(&persona, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonaNoEncontrada
+		}
+		return nil, err
+	}
+	return &persona, nil
+}
+
+// GetVista devuelve la persona con los campos que el visor puede ver. Si no
+// puede verla en absoluto responde como si no existiera.
+func (s *PersonaService) GetVista(id uint, visor *models.User) (*PersonaVista, error) {
+	persona, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	vistas, err := s.Proyectar(visor, []models.Persona{*persona})
+	if err != nil {
+		return nil, err
+	}
+	if len(vistas) == 0 {
+		return nil, ErrPersonaNoEncontrada
+	}
+	return &vistas[0], nil
+}
+
+// Proyectar aplica ProyectarPersona con el nivel de acceso del visor sobre
+// cada persona; las que no puede ver se omiten.
+func (s *PersonaService) Proyectar(visor *models.User, personas []models.Persona) ([]PersonaVista, error) {
+	ids := make([]uint, len(personas))
+	for i, p := range personas {
+		ids[i] = p.IDPersona
+	}
+	niveles, err := nivelesAcceso(s.db, visor, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	vistas := make([]PersonaVista, 0, len(personas))
+	for i := range personas {
+		if v := ProyectarPersona(&personas[i], niveles[personas[i].IDPersona]); v != nil {
+			vistas = append(vistas, *v)
+		}
+	}
+	return vistas, nil
+}
+
+func (s *PersonaService) Create(input PersonaInput) (*models.Persona, error) {
+	var persona models.Persona
+	if err := s.aplicarInput(&persona, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&persona).Error; err != nil {
+		return nil, traducirErrorPersona(err)
+	}
+	return &persona, nil
+}
+
+// Update reemplaza todos los campos editables. Para PATCH el handler combina
+// primero el cuerpo con InputDesdePersona.
+func (s *PersonaService) Update(id uint, input PersonaInput) (*models.Persona, error) {
+	persona, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.aplicarInput(persona, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(persona).Error; err != nil {
+		return nil, traducirErrorPersona(err)
+	}
+	return persona, nil
+}
+
+// Delete solo borra personas sin registros asociados. Las referencias se
+// cuentan antes de borrar porque no todas las bases tienen las foreign keys.
+func (s *PersonaService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var persona models.Persona
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&persona, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPersonaNoEncontrada
+			}
+			return err
+		}
+
+		referenciada, err := personaReferenciada(tx, id)
+		if err != nil {
+			return err
+		}
+		if referenciada {
+			return ErrPersonaConReferencias
+		}
+
+		if err := tx.Delete(&persona).Error; err != nil {
+			if esViolacionFK(err) {
+				return ErrPersonaConReferencias
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// referenciasPersona son las tablas que apuntan a una persona y la condición
+// con la que se buscan.
+var referenciasPersona = []struct {
+	modelo    any
+	condicion string
+}{
+	{&models.User{}, "id_persona = @id"},
+	{&models.Empresa{}, "id_propietario = @id"},
+	{&models.ParticipacionEvento{}, "id_persona = @id"},
+	{&models.Genealogia{}, "id_persona = @id OR id_pariente = @id OR id_persona_solicitante = @id"},
+}
+
+func personaReferenciada(tx *gorm.DB, id uint) (bool, error) {
+	for _, r := range referenciasPersona {
+		var total int64
+		if err := tx.Model(r.modelo).Where(r.condicion, sql.Named("id", id)).Count(&total).Error; err != nil {
+			return false, err
+		}
+		if total > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// InputDesdePersona devuelve el estado actual como input, para que un PATCH
+// solo sobrescriba los campos que vengan en el cuerpo.
+func InputDesdePersona(p *models.Persona) PersonaInput {
+	return PersonaInput{
+		IDFamilia:               p.IDFamilia,
+		Nombres:                 p.Nombres,
+		ApellidoPaterno:         p.ApellidoPaterno,
+		ApellidoMaterno:         p.ApellidoMaterno,
+		NombreJapones:           p.NombreJapones,
+		NombreKanji:             p.NombreKanji,
+		Genero:                  p.Genero,
+		FechaNacimiento:         utils.FormatFecha(p.FechaNacimiento),
+		LugarNacimiento:         p.LugarNacimiento,
+		Generacion:              p.Generacion,
+		EstadoCivil:             p.EstadoCivil,
+		TelefonoPrincipal:       p.TelefonoPrincipal,
+		TelefonoAlternativo:     p.TelefonoAlternativo,
+		EmailPersonal:           p.EmailPersonal,
+		DireccionCompleta:       p.DireccionCompleta,
+		Ciudad:                  p.Ciudad,
+		Estado:                  p.Estado,
+		CodigoPostal:            p.CodigoPostal,
+		FotoPerfil:              p.FotoPerfil,
+		EsMiembroActivo:         p.EsMiembroActivo,
+		FechaIngresoAsociacion:  utils.FormatFecha(p.FechaIngresoAsociacion),
+		NivelJapones:            p.NivelJapones,
+		ParticipaEventos:        &p.ParticipaEventos,
+		AceptaDirectorioPublico: p.AceptaDirectorioPublico,
+		AceptaComunicaciones:    &p.AceptaComunicaciones,
+		NotasAdministrativas:    p.NotasAdministrativas,
+		IDEmpresaEmpleadora:     p.IDEmpresaEmpleadora,
+		Puesto:                  p.Puesto,
+	}
+}
+
+func (s *PersonaService) aplicarInput(p *models.Persona, in PersonaInput) error {
+	fields := utils.FieldErrors{}
+
+	var familias int64
+	if err := s.db.Model(&models.Familia{}).Where("id_familia = ?", in.IDFamilia).Count(&familias).Error; err != nil {
+		return err
+	}
+	if familias == 0 {
+		fields["id_familia"] = "la familia no existe"
+	}
+
+	if in.IDEmpresaEmpleadora != nil {
+		var empresas int64
+		err := s.db.Model(&models.EmpresaEmpleadora{}).
+			Where("id_empresa_empleadora = ?", *in.IDEmpresaEmpleadora).
+			Count(&empresas).Error
+		if err != nil {
+			return err
+		}
+		if empresas == 0 {
+			fields["id_empresa_empleadora"] = "la empresa empleadora no existe"
+		}
+	}
+
+	fechaNacimiento, _ := utils.ParseFecha(in.FechaNacimiento)
+	if fechaNacimiento != nil && fechaNacimiento.After(time.Now()) {
+		fields["fecha_nacimiento"] = "no puede ser una fecha futura"
+	}
+	fechaIngreso, _ := utils.ParseFecha(in.FechaIngresoAsociacion)
+
+	if len(fields) > 0 {
+		return fields
+	}
+
+	p.IDFamilia = in.IDFamilia
+	p.Nombres = strings.TrimSpace(in.Nombres)
+	p.ApellidoPaterno = strings.TrimSpace(in.ApellidoPaterno)
+	p.ApellidoMaterno = in.ApellidoMaterno
+	p.NombreJapones = in.NombreJapones
+	p.NombreKanji = in.NombreKanji
+	p.Genero = in.Genero
+	p.FechaNacimiento = fechaNacimiento
+	p.LugarNacimiento = in.LugarNacimiento
+	p.Generacion = in.Generacion
+	p.EstadoCivil = in.EstadoCivil
+	p.TelefonoPrincipal = in.TelefonoPrincipal
+	p.TelefonoAlternativo = in.TelefonoAlternativo
+	p.EmailPersonal = in.EmailPersonal
+	p.DireccionCompleta = in.DireccionCompleta
+	p.Ciudad = in.Ciudad
+	p.Estado = in.Estado
+	if p.Estado == "" {
+		p.Estado = "Sinaloa"
+	}
+	p.CodigoPostal = in.CodigoPostal
+	p.Latitud, p.Longitud = ubicarMunicipio(s.geocodificador, p.Ciudad, p.Estado)
+	p.FotoPerfil = in.FotoPerfil
+	p.EsMiembroActivo = in.EsMiembroActivo
+	p.FechaIngresoAsociacion = fechaIngreso
+	p.NivelJapones = in.NivelJapones
+	p.ParticipaEventos = in.ParticipaEventos == nil || *in.ParticipaEventos
+	p.AceptaDirectorioPublico = in.AceptaDirectorioPublico
+	p.AceptaComunicaciones = in.AceptaComunicaciones == nil || *in.AceptaComunicaciones
+	p.NotasAdministrativas = in.NotasAdministrativas
+	p.IDEmpresaEmpleadora = in.IDEmpresaEmpleadora
+	p.Puesto = in.Puesto
+	return nil
+}
+
+// traducirErrorPersona convierte las violaciones de CHECK (chk_personas_<campo>)
+// en errores por campo por si la validación previa no las detectó.
+func traducirErrorPersona(err error) error {
+	if pgErr := pgError(err); pgErr != nil {
+		switch pgErr.Code {
+		case pgCheckViolation:
+			campo := strings.TrimPrefix(pgErr.ConstraintName, "chk_personas_")
+			return utils.FieldErrors{campo: "valor no permitido"}
+		case pgForeignKeyViolation:
+			return utils.FieldErrors{"id_familia": "hace referencia a un registro inexistente"}
+		}
+	}
+	return err
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+// NivelAcceso ordena quién mira a una persona, de menos a más confianza.
+type NivelAcceso int
+
+const (
+	AccesoPublico NivelAcceso = iota
+	AccesoMiembro
+	AccesoFamiliar
+	AccesoPropio
+	AccesoAdmin
+)
+
+func (n NivelAcceso) String() string {
+	switch n {
+	case AccesoMiembro:
+		return "miembro"
+	case AccesoFamiliar:
+		return "familiar"
+	case AccesoPropio:
+		return "propio"
+	case AccesoAdmin:
+		return "admin"
+	}
+	return "publico"
+}
+
+// PersonaVista es lo que se serializa de una persona. Los campos que el nivel
+// de acceso no permite ver quedan en nil y no aparecen en el JSON.
+type PersonaVista struct {
+	IDPersona               uint       `json:"id_persona"`
+	IDFamilia               uint       `json:"id_familia"`
+	Nombres                 string     `json:"nombres"`
+	ApellidoPaterno         string     `json:"apellido_paterno"`
+	ApellidoMaterno         *string    `json:"apellido_materno,omitempty"`
+	NombreJapones           *string    `json:"nombre_japones,omitempty"`
+	NombreKanji             *string    `json:"nombre_kanji,omitempty"`
+	Generacion              string     `json:"generacion"`
+	Genero                  *string    `json:"genero,omitempty"`
+	FechaNacimiento         *time.Time `json:"fecha_nacimiento,omitempty"`
+	AnioNacimiento          *int       `json:"anio_nacimiento,omitempty"`
+	LugarNacimiento         *string    `json:"lugar_nacimiento,omitempty"`
+	EstadoCivil             *string    `json:"estado_civil,omitempty"`
+	TelefonoPrincipal       *string    `json:"telefono_principal,omitempty"`
+	TelefonoAlternativo     *string    `json:"telefono_alternativo,omitempty"`
+	EmailPersonal           *string    `json:"email_personal,omitempty"`
+	DireccionCompleta       *string    `json:"direccion_completa,omitempty"`
+	Ciudad                  *string    `json:"ciudad,omitempty"`
+	Estado                  *string    `json:"estado,omitempty"`
+	CodigoPostal            *string    `json:"codigo_postal,omitempty"`
+	Latitud                 *float64   `json:"latitud,omitempty"`
+	Longitud                *float64   `json:"longitud,omitempty"`
+	FotoPerfil              *string    `json:"foto_perfil,omitempty"`
+	EsMiembroActivo         *bool      `json:"es_miembro_activo,omitempty"`
+	FechaIngresoAsociacion  *time.Time `json:"fecha_ingreso_asociacion,omitempty"`
+	NivelJapones            *string    `json:"nivel_japones,omitempty"`
+	ParticipaEventos        *bool      `json:"participa_eventos,omitempty"`
+	AceptaDirectorioPublico *bool      `json:"acepta_directorio_publico,omitempty"`
+	AceptaComunicaciones    *bool      `json:"acepta_comunicaciones,omitempty"`
+	NotasAdministrativas    *string    `json:"notas_administrativas,omitempty"`
+	IDEmpresaEmpleadora     *uint      `json:"id_empresa_empleadora,omitempty"`
+	Puesto                  *string    `json:"puesto,omitempty"`
+	CreatedAt               *time.Time `json:"created_at,omitempty"`
+	UpdatedAt               *time.Time `json:"updated_at,omitempty"`
+	Acceso                  string     `json:"acceso"`
+}
+
+// ProyectarPersona aplica las reglas de privacidad:
+//   - público: solo quien aceptó el directorio, con nombre, generación y ciudad.
+//   - miembro: el nombre de todos; el resto del perfil de directorio (sin
+//     teléfonos) solo si la persona lo aceptó.
+//   - familiar con vínculo confirmado: además fecha y lugar de nacimiento,
+//     estado civil y teléfonos, haya aceptado o no el directorio.
+//   - la propia persona: todo menos las notas administrativas.
+//   - admin: todo.
+//
+// La dirección, el código postal y las coordenadas nunca salen de la persona y los admins.
+// Devuelve nil si el nivel no alcanza para ver a la persona.
+func ProyectarPersona(p *models.Persona, nivel NivelAcceso) *PersonaVista {
+	directorio := p.AceptaDirectorioPublico
+	if nivel == AccesoPublico && !directorio {
+		return nil
+	}
+
+	v := &PersonaVista{
+		IDPersona:       p.IDPersona,
+		IDFamilia:       p.IDFamilia,
+		Nombres:         p.Nombres,
+		ApellidoPaterno: p.ApellidoPaterno,
+		ApellidoMaterno: p.ApellidoMaterno,
+		NombreJapones:   p.NombreJapones,
+		NombreKanji:     p.NombreKanji,
+		Generacion:      p.Generacion,
+		Acceso:          nivel.String(),
+	}
+
+	if directorio || nivel >= AccesoFamiliar {
+		v.FotoPerfil = p.FotoPerfil
+		v.Ciudad = p.Ciudad
+		v.Estado = &p.Estado
+	}
+	if nivel == AccesoPublico {
+		return v
+	}
+
+	v.EsMiembroActivo = &p.EsMiembroActivo
+	if directorio || nivel >= AccesoFamiliar {
+		v.Genero = p.Genero
+		v.NivelJapones = p.NivelJapones
+		v.Puesto = p.Puesto
+		v.IDEmpresaEmpleadora = p.IDEmpresaEmpleadora
+		v.EmailPersonal = p.EmailPersonal
+		if p.FechaNacimiento != nil {
+			anio := p.FechaNacimiento.Year()
+			v.AnioNacimiento = &anio
+		}
+	}
+	if nivel < AccesoFamiliar {
+		return v
+	}
+
+	v.FechaNacimiento = p.FechaNacimiento
+	v.LugarNacimiento = p.LugarNacimiento
+	v.EstadoCivil = p.EstadoCivil
+	v.TelefonoPrincipal = p.TelefonoPrincipal
+	v.TelefonoAlternativo = p.TelefonoAlternativo
+	if nivel < AccesoPropio {
+		return v
+	}
+
+	v.DireccionCompleta = p.DireccionCompleta
+	v.CodigoPostal = p.CodigoPostal
+	v.Latitud = p.Latitud
+	v.Longitud = p.Longitud
+	v.FechaIngresoAsociacion = p.FechaIngresoAsociacion
+	v.ParticipaEventos = &p.ParticipaEventos
+	v.AceptaDirectorioPublico = &p.AceptaDirectorioPublico
+	v.AceptaComunicaciones = &p.AceptaComunicaciones
+	v.CreatedAt = &p.CreatedAt
+	v.UpdatedAt = &p.UpdatedAt
+	if nivel == AccesoAdmin {
+		v.NotasAdministrativas = p.NotasAdministrativas
+	}
+	return v
+}
+
+// nivelesAcceso calcula con una sola consulta el nivel del visor sobre cada
+// persona. Un usuario sin sesión o pendiente solo tiene acceso público, salvo
+// a su propio registro.
+func nivelesAcceso(db *gorm.DB, visor *models.User, ids []uint) (map[uint]NivelAcceso, error) {
+	niveles := make(map[uint]NivelAcceso, len(ids))
+	base := AccesoPublico
+	switch {
+	case visor != nil && visor.EsAdmin():
+		base = AccesoAdmin
+	case visor != nil && visor.EsMiembro():
+		base = AccesoMiembro
+	}
+	for _, id := range ids {
+		niveles[id] = base
+	}
+	if base == AccesoAdmin || visor == nil || visor.IDPersona == nil {
+		return niveles, nil
+	}
+
+	propia := *visor.IDPersona
+	if base == AccesoMiembro && len(ids) > 0 {
+		var familiares []uint
+		err := db.Model(&models.Genealogia{}).
+			Where("id_persona = ? AND id_pariente IN ? AND confirmado_ambas_partes = ?", propia, ids, true).
+			Distinct().Pluck("id_pariente", &familiares).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range familiares {
+			niveles[id] = AccesoFamiliar
+		}
+	}
+	if _, ok := niveles[propia]; ok {
+		niveles[propia] = AccesoPropio
+	}
+	return niveles, nil
+}
+
+// vistasPorID carga las personas y las proyecta con el nivel del visor; las
+// que no puede ver no aparecen en el mapa.
+func vistasPorID(db *gorm.DB, visor *models.User, ids []uint) (map[uint]*PersonaVista, error) {
+	personas, err := cargarPersonasPorID(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	niveles, err := nivelesAcceso(db, visor, ids)
+	if err != nil {
+		return nil, err
+	}
+	vistas := make(map[uint]*PersonaVista, len(personas))
+	for id, p := range personas {
+		if v := ProyectarPersona(p, niveles[id]); v != nil {
+			vistas[id] = v
+		}
+	}
+	return vistas, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/dbtest"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+)
+
+// camposVigilados son los campos de la persona de prueba cuyo acceso cambia
+// con el nivel del visor.
+var camposVigilados = []string{
+	"ciudad", "puesto", "lugar_nacimiento", "telefono_principal",
+	"direccion_completa", "codigo_postal", "latitud", "longitud", "notas_administrativas",
+}
+
+func personaCompleta(consiente bool) *models.Persona {
+	texto := func(s string) *string { return &s }
+	coord := func(f float64) *float64 { return &f }
+	nacimiento := time.Date(1950, 4, 1, 0, 0, 0, 0, time.UTC)
+	return &models.Persona{
+		IDPersona:               1,
+		IDFamilia:               1,
+		Nombres:                 "Taro",
+		ApellidoPaterno:         "Tanaka",
+		Generacion:              "nisei",
+		FechaNacimiento:         &nacimiento,
+		LugarNacimiento:         texto("Culiacán"),
+		TelefonoPrincipal:       texto("6671234567"),
+		DireccionCompleta:       texto("Av. Obregón 100"),
+		Ciudad:                  texto("Culiacán"),
+		Estado:                  "Sinaloa",
+		CodigoPostal:            texto("80000"),
+		Latitud:                 coord(24.8),
+		Longitud:                coord(-107.4),
+		Puesto:                  texto("Contador"),
+		NotasAdministrativas:    texto("cuota pendiente"),
+		AceptaDirectorioPublico: consiente,
+	}
+}
+
+func TestProyectarPersonaPorNivel(t *testing.T) {
+	id := func(n uint) *uint { return &n }
+	miembro := &models.User{Role: "miembro", IDPersona: id(8)}
+
+	casos := []struct {
+		nombre    string
+		visor     *models.User
+		familiar  bool
+		consiente bool
+		nivel     NivelAcceso
+		visibles  []string // nil si la persona no debe aparecer
+	}{
+		{"público sin consentimiento", nil, false, false, AccesoPublico, nil},
+		{"público con consentimiento", nil, false, true, AccesoPublico, []string{"ciudad"}},
+		{"pendiente con consentimiento", &models.User{Role: "pendiente"}, false, true, AccesoPublico, []string{"ciudad"}},
+		{"miembro sin consentimiento", miembro, false, false, AccesoMiembro, []string{}},
+		{"miembro con consentimiento", miembro, false, true, AccesoMiembro, []string{"ciudad", "puesto"}},
+		{"familiar sin consentimiento", miembro, true, false, AccesoFamiliar,
+			[]string{"ciudad", "puesto", "lugar_nacimiento", "telefono_principal"}},
+		{"familiar con consentimiento", miembro, true, true, AccesoFamiliar,
+			[]string{"ciudad", "puesto", "lugar_nacimiento", "telefono_principal"}},
+		{"propio sin consentimiento", &models.User{Role: "miembro", IDPersona: id(1)}, false, false, AccesoPropio,
+			[]string{"ciudad", "puesto", "lugar_nacimiento", "telefono_principal", "direccion_completa", "codigo_postal", "latitud", "longitud"}},
+		{"propio con consentimiento", &models.User{Role: "miembro", IDPersona: id(1)}, false, true, AccesoPropio,
+			[]string{"ciudad", "puesto", "lugar_nacimiento", "telefono_principal", "direccion_completa", "codigo_postal", "latitud", "longitud"}},
+		{"admin sin consentimiento", &models.User{Role: "admin"}, false, false, AccesoAdmin, camposVigilados},
+		{"admin con consentimiento", &models.User{Role: "admin"}, false, true, AccesoAdmin, camposVigilados},
+	}
+
+	for _, c := range casos {
+		db, base := dbtest.Nueva(t)
+		if c.familiar {
+			base.Responder(`FROM "genealogia"`, dbtest.Filas([]string{"id_pariente"}, []driver.Value{int64(1)}))
+		}
+
+		niveles, err := nivelesAcceso(db, c.visor, []uint{1})
+		if err != nil {
+			t.Fatalf("%s: %v", c.nombre, err)
+		}
+		if niveles[1] != c.nivel {
+			t.Errorf("%s: nivel = %s; se esperaba %s", c.nombre, niveles[1], c.nivel)
+		}
+
+		vista := ProyectarPersona(personaCompleta(c.consiente), c.nivel)
+		if c.visibles == nil {
+			if vista != nil {
+				t.Errorf("%s: se esperaba que la persona no fuera visible", c.nombre)
+			}
+			continue
+		}
+		if vista == nil {
+			t.Errorf("%s: se esperaba ver a la persona", c.nombre)
+			continue
+		}
+
+		cuerpo, _ := json.Marshal(vista)
+		var campos map[string]any
+		if err := json.Unmarshal(cuerpo, &campos); err != nil {
+			t.Fatal(err)
+		}
+		permitidos := make(map[string]bool, len(c.visibles))
+		for _, campo := range c.visibles {
+			permitidos[campo] = true
+		}
+		for _, campo := range camposVigilados {
+			if _, ok := campos[campo]; ok != permitidos[campo] {
+				t.Errorf("%s: %s visible = %v; se esperaba %v", c.nombre, campo, ok, permitidos[campo])
+			}
+		}
+		if campos["nombres"] != "Taro" {
+			t.Errorf("%s: nombres = %v; se esperaba Taro", c.nombre, campos["nombres"])
+		}
+	}
+}
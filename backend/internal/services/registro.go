@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+var (
+	ErrEmailRegistrado       = errors.New("ya existe una cuenta con ese correo")
+	ErrFamiliaNoEncontrada   = errors.New("la familia indicada no existe")
+	ErrSolicitudNoEncontrada = errors.New("no existe una solicitud pendiente con ese id")
+)
+
+type RegistroService struct {
+	db *gorm.DB
+}
+
+type RegistroInput struct {
+	Email           string
+	Password        string
+	IDFamilia       uint
+	Nombres         string
+	ApellidoPaterno string
+	ApellidoMaterno *string
+	Generacion      string
+	FechaNacimiento *time.Time
+	Telefono        *string
+	Ciudad          *string
+}
+
+type SolicitudPendiente struct {
+	IDUser         uint            `json:"id_user"`
+	Email          string          `json:"email"`
+	EmailVerified  bool            `json:"email_verified"`
+	FechaSolicitud time.Time       `json:"fecha_solicitud"`
+	Persona        *models.Persona `json:"persona"`
+	Familia        *models.Familia `json:"familia"`
+}
+
+func NewRegistroService(db *gorm.DB) *RegistroService {
+	return &RegistroService{db: db}
+}
+
+// Registrar crea la cuenta en estado pendiente junto con el borrador de su
+// persona. La persona no cuenta como miembro hasta que un admin la apruebe.
+func (s *RegistroService) Registrar(input RegistroInput) (*models.User, error) {
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+
+	hash, err := utils.HashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var existentes int64
+		if err := tx.Model(&models.User{}).Where("LOWER(email) = ?", email).Count(&existentes).Error; err != nil {
+			return err
+		}
+		if existentes > 0 {
+			return ErrEmailRegistrado
+		}
+
+		var familia models.Familia
+		if err := tx.First(&familia, input.IDFamilia).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFamiliaNoEncontrada
+			}
+			return err
+		}
+
+		persona := models.Persona{
+			IDFamilia:         familia.IDFamilia,
+			Nombres:           strings.TrimSpace(input.Nombres),
+			ApellidoPaterno:   strings.TrimSpace(input.ApellidoPaterno),
+			ApellidoMaterno:   input.ApellidoMaterno,
+			Generacion:        input.Generacion,
+			FechaNacimiento:   input.FechaNacimiento,
+			TelefonoPrincipal: input.Telefono,
+			EmailPersonal:     &email,
+			Ciudad:            input.Ciudad,
+			EsMiembroActivo:   false,
+		}
+		if err := tx.Create(&persona).Error; err != nil {
+			return err
+		}
+
+		user = models.User{
+			Email:        email,
+			PasswordHash: hash,
+			Role:         "pendiente",
+			IsActive:     true,
+			IDPersona:    &persona.IDPersona,
+		}
+		// El conteo de arriba no evita que dos registros simultáneos pasen;
+		// el índice único decide y el perdedor recibe el mismo error.
+		if err := tx.Create(&user).Error; err != nil {
+			if esViolacionUnica(err, "idx_users_email") {
+				return ErrEmailRegistrado
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *RegistroService) ListarPendientes() ([]SolicitudPendiente, error) {
+	var users []models.User
+	err := s.db.Where("role = ? AND is_active = ?", "pendiente", true).
+		Order("created_at ASC").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	personaIDs := make([]uint, 0, len(users))
+	for _, u := range users {
+		if u.IDPersona != nil {
+			personaIDs = append(personaIDs, *u.IDPersona)
+		}
+	}
+
+	personas := make(map[uint]*models.Persona)
+	familias := make(map[uint]*models.Familia)
+	if len(personaIDs) > 0 {
+		var lista []models.Persona
+		if err := s.db.Where("id_persona IN ?", personaIDs).Find(&lista).Error; err != nil {
+			return nil, err
+		}
+
+		familiaIDs := make([]uint, 0, len(lista))
+		for i := range lista {
+			personas[lista[i].IDPersona] = &lista[i]
+			familiaIDs = append(familiaIDs, lista[i].IDFamilia)
+		}
+
+		var listaFamilias []models.Familia
+		if err := s.db.Where("id_familia IN ?", familiaIDs).Find(&listaFamilias).Error; err != nil {
+			return nil, err
+		}
+		for i := range listaFamilias {
+			familias[listaFamilias[i].IDFamilia] = &listaFamilias[i]
+		}
+	}
+
+	solicitudes := make([]SolicitudPendiente, 0, len(users))
+	for _, u := range users {
+		solicitud := SolicitudPendiente{
+			IDUser:         u.IDUser,
+			Email:          u.Email,
+			EmailVerified:  u.EmailVerified,
+			FechaSolicitud: u.CreatedAt,
+		}
+		if u.IDPersona != nil {
+			if persona, ok := personas[*u.IDPersona]; ok {
+				solicitud.Persona = persona
+				solicitud.Familia = familias[persona.IDFamilia]
+			}
+		}
+		solicitudes = append(solicitudes, solicitud)
+	}
+
+	return solicitudes, nil
+}
+
+func (s *RegistroService) Aprobar(idUser uint) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.cargarPendiente(tx, idUser, &user); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&user).Update("role", "miembro").Error; err != nil {
+			return err
+		}
+
+		if user.IDPersona != nil {
+			hoy := time.Now()
+			err := tx.Model(&models.Persona{}).
+				Where("id_persona = ?", *user.IDPersona).
+				Updates(map[string]interface{}{
+					"es_miembro_activo":        true,
+					"fecha_ingreso_asociacion": hoy,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// Rechazar desactiva la cuenta en lugar de borrarla para conservar el
+// registro de la solicitud; la persona en borrador se mantiene inactiva.
+func (s *RegistroService) Rechazar(idUser uint) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.cargarPendiente(tx, idUser, &user); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Sesion{}).
+			Where("id_user = ? AND revocada_en IS NULL", user.IDUser).
+			Update("revocada_en", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *RegistroService) cargarPendiente(tx *gorm.DB, idUser uint, user *models.User) error {
+	err := tx.Where("id_user = ? AND role = ? AND is_active = ?", idUser, "pendiente", true).First(user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSolicitudNoEncontrada
+	}
+	return err
+}
@@ -0,0 +1,524 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/models"
+	"github.com/JuanvlzqzTec/nikkei-sistema/backend/internal/utils"
+)
+
+const sinDato = "sin_dato"
+
+var (
+	generaciones   = []string{"issei", "nisei", "sansei", "yonsei", "gosei", "roksei"}
+	nivelesJapones = []string{"ninguno", "basico", "intermedio", "avanzado", "nativo"}
+)
+
+// bandaEdad es [desde, hasta) en años cumplidos; hasta 0 es sin límite. La
+// última banda coincide con Persona.EsAdultoMayor.
+type bandaEdad struct {
+	nombre       string
+	desde, hasta int
+}
+
+var bandasEdad = []bandaEdad{
+	{"0-17", 0, 18},
+	{"18-29", 18, 30},
+	{"30-44", 30, 45},
+	{"45-64", 45, 65},
+	{"adulto_mayor", 65, 0},
+}
+
+type ReporteFiltros struct {
+	Estado      *string `form:"estado"`
+	Ciudad      *string `form:"ciudad"`
+	SoloActivos bool    `form:"solo_activos"`
+	Agrupacion  string  `form:"agrupacion" binding:"omitempty,oneof=anio mes"`
+}
+
+// ConteoCategoria es una fila de un desglose. Porcentaje es sobre el total
+// del reporte.
+type ConteoCategoria struct {
+	Categoria  string  `json:"categoria"`
+	Total      int64   `json:"total"`
+	Activos    int64   `json:"activos"`
+	Porcentaje float64 `json:"porcentaje"`
+}
+
+type ConteoCiudad struct {
+	Ciudad     string  `json:"ciudad"`
+	Estado     string  `json:"estado"`
+	Total      int64   `json:"total"`
+	Activos    int64   `json:"activos"`
+	Porcentaje float64 `json:"porcentaje"`
+}
+
+// PuntoCrecimiento cuenta los ingresos a la asociación en un periodo
+// ("2024" o "2024-03") y el acumulado hasta ese periodo.
+type PuntoCrecimiento struct {
+	Periodo   string `json:"periodo"`
+	Nuevos    int64  `json:"nuevos"`
+	Acumulado int64  `json:"acumulado"`
+}
+
+type ReporteDemografico struct {
+	GeneradoEn      time.Time          `json:"generado_en"`
+	Total           int64              `json:"total"`
+	Activos         int64              `json:"activos"`
+	TasaActivos     float64            `json:"tasa_activos"`
+	PorGeneracion   []ConteoCategoria  `json:"por_generacion"`
+	PorEstado       []ConteoCategoria  `json:"por_estado"`
+	PorCiudad       []ConteoCiudad     `json:"por_ciudad"`
+	PorEdad         []ConteoCategoria  `json:"por_edad"`
+	PorNivelJapones []ConteoCategoria  `json:"por_nivel_japones"`
+	SinFechaIngreso int64              `json:"sin_fecha_ingreso"`
+	Crecimiento     []PuntoCrecimiento `json:"crecimiento"`
+}
+
+type ReporteService struct {
+	db *gorm.DB
+}
+
+func NewReporteService(db *gorm.DB) *ReporteService {
+	return &ReporteService{db: db}
+}
+
+// conteoFila es el resultado de los GROUP BY de este archivo.
+type conteoFila struct {
+	Categoria string
+	Ciudad    string
+	Total     int64
+	Activos   int64
+}
+
+// personas arma una consulta nueva con los filtros del reporte; cada
+// desglose necesita la suya.
+func (s *ReporteService) personas(filtros ReporteFiltros) *gorm.DB {
+	query := s.db.Model(&models.Persona{})
+	if filtros.Estado != nil && *filtros.Estado != "" {
+		query = query.Where("LOWER(estado) = LOWER(?)", strings.TrimSpace(*filtros.Estado))
+	}
+	if filtros.Ciudad != nil && *filtros.Ciudad != "" {
+		query = query.Where("LOWER(ciudad) = LOWER(?)", strings.TrimSpace(*filtros.Ciudad))
+	}
+	if filtros.SoloActivos {
+		query = query.Where("es_miembro_activo = ?", true)
+	}
+	return query
+}
+
+// Demografico calcula los desgloses de personas. La edad se mide a la fecha
+// ahora para que el reporte sea reproducible.
+func (s *ReporteService) Demografico(filtros ReporteFiltros, ahora time.Time) (*ReporteDemografico, error) {
+	reporte := &ReporteDemografico{GeneradoEn: ahora}
+
+	var totales conteoFila
+	err := s.personas(filtros).
+		Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE es_miembro_activo) AS activos").
+		Scan(&totales).Error
+	if err != nil {
+		return nil, err
+	}
+	reporte.Total = totales.Total
+	reporte.Activos = totales.Activos
+	reporte.TasaActivos = porcentaje(totales.Activos, totales.Total)
+
+	if reporte.PorGeneracion, err = s.desglose(filtros, "generacion", generaciones, reporte.Total); err != nil {
+		return nil, err
+	}
+	if reporte.PorNivelJapones, err = s.desglose(filtros, "nivel_japones", nivelesJapones, reporte.Total); err != nil {
+		return nil, err
+	}
+	if reporte.PorEstado, err = s.desglose(filtros, "NULLIF(TRIM(estado), '')", nil, reporte.Total); err != nil {
+		return nil, err
+	}
+	if reporte.PorEdad, err = s.porEdad(filtros, ahora, reporte.Total); err != nil {
+		return nil, err
+	}
+	if reporte.PorCiudad, err = s.porCiudad(filtros, reporte.Total); err != nil {
+		return nil, err
+	}
+	if reporte.Crecimiento, reporte.SinFechaIngreso, err = s.crecimiento(filtros); err != nil {
+		return nil, err
+	}
+	return reporte, nil
+}
+
+// desglose agrupa por una expresión SQL fija. Con orden, las categorías
+// salen en ese orden y aparecen aunque no tengan personas; sin él, de mayor a
+// menor. Los nulos se cuentan como sin_dato al final.
+func (s *ReporteService) desglose(filtros ReporteFiltros, expresion string, orden []string, total int64) ([]ConteoCategoria, error) {
+	var filas []conteoFila
+	err := s.personas(filtros).
+		Select(fmt.Sprintf("COALESCE(%s, '%s') AS categoria, COUNT(*) AS total, COUNT(*) FILTER (WHERE es_miembro_activo) AS activos", expresion, sinDato)).
+		Group("categoria").
+		Order("total DESC, categoria ASC").
+		Scan(&filas).Error
+	if err != nil {
+		return nil, err
+	}
+	return ordenarConteos(filas, orden, total), nil
+}
+
+func (s *ReporteService) porEdad(filtros ReporteFiltros, ahora time.Time, total int64) ([]ConteoCategoria, error) {
+	var casos strings.Builder
+	var args []any
+	casos.WriteString("CASE WHEN fecha_nacimiento IS NULL THEN '" + sinDato + "'")
+	nombres := make([]string, len(bandasEdad))
+	for i, b := range bandasEdad {
+		nombres[i] = b.nombre
+		if b.hasta == 0 {
+			casos.WriteString(fmt.Sprintf(" ELSE '%s' END", b.nombre))
+			break
+		}
+		casos.WriteString(fmt.Sprintf(" WHEN DATE_PART('year', AGE(?::date, fecha_nacimiento)) < %d THEN '%s'", b.hasta, b.nombre))
+		args = append(args, ahora.Format(utils.FechaLayout))
+	}
+
+	var filas []conteoFila
+	err := s.personas(filtros).
+		Select(casos.String()+" AS categoria, COUNT(*) AS total, COUNT(*) FILTER (WHERE es_miembro_activo) AS activos", args...).
+		Group("categoria").
+		Scan(&filas).Error
+	if err != nil {
+		return nil, err
+	}
+	return ordenarConteos(filas, nombres, total), nil
+}
+
+func (s *ReporteService) porCiudad(filtros ReporteFiltros, total int64) ([]ConteoCiudad, error) {
+	var filas []conteoFila
+	err := s.personas(filtros).
+		Select("COALESCE(NULLIF(TRIM(ciudad), ''), '" + sinDato + "') AS ciudad, estado AS categoria, " +
+			"COUNT(*) AS total, COUNT(*) FILTER (WHERE es_miembro_activo) AS activos").
+		Group("1, 2").
+		Order("total DESC, ciudad ASC").
+		Scan(&filas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ciudades := make([]ConteoCiudad, len(filas))
+	for i, f := range filas {
+		ciudades[i] = ConteoCiudad{
+			Ciudad:     f.Ciudad,
+			Estado:     f.Categoria,
+			Total:      f.Total,
+			Activos:    f.Activos,
+			Porcentaje: porcentaje(f.Total, total),
+		}
+	}
+	return ciudades, nil
+}
+
+// crecimiento cuenta los ingresos a la asociación por año o mes. Los
+// periodos sin ingresos no aparecen. También devuelve cuántas personas no
+// tienen fecha de ingreso y por eso quedan fuera de la serie.
+func (s *ReporteService) crecimiento(filtros ReporteFiltros) ([]PuntoCrecimiento, int64, error) {
+	formato := "YYYY"
+	if filtros.Agrupacion == "mes" {
+		formato = "YYYY-MM"
+	}
+
+	var filas []conteoFila
+	err := s.personas(filtros).
+		Select("TO_CHAR(fecha_ingreso_asociacion, '" + formato + "') AS categoria, COUNT(*) AS total").
+		Where("fecha_ingreso_asociacion IS NOT NULL").
+		Group("categoria").
+		Order("categoria ASC").
+		Scan(&filas).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var sinFecha int64
+	if err := s.personas(filtros).Where("fecha_ingreso_asociacion IS NULL").Count(&sinFecha).Error; err != nil {
+		return nil, 0, err
+	}
+
+	puntos := make([]PuntoCrecimiento, len(filas))
+	var acumulado int64
+	for i, f := range filas {
+		acumulado += f.Total
+		puntos[i] = PuntoCrecimiento{Periodo: f.Categoria, Nuevos: f.Total, Acumulado: acumulado}
+	}
+	return puntos, sinFecha, nil
+}
+
+// EmpresaReporte es una fila del directorio interno de empresas; a
+// diferencia del público incluye las que no aceptaron la promoción.
+type EmpresaReporte struct {
+	IDEmpresa                 uint    `json:"id_empresa"`
+	NombreEmpresa             string  `json:"nombre_empresa"`
+	Sector                    *string `json:"sector"`
+	GiroComercial             *string `json:"giro_comercial"`
+	Ciudad                    *string `json:"ciudad"`
+	Estado                    string  `json:"estado"`
+	Telefono                  *string `json:"telefono"`
+	Email                     *string `json:"email"`
+	SitioWeb                  *string `json:"sitio_web"`
+	NumeroEmpleados           *int    `json:"numero_empleados"`
+	Propietario               string  `json:"propietario"`
+	AceptaPromocionDirectorio bool    `json:"acepta_promocion_directorio"`
+}
+
+func (s *ReporteService) Empresas() ([]EmpresaReporte, error) {
+	empresas := []EmpresaReporte{}
+	err := s.db.Table("empresas e").
+		Select(`e.id_empresa, e.nombre_empresa, e.sector, e.giro_comercial, e.ciudad, e.estado,
+			e.telefono, e.email, e.sitio_web, e.numero_empleados, e.acepta_promocion_directorio,
+			p.nombres || ' ' || p.apellido_paterno || COALESCE(' ' || p.apellido_materno, '') AS propietario`).
+		Joins("JOIN personas p ON p.id_persona = e.id_propietario").
+		Order("e.sector ASC NULLS LAST, e.nombre_empresa ASC").
+		Scan(&empresas).Error
+	if err != nil {
+		return nil, err
+	}
+	return empresas, nil
+}
+
+func ordenarConteos(filas []conteoFila, orden []string, total int64) []ConteoCategoria {
+	porCategoria := make(map[string]conteoFila, len(filas))
+	for _, f := range filas {
+		porCategoria[f.Categoria] = f
+	}
+
+	conteos := make([]ConteoCategoria, 0, len(filas)+len(orden))
+	agregar := func(categoria string) {
+		f := porCategoria[categoria]
+		conteos = append(conteos, ConteoCategoria{
+			Categoria:  categoria,
+			Total:      f.Total,
+			Activos:    f.Activos,
+			Porcentaje: porcentaje(f.Total, total),
+		})
+		delete(porCategoria, categoria)
+	}
+
+	for _, categoria := range orden {
+		agregar(categoria)
+	}
+	for _, f := range filas {
+		if _, ok := porCategoria[f.Categoria]; ok && f.Categoria != sinDato {
+			agregar(f.Categoria)
+		}
+	}
+	if _, ok := porCategoria[sinDato]; ok {
+		agregar(sinDato)
+	}
+	return conteos
+}
+
+// porcentaje redondea a un decimal.
+func porcentaje(parte, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(parte)*1000/float64(total)) / 10
+}
+
+// Documento convierte el reporte en tablas para exportarlo.
+func (r *ReporteDemografico) Documento() *DocumentoReporte {
+	return &DocumentoReporte{
+		Nombre:     "reporte-demografico",
+		Titulo:     "Reporte demográfico",
+		GeneradoEn: r.GeneradoEn,
+		Secciones: []SeccionReporte{
+			{
+				Titulo:   "Resumen",
+				Columnas: []string{"Indicador", "Valor"},
+				Filas: [][]any{
+					{"Personas registradas", r.Total},
+					{"Miembros activos", r.Activos},
+					{"Tasa de miembros activos (%)", r.TasaActivos},
+					{"Sin fecha de ingreso", r.SinFechaIngreso},
+				},
+			},
+			seccionConteos("Personas por generación", "Generación", "Generación", r.PorGeneracion),
+			seccionConteos("Personas por edad", "Edad", "Edad", r.PorEdad),
+			seccionConteos("Nivel de japonés", "Nivel japonés", "Nivel", r.PorNivelJapones),
+			seccionConteos("Personas por estado", "Estado", "Estado", r.PorEstado),
+			seccionCiudades(r.PorCiudad),
+			seccionCrecimiento(r.Crecimiento),
+		},
+	}
+}
+
+func seccionConteos(titulo, hoja, columna string, conteos []ConteoCategoria) SeccionReporte {
+	filas := make([][]any, len(conteos))
+	for i, c := range conteos {
+		filas[i] = []any{c.Categoria, c.Total, c.Activos, c.Porcentaje}
+	}
+	return SeccionReporte{
+		Titulo:   titulo,
+		Hoja:     hoja,
+		Columnas: []string{columna, "Total", "Activos", "%"},
+		Filas:    filas,
+		Grafica:  &GraficaSeccion{Etiqueta: 0, Valor: 1},
+	}
+}
+
+func seccionCiudades(ciudades []ConteoCiudad) SeccionReporte {
+	filas := make([][]any, len(ciudades))
+	for i, c := range ciudades {
+		filas[i] = []any{c.Ciudad, c.Estado, c.Total, c.Activos, c.Porcentaje}
+	}
+	return SeccionReporte{
+		Titulo:   "Personas por ciudad",
+		Hoja:     "Ciudad",
+		Columnas: []string{"Ciudad", "Estado", "Total", "Activos", "%"},
+		Filas:    filas,
+		Grafica:  &GraficaSeccion{Etiqueta: 0, Valor: 2},
+	}
+}
+
+func seccionCrecimiento(puntos []PuntoCrecimiento) SeccionReporte {
+	filas := make([][]any, len(puntos))
+	for i, p := range puntos {
+		filas[i] = []any{p.Periodo, p.Nuevos, p.Acumulado}
+	}
+	return SeccionReporte{
+		Titulo:   "Ingresos a la asociación",
+		Hoja:     "Crecimiento",
+		Columnas: []string{"Periodo", "Nuevos", "Acumulado"},
+		Filas:    filas,
+		Grafica:  &GraficaSeccion{Etiqueta: 0, Valor: 1},
+	}
+}
+
+func seccionAsistencia(eventos []AsistenciaEvento) SeccionReporte {
+	filas := make([][]any, len(eventos))
+	for i, e := range eventos {
+		filas[i] = []any{e.Titulo, e.TipoEvento, e.FechaInicio, e.Status, e.Registrados, e.Confirmados,
+			e.Asistieron, e.NoAsistieron, e.TasaInasistencia, e.AforoReal, calificacionCelda(e.CalificacionPromedio)}
+	}
+	return SeccionReporte{
+		Titulo: "Asistencia a eventos",
+		Hoja:   "Asistencia",
+		Columnas: []string{"Evento", "Tipo", "Fecha", "Estado", "Registrados", "Confirmados",
+			"Asistieron", "No asistieron", "Inasistencia (%)", "Aforo real", "Calificación"},
+		Filas:   filas,
+		Grafica: &GraficaSeccion{Etiqueta: 0, Valor: 9},
+	}
+}
+
+func seccionTiposEvento(tipos []AsistenciaTipoEvento) SeccionReporte {
+	filas := make([][]any, len(tipos))
+	for i, t := range tipos {
+		filas[i] = []any{t.TipoEvento, t.Eventos, t.PromedioRegistrados, t.PromedioAsistentes, t.PromedioAforoReal,
+			t.TasaAsistencia, t.TasaInasistencia, calificacionCelda(t.CalificacionPromedio), t.Calificaciones}
+	}
+	return SeccionReporte{
+		Titulo: "Asistencia por tipo de evento",
+		Hoja:   "Tipos de evento",
+		Columnas: []string{"Tipo", "Eventos", "Registrados por evento", "Asistentes por evento", "Aforo por evento",
+			"Asistencia (%)", "Inasistencia (%)", "Calificación", "Calificaciones"},
+		Filas:   filas,
+		Grafica: &GraficaSeccion{Etiqueta: 0, Valor: 5},
+	}
+}
+
+func seccionCompromiso(reporte *ReporteCompromiso) SeccionReporte {
+	filas := make([][]any, len(reporte.Personas))
+	for i, p := range reporte.Personas {
+		filas[i] = []any{p.Nombre, p.Puntaje, p.Asistencias, p.Inasistencias, p.Cancelaciones, p.Calificaciones}
+	}
+	return SeccionReporte{
+		Titulo:   fmt.Sprintf("Compromiso en los últimos %d eventos", reporte.EventosConsiderados),
+		Hoja:     "Compromiso",
+		Columnas: []string{"Persona", "Puntaje", "Asistencias", "Inasistencias", "Cancelaciones", "Calificaciones"},
+		Filas:    filas,
+		Grafica:  &GraficaSeccion{Etiqueta: 0, Valor: 1},
+	}
+}
+
+// calificacionCelda deja la celda vacía en eventos sin calificaciones.
+func calificacionCelda(promedio *float64) any {
+	if promedio == nil {
+		return ""
+	}
+	return *promedio
+}
+
+func seccionEmpresas(empresas []EmpresaReporte) SeccionReporte {
+	filas := make([][]any, len(empresas))
+	for i, e := range empresas {
+		filas[i] = []any{e.NombreEmpresa, e.Sector, e.GiroComercial, e.Ciudad, e.Estado, e.Telefono,
+			e.Email, e.SitioWeb, e.NumeroEmpleados, e.Propietario, e.AceptaPromocionDirectorio}
+	}
+	return SeccionReporte{
+		Titulo: "Directorio de empresas",
+		Hoja:   "Empresas",
+		Columnas: []string{"Empresa", "Sector", "Giro", "Ciudad", "Estado", "Teléfono",
+			"Email", "Sitio web", "Empleados", "Propietario", "En directorio"},
+		Filas: filas,
+	}
+}
+
+func DocumentoAsistencia(eventos []AsistenciaEvento, ahora time.Time) *DocumentoReporte {
+	return &DocumentoReporte{
+		Nombre:     "reporte-asistencia",
+		Titulo:     "Asistencia a eventos",
+		GeneradoEn: ahora,
+		Secciones:  []SeccionReporte{seccionAsistencia(eventos)},
+	}
+}
+
+func DocumentoTiposEvento(tipos []AsistenciaTipoEvento, ahora time.Time) *DocumentoReporte {
+	return &DocumentoReporte{
+		Nombre:     "reporte-tipos-evento",
+		Titulo:     "Asistencia por tipo de evento",
+		GeneradoEn: ahora,
+		Secciones:  []SeccionReporte{seccionTiposEvento(tipos)},
+	}
+}
+
+func DocumentoCompromiso(reporte *ReporteCompromiso, ahora time.Time) *DocumentoReporte {
+	return &DocumentoReporte{
+		Nombre:     "reporte-compromiso",
+		Titulo:     "Compromiso de participantes",
+		GeneradoEn: ahora,
+		Secciones:  []SeccionReporte{seccionCompromiso(reporte)},
+	}
+}
+
+func DocumentoEmpresas(empresas []EmpresaReporte, ahora time.Time) *DocumentoReporte {
+	return &DocumentoReporte{
+		Nombre:     "directorio-empresas",
+		Titulo:     "Directorio de empresas",
+		GeneradoEn: ahora,
+		Secciones:  []SeccionReporte{seccionEmpresas(empresas)},
+	}
+}
+
+// General junta en un solo documento el reporte demográfico, la asistencia
+// a eventos por evento y por tipo, y el directorio de empresas.
+func (s *ReporteService) General(ahora time.Time) (*DocumentoReporte, error) {
+	demografico, err := s.Demografico(ReporteFiltros{}, ahora)
+	if err != nil {
+		return nil, err
+	}
+	eventos, err := s.Asistencia(AsistenciaFiltros{})
+	if err != nil {
+		return nil, err
+	}
+	tipos, err := s.AsistenciaPorTipo(AsistenciaFiltros{})
+	if err != nil {
+		return nil, err
+	}
+	empresas, err := s.Empresas()
+	if err != nil {
+		return nil, err
+	}
+
+	documento := demografico.Documento()
+	documento.Nombre = "reporte-general"
+	documento.Titulo = "Reporte general"
+	documento.Secciones = append(documento.Secciones, seccionAsistencia(eventos), seccionTiposEvento(tipos), seccionEmpresas(empresas))
+	return documento, nil
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrCheckinInvalido = errors.New("código de acceso inválido")
+
+// FirmarCheckin genera el token que va dentro del QR de una participación:
+// "<id_participacion>.<id_evento>.<firma>". Es corto para que el QR sea fácil
+// de leer con la cámara de un teléfono.
+func FirmarCheckin(secret string, idParticipacion, idEvento uint) string {
+	payload := fmt.Sprintf("%d.%d", idParticipacion, idEvento)
+	return payload + "." + firmaCheckin(secret, payload)
+}
+
+func VerificarCheckin(secret, token string) (idParticipacion, idEvento uint, err error) {
+	partes := strings.Split(strings.TrimSpace(token), ".")
+	if len(partes) != 3 {
+		return 0, 0, ErrCheckinInvalido
+	}
+
+	payload := partes[0] + "." + partes[1]
+	if !hmac.Equal([]byte(partes[2]), []byte(firmaCheckin(secret, payload))) {
+		return 0, 0, ErrCheckinInvalido
+	}
+
+	p, err := strconv.ParseUint(partes[0], 10, 64)
+	if err != nil {
+		return 0, 0, ErrCheckinInvalido
+	}
+	e, err := strconv.ParseUint(partes[1], 10, 64)
+	if err != nil {
+		return 0, 0, ErrCheckinInvalido
+	}
+	return uint(p), uint(e), nil
+}
+
+func firmaCheckin(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte("checkin:"+secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "time"
+
+// FechaLayout es el formato de las fechas sin hora que entran y salen de la
+// API, el mismo que revisa la validación "fecha".
+const FechaLayout = "2006-01-02"
+
+// ParseFecha lee una fecha opcional; nil o vacío es sin fecha.
+func ParseFecha(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(FechaLayout, *value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// FormatFecha es la inversa de ParseFecha.
+func FormatFecha(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(FechaLayout)
+	return &s
+}
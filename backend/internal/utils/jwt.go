@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var ErrTokenInvalido = errors.New("token inválido o expirado")
+
+type Claims struct {
+	UserID    uint   `json:"uid"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SesionID  uint   `json:"sid"`
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+func GenerateToken(secret string, userID uint, email, role string, sesionID uint, tokenType string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims := Claims{
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SesionID:  sesionID,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    "nikkei-sistema",
+			ID:        RandomToken(16),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("firmando token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+func ParseToken(secret, tokenString, expectedType string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, ErrTokenInvalido
+	}
+	if claims.TokenType != expectedType {
+		return nil, ErrTokenInvalido
+	}
+	return claims, nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// sinDiacriticosLatinos quita acentos, diéresis y macrones (Satō → Sato) sin
+// tocar el dakuten del kana, que también es una marca combinante.
+var sinDiacriticosLatinos = transform.Chain(
+	norm.NFD,
+	runes.Remove(runes.Predicate(func(r rune) bool {
+		return r >= 0x0300 && r <= 0x036F
+	})),
+	norm.NFC,
+)
+
+// NormalizarTexto deja un texto listo para comparar: minúsculas, sin acentos
+// y con los espacios colapsados.
+func NormalizarTexto(s string) string {
+	limpio, _, err := transform.String(sinDiacriticosLatinos, s)
+	if err != nil {
+		limpio = s
+	}
+	limpio = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) && r != '\'' {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, limpio)
+	return strings.Join(strings.Fields(limpio), " ")
+}
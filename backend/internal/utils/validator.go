@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldErrors asocia el nombre JSON de cada campo con un mensaje legible.
+type FieldErrors map[string]string
+
+func (f FieldErrors) Error() string {
+	parts := make([]string, 0, len(f))
+	for field, msg := range f {
+		parts = append(parts, field+": "+msg)
+	}
+	return "datos inválidos: " + strings.Join(parts, "; ")
+}
+
+// SetupValidator hace que los errores de validación usen los nombres JSON de
+// los campos y registra las reglas propias del proyecto.
+func SetupValidator() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			name = strings.SplitN(f.Tag.Get("form"), ",", 2)[0]
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	v.RegisterValidation("fecha", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(FechaLayout, fl.Field().String())
+		return err == nil
+	})
+}
+
+// ParseBindingError traduce los errores de ShouldBindJSON/ShouldBindQuery a
+// errores por campo.
+func ParseBindingError(err error) FieldErrors {
+	fields := FieldErrors{}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fe := range validationErrs {
+			fields[fieldPath(fe)] = validationMessage(fe)
+		}
+	case errors.As(err, &typeErr):
+		name := typeErr.Field
+		if name == "" {
+			name = "body"
+		}
+		fields[name] = fmt.Sprintf("tipo de dato inválido, se esperaba %s", typeErr.Type.String())
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		fields["body"] = "el cuerpo de la petición no es JSON válido"
+	default:
+		fields["body"] = err.Error()
+	}
+
+	return fields
+}
+
+func ValidationError(c *gin.Context, fields FieldErrors) {
+	ErrorWithDetails(c, http.StatusUnprocessableEntity, "validacion", "Algunos campos no son válidos", fields)
+}
+
+func BindError(c *gin.Context, err error) {
+	ValidationError(c, ParseBindingError(err))
+}
+
+func fieldPath(fe validator.FieldError) string {
+	// Namespace incluye el nombre del struct raíz: "PersonaInput.nombres".
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "es obligatorio"
+	case "email":
+		return "debe ser un correo electrónico válido"
+	case "oneof":
+		return "debe ser uno de: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("debe tener al menos %s caracteres", fe.Param())
+		}
+		return "debe ser mayor o igual a " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("no debe exceder %s caracteres", fe.Param())
+		}
+		return "debe ser menor o igual a " + fe.Param()
+	case "gte":
+		return "debe ser mayor o igual a " + fe.Param()
+	case "lte":
+		return "debe ser menor o igual a " + fe.Param()
+	case "required_with":
+		return "es obligatorio junto con " + strings.ToLower(fe.Param())
+	case "fecha":
+		return "debe tener el formato AAAA-MM-DD"
+	case "url":
+		return "debe ser una URL válida"
+	default:
+		return "no es válido (" + fe.Tag() + ")"
+	}
+}